@@ -0,0 +1,382 @@
+// Package arkworks serializza prove e chiavi Groth16 BN254 di gnark nel formato
+// canonico compresso di ark-serialize, così che un verificatore Rust possa
+// leggerle con CanonicalDeserialize::deserialize_compressed come
+// ark_groth16::Proof<Bn254>, ark_groth16::VerifyingKey<Bn254> e Vec<Fr>.
+//
+// Il formato non coincide con WriteTo di gnark:
+//   - gli elementi di campo sono little-endian su 32 byte (gnark usa big-endian);
+//   - un punto compresso è solo la x, con i flag nei 2 bit alti dell'ultimo byte:
+//     0x80 se y > -y, 0x40 per il punto all'infinito;
+//   - in Fq2 viene scritto prima c0 e poi c1, e l'ordine tra y e -y confronta
+//     prima c1 e poi c0;
+//   - i vettori hanno la lunghezza come u64 little-endian in testa.
+package arkworks
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+)
+
+const (
+	flagNegative = 1 << 7
+	flagInfinity = 1 << 6
+	flagMask     = flagNegative | flagInfinity
+
+	fieldSize = 32
+)
+
+// Nomi dei file letti da testRsnarkRust.
+const (
+	ProofFile  = "proof.bin"
+	VkFile     = "vk.bin"
+	PublicFile = "public_witness.bin"
+)
+
+// WriteProof scrive la prova come ark_groth16::Proof { a, b, c }.
+func WriteProof(w io.Writer, proof *groth16_bn254.Proof) error {
+	if len(proof.Commitments) > 0 {
+		return errors.New("ark-groth16 non supporta prove con commitment")
+	}
+	return writeAll(w, encodeG1(&proof.Ar), encodeG2(&proof.Bs), encodeG1(&proof.Krs))
+}
+
+// WriteVerifyingKey scrive la vk come ark_groth16::VerifyingKey
+// { alpha_g1, beta_g2, gamma_g2, delta_g2, gamma_abc_g1 }.
+func WriteVerifyingKey(w io.Writer, vk *groth16_bn254.VerifyingKey) error {
+	if len(vk.CommitmentKeys) > 0 {
+		return errors.New("ark-groth16 non supporta vk con commitment")
+	}
+	chunks := [][]byte{encodeG1(&vk.G1.Alpha), encodeG2(&vk.G2.Beta), encodeG2(&vk.G2.Gamma), encodeG2(&vk.G2.Delta), encodeLen(len(vk.G1.K))}
+	for i := range vk.G1.K {
+		chunks = append(chunks, encodeG1(&vk.G1.K[i]))
+	}
+	return writeAll(w, chunks...)
+}
+
+// WritePublicInputs scrive gli input pubblici come Vec<Fr>, senza l'1 iniziale.
+func WritePublicInputs(w io.Writer, public fr.Vector) error {
+	chunks := [][]byte{encodeLen(len(public))}
+	for i := range public {
+		chunks = append(chunks, encodeFr(&public[i]))
+	}
+	return writeAll(w, chunks...)
+}
+
+// ReadProof legge una prova scritta da WriteProof, con controllo di sottogruppo.
+func ReadProof(r io.Reader) (*groth16_bn254.Proof, error) {
+	var proof groth16_bn254.Proof
+	var err error
+	if proof.Ar, err = readG1(r); err != nil {
+		return nil, err
+	}
+	if proof.Bs, err = readG2(r); err != nil {
+		return nil, err
+	}
+	if proof.Krs, err = readG1(r); err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// ReadVerifyingKey legge una vk scritta da WriteVerifyingKey.
+func ReadVerifyingKey(r io.Reader) (*groth16_bn254.VerifyingKey, error) {
+	var vk groth16_bn254.VerifyingKey
+	var err error
+	if vk.G1.Alpha, err = readG1(r); err != nil {
+		return nil, err
+	}
+	for _, p := range []*bn254.G2Affine{&vk.G2.Beta, &vk.G2.Gamma, &vk.G2.Delta} {
+		if *p, err = readG2(r); err != nil {
+			return nil, err
+		}
+	}
+	n, err := readLen(r)
+	if err != nil {
+		return nil, err
+	}
+	vk.G1.K = make([]bn254.G1Affine, n)
+	for i := range vk.G1.K {
+		if vk.G1.K[i], err = readG1(r); err != nil {
+			return nil, err
+		}
+	}
+	// alpha·beta e gli altri valori derivati servono a groth16_bn254.Verify
+	if err := vk.Precompute(); err != nil {
+		return nil, err
+	}
+	return &vk, nil
+}
+
+// ReadPublicInputs legge un Vec<Fr> scritto da WritePublicInputs.
+func ReadPublicInputs(r io.Reader) (fr.Vector, error) {
+	n, err := readLen(r)
+	if err != nil {
+		return nil, err
+	}
+	public := make(fr.Vector, n)
+	var buf [fieldSize]byte
+	for i := range public {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, err
+		}
+		if public[i], err = fr.LittleEndian.Element(&buf); err != nil {
+			return nil, fmt.Errorf("input pubblico %d: %w", i, err)
+		}
+	}
+	return public, nil
+}
+
+// Export scrive proof.bin, vk.bin e public_witness.bin in dir e li rilegge,
+// controllando che i punti decodificati coincidano con quelli di partenza e che
+// la prova riletta verifichi.
+func Export(dir string, proof *groth16_bn254.Proof, vk *groth16_bn254.VerifyingKey, public fr.Vector) error {
+	if err := writeFile(filepath.Join(dir, ProofFile), func(w io.Writer) error { return WriteProof(w, proof) }); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, VkFile), func(w io.Writer) error { return WriteVerifyingKey(w, vk) }); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, PublicFile), func(w io.Writer) error { return WritePublicInputs(w, public) }); err != nil {
+		return err
+	}
+	return Check(dir, proof, vk, public)
+}
+
+// Check rilegge i file di Export e li confronta con i valori attesi.
+func Check(dir string, proof *groth16_bn254.Proof, vk *groth16_bn254.VerifyingKey, public fr.Vector) error {
+	var gotProof *groth16_bn254.Proof
+	var gotVk *groth16_bn254.VerifyingKey
+	var gotPublic fr.Vector
+	err := readFile(filepath.Join(dir, ProofFile), func(r io.Reader) (err error) {
+		gotProof, err = ReadProof(r)
+		return
+	})
+	if err != nil {
+		return err
+	}
+	err = readFile(filepath.Join(dir, VkFile), func(r io.Reader) (err error) {
+		gotVk, err = ReadVerifyingKey(r)
+		return
+	})
+	if err != nil {
+		return err
+	}
+	err = readFile(filepath.Join(dir, PublicFile), func(r io.Reader) (err error) {
+		gotPublic, err = ReadPublicInputs(r)
+		return
+	})
+	if err != nil {
+		return err
+	}
+
+	if !gotProof.Ar.Equal(&proof.Ar) || !gotProof.Bs.Equal(&proof.Bs) || !gotProof.Krs.Equal(&proof.Krs) {
+		return errors.New("round-trip: la prova decodificata è diversa")
+	}
+	if !gotVk.G1.Alpha.Equal(&vk.G1.Alpha) || !gotVk.G2.Beta.Equal(&vk.G2.Beta) ||
+		!gotVk.G2.Gamma.Equal(&vk.G2.Gamma) || !gotVk.G2.Delta.Equal(&vk.G2.Delta) || len(gotVk.G1.K) != len(vk.G1.K) {
+		return errors.New("round-trip: la vk decodificata è diversa")
+	}
+	for i := range vk.G1.K {
+		if !gotVk.G1.K[i].Equal(&vk.G1.K[i]) {
+			return fmt.Errorf("round-trip: punto K[%d] della vk diverso", i)
+		}
+	}
+	if !gotPublic.Equal(public) {
+		return errors.New("round-trip: input pubblici diversi")
+	}
+	if err := groth16_bn254.Verify(gotProof, gotVk, gotPublic); err != nil {
+		return fmt.Errorf("round-trip: la prova decodificata non verifica: %w", err)
+	}
+	return nil
+}
+
+func encodeFr(e *fr.Element) []byte {
+	var b [fieldSize]byte
+	fr.LittleEndian.PutElement(&b, *e)
+	return b[:]
+}
+
+func encodeFp(e *fp.Element) []byte {
+	var b [fieldSize]byte
+	fp.LittleEndian.PutElement(&b, *e)
+	return b[:]
+}
+
+func encodeLen(n int) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(n))
+	return b[:]
+}
+
+func encodeG1(p *bn254.G1Affine) []byte {
+	if p.IsInfinity() {
+		b := make([]byte, fieldSize)
+		b[fieldSize-1] |= flagInfinity
+		return b
+	}
+	b := encodeFp(&p.X)
+	var negY fp.Element
+	negY.Neg(&p.Y)
+	if p.Y.Cmp(&negY) > 0 {
+		b[fieldSize-1] |= flagNegative
+	}
+	return b
+}
+
+func encodeG2(p *bn254.G2Affine) []byte {
+	if p.IsInfinity() {
+		b := make([]byte, 2*fieldSize)
+		b[2*fieldSize-1] |= flagInfinity
+		return b
+	}
+	b := append(encodeFp(&p.X.A0), encodeFp(&p.X.A1)...)
+	var negY bn254.E2
+	negY.Neg(&p.Y)
+	if cmpE2(&p.Y, &negY) > 0 {
+		b[2*fieldSize-1] |= flagNegative
+	}
+	return b
+}
+
+// cmpE2 ordina come ark-ff: prima c1, poi c0.
+func cmpE2(a, b *bn254.E2) int {
+	if c := a.A1.Cmp(&b.A1); c != 0 {
+		return c
+	}
+	return a.A0.Cmp(&b.A0)
+}
+
+func readLen(r io.Reader) (int, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	n := binary.LittleEndian.Uint64(b[:])
+	if n > 1<<24 {
+		return 0, fmt.Errorf("lunghezza %d non plausibile", n)
+	}
+	return int(n), nil
+}
+
+// readFp legge un elemento little-endian e ne separa i flag.
+func readFp(r io.Reader, withFlags bool) (fp.Element, byte, error) {
+	var b [fieldSize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return fp.Element{}, 0, err
+	}
+	var flags byte
+	if withFlags {
+		flags = b[fieldSize-1] & flagMask
+		b[fieldSize-1] &^= flagMask
+	}
+	e, err := fp.LittleEndian.Element(&b)
+	return e, flags, err
+}
+
+func readG1(r io.Reader) (bn254.G1Affine, error) {
+	var p bn254.G1Affine
+	x, flags, err := readFp(r, true)
+	if err != nil {
+		return p, err
+	}
+	if flags&flagInfinity != 0 {
+		return p, nil
+	}
+
+	// y² = x³ + 3
+	var y2, three fp.Element
+	three.SetUint64(3)
+	y2.Square(&x).Mul(&y2, &x).Add(&y2, &three)
+	if p.Y.Sqrt(&y2) == nil {
+		return p, errors.New("x non appartiene a G1")
+	}
+	p.X = x
+	var negY fp.Element
+	negY.Neg(&p.Y)
+	if (p.Y.Cmp(&negY) > 0) != (flags&flagNegative != 0) {
+		p.Y = negY
+	}
+	if !p.IsInSubGroup() {
+		return p, errors.New("punto G1 fuori dal sottogruppo")
+	}
+	return p, nil
+}
+
+func readG2(r io.Reader) (bn254.G2Affine, error) {
+	var p bn254.G2Affine
+	x0, _, err := readFp(r, false)
+	if err != nil {
+		return p, err
+	}
+	x1, flags, err := readFp(r, true)
+	if err != nil {
+		return p, err
+	}
+	if flags&flagInfinity != 0 {
+		return p, nil
+	}
+	p.X.A0, p.X.A1 = x0, x1
+
+	// y² = x³ + 3/(9+u) sulla twist
+	var b, y2 bn254.E2
+	b.A0.SetUint64(9)
+	b.A1.SetOne()
+	b.Inverse(&b)
+	var three fp.Element
+	three.SetUint64(3)
+	b.MulByElement(&b, &three)
+	y2.Square(&p.X).Mul(&y2, &p.X).Add(&y2, &b)
+	if y2.Legendre() == -1 {
+		return p, errors.New("x non appartiene a G2")
+	}
+	p.Y.Sqrt(&y2)
+
+	var negY bn254.E2
+	negY.Neg(&p.Y)
+	if (cmpE2(&p.Y, &negY) > 0) != (flags&flagNegative != 0) {
+		p.Y = negY
+	}
+	if !p.IsInSubGroup() {
+		return p, errors.New("punto G2 fuori dal sottogruppo")
+	}
+	return p, nil
+}
+
+func writeAll(w io.Writer, chunks ...[]byte) error {
+	for _, c := range chunks {
+		if _, err := w.Write(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readFile(path string, read func(io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return read(f)
+}
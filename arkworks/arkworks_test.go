@@ -0,0 +1,229 @@
+package arkworks
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+)
+
+// fixtures sono le cartelle testRsnarkRust committate dai main.
+var fixtures = []string{
+	"zsnark_MiMC",
+	"zsnark_Poseidon_merkle_tree",
+	"zsnark_Poseidon_linear_commitment",
+}
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// reverse restituisce b little-endian a partire dalla scrittura big-endian.
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+// I vettori sono scritti a mano dalle regole di ark-serialize per
+// deserialize_compressed (x little-endian, SWFlags nei 2 bit alti dell'ultimo
+// byte: YIsNegative 0x80 se y > -y, PointAtInfinity 0x40) e dai generatori di
+// EIP-197, non con il codice del package.
+func TestKnownPoints(t *testing.T) {
+	zeros := strings.Repeat("00", 31)
+	// x di G2 dell'EIP-197, big-endian: c0 e c1 di x = c0 + c1·u
+	g2c0 := reverse(unhex(t, "1800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed"))
+	g2c1 := reverse(unhex(t, "198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c2"))
+	g2neg := append(append([]byte(nil), g2c0...), g2c1...)
+	g2neg[63] |= 0x80
+
+	_, _, g1, g2 := bn254.Generators()
+	var negG1 bn254.G1Affine
+	negG1.Neg(&g1)
+	var negG2 bn254.G2Affine
+	negG2.Neg(&g2)
+
+	g1Cases := []struct {
+		name string
+		want []byte
+		p    bn254.G1Affine
+	}{
+		// (1, 2): 2 < p - 2, flag a zero
+		{"generatore G1", unhex(t, "01"+zeros), g1},
+		// (1, p - 2): y > -y, flag di segno
+		{"-generatore G1", unhex(t, "01"+strings.Repeat("00", 30)+"80"), negG1},
+		{"infinito G1", unhex(t, zeros+"40"), bn254.G1Affine{}},
+	}
+	for _, tc := range g1Cases {
+		if got := encodeG1(&tc.p); !bytes.Equal(got, tc.want) {
+			t.Fatalf("%s: %x invece di %x", tc.name, got, tc.want)
+		}
+		p, err := readG1(bytes.NewReader(tc.want))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !p.Equal(&tc.p) {
+			t.Fatalf("%s: decodificato %v", tc.name, p)
+		}
+	}
+
+	g2Cases := []struct {
+		name string
+		want []byte
+		p    bn254.G2Affine
+	}{
+		// la y del generatore ha c1 più piccolo di quello di -y: flag a zero
+		{"generatore G2", append(append([]byte(nil), g2c0...), g2c1...), g2},
+		{"-generatore G2", g2neg, negG2},
+		{"infinito G2", unhex(t, strings.Repeat("00", 63)+"40"), bn254.G2Affine{}},
+	}
+	for _, tc := range g2Cases {
+		if got := encodeG2(&tc.p); !bytes.Equal(got, tc.want) {
+			t.Fatalf("%s: %x invece di %x", tc.name, got, tc.want)
+		}
+		p, err := readG2(bytes.NewReader(tc.want))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !p.Equal(&tc.p) {
+			t.Fatalf("%s: decodificato %v", tc.name, p)
+		}
+	}
+
+	// Vec<Fr> [7834]: lunghezza u64 e poi 0x1e9a little-endian
+	var buf bytes.Buffer
+	if err := WritePublicInputs(&buf, fr.Vector{fr.NewElement(7834)}); err != nil {
+		t.Fatal(err)
+	}
+	if want := unhex(t, "0100000000000000"+"9a1e"+strings.Repeat("00", 30)); !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("Vec<Fr>: %x invece di %x", buf.Bytes(), want)
+	}
+
+	// x = 0 non sta su G1 (y² = 3 non è un quadrato)
+	if _, err := readG1(bytes.NewReader(make([]byte, fieldSize))); err == nil {
+		t.Fatal("accettato x = 0 su G1")
+	}
+}
+
+func readFixture(t *testing.T, dir string) (proof *groth16_bn254.Proof, vk *groth16_bn254.VerifyingKey, public fr.Vector) {
+	t.Helper()
+	read := func(name string, read func(r *bytes.Reader) error) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := bytes.NewReader(data)
+		if err := read(r); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if r.Len() != 0 {
+			t.Fatalf("%s: %d byte in coda", name, r.Len())
+		}
+	}
+	read(ProofFile, func(r *bytes.Reader) (err error) { proof, err = ReadProof(r); return })
+	read(VkFile, func(r *bytes.Reader) (err error) { vk, err = ReadVerifyingKey(r); return })
+	read(PublicFile, func(r *bytes.Reader) (err error) { public, err = ReadPublicInputs(r); return })
+	return
+}
+
+// I file committati si decodificano, la prova verifica, gli input pubblici
+// sono quelli del public.json di snarkjs della stessa esecuzione, ed Export
+// li riscrive byte per byte.
+func TestFixtures(t *testing.T) {
+	for _, name := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			dir := filepath.Join("..", name, "testRsnarkRust")
+			proof, vk, public := readFixture(t, dir)
+			if err := groth16_bn254.Verify(proof, vk, public); err != nil {
+				t.Fatalf("prova rifiutata: %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join("..", name, "testSnarkJS", "public.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decimals []string
+			if err := json.Unmarshal(data, &decimals); err != nil {
+				t.Fatal(err)
+			}
+			if len(decimals) != len(public) {
+				t.Fatalf("%d input pubblici, %d in public.json", len(public), len(decimals))
+			}
+			for i, d := range decimals {
+				want, ok := new(big.Int).SetString(d, 10)
+				if !ok || public[i].BigInt(new(big.Int)).Cmp(want) != 0 {
+					t.Fatalf("input %d: %s invece di %s", i, public[i].String(), d)
+				}
+			}
+
+			out := t.TempDir()
+			if err := Export(out, proof, vk, public); err != nil {
+				t.Fatal(err)
+			}
+			for _, file := range []string{ProofFile, VkFile, PublicFile} {
+				want, _ := os.ReadFile(filepath.Join(dir, file))
+				got, err := os.ReadFile(filepath.Join(out, file))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("%s riscritto diverso", file)
+				}
+			}
+		})
+	}
+}
+
+// A in zsnark_MiMC ha il flag di segno (ultimo byte 0x97): senza il flag, o
+// con il flag di infinito, si decodifica un altro punto e la prova non
+// verifica più.
+func TestFixtureFlags(t *testing.T) {
+	dir := filepath.Join("..", "zsnark_MiMC", "testRsnarkRust")
+	proof, vk, public := readFixture(t, dir)
+	data, err := os.ReadFile(filepath.Join(dir, ProofFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[fieldSize-1]&flagMask != flagNegative {
+		t.Fatalf("A senza flag di segno: ultimo byte %#x", data[fieldSize-1])
+	}
+	var negY fp.Element
+	negY.Neg(&proof.Ar.Y)
+	if proof.Ar.Y.Cmp(&negY) <= 0 {
+		t.Fatal("A decodificato con la y sbagliata")
+	}
+
+	for _, tc := range []struct {
+		name  string
+		flags byte
+	}{
+		{"senza flag di segno", 0},
+		{"punto all'infinito", flagInfinity},
+	} {
+		bad := append([]byte(nil), data...)
+		bad[fieldSize-1] = bad[fieldSize-1]&^flagMask | tc.flags
+		p, err := ReadProof(bytes.NewReader(bad))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if groth16_bn254.Verify(p, vk, public) == nil {
+			t.Fatalf("%s: prova accettata", tc.name)
+		}
+	}
+}
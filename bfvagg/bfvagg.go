@@ -0,0 +1,150 @@
+// Package bfvagg è il server di aggregazione cross-tenant: le consociate
+// cifrano i propri KPI con la stessa chiave pubblica BFV e mandano il
+// ciphertext serializzato con MarshalBinary; il server li somma slot per slot
+// senza decifrare e tiene l'elenco dei contributori inclusi nella somma.
+//
+// I byte ricevuti non sono fidati: prima di UnmarshalBinary si controlla che
+// la struttura sia quella di un ciphertext dei parametri del server (lattigo
+// alloca quello che dice l'header e va in panic su dati troncati), poi che i
+// coefficienti siano ridotti mod q_i.
+package bfvagg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+)
+
+// ErrEmpty è restituito da Sum prima del primo contributo.
+var ErrEmpty = errors.New("bfvagg: nessun contributo")
+
+// polyHeaderLen è l'header di ogni polinomio scritto da ring.Poly.Encode64:
+// N in 4 byte big-endian e il livello in un byte.
+const polyHeaderLen = 5
+
+// Server accumula i ciphertext ricevuti; è sicuro per l'uso concorrente.
+type Server struct {
+	params  bfv.Parameters
+	ref     []byte // ciphertext nullo serializzato, per confrontare gli header
+	polyLen int    // byte di un polinomio serializzato
+	metaLen int    // byte dei metadati prima del grado
+
+	mu           sync.Mutex
+	eval         bfv.Evaluator
+	sum          *rlwe.Ciphertext
+	contributors []string
+	seen         map[string]bool
+}
+
+// NewServer crea un server vuoto per i parametri params.
+func NewServer(params bfv.Parameters) (*Server, error) {
+	zero := bfv.NewCiphertext(params, 1, params.MaxLevel())
+	ref, err := zero.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		params:  params,
+		ref:     ref,
+		polyLen: zero.Value[0].MarshalBinarySize64(),
+		metaLen: zero.MetaData.MarshalBinarySize(),
+		eval:    bfv.NewEvaluator(params, rlwe.EvaluationKey{}),
+		seen:    make(map[string]bool),
+	}, nil
+}
+
+// Submit decodifica il ciphertext di contributor e lo somma all'aggregato.
+// Ogni contributore entra una volta sola: un secondo invio viene rifiutato
+// e l'aggregato non cambia.
+func (s *Server) Submit(contributor string, data []byte) error {
+	if contributor == "" {
+		return errors.New("bfvagg: contributore senza nome")
+	}
+	ct, err := s.decode(data)
+	if err != nil {
+		return fmt.Errorf("bfvagg: ciphertext di %s: %w", contributor, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[contributor] {
+		return fmt.Errorf("bfvagg: %s ha già contribuito", contributor)
+	}
+	if s.sum == nil {
+		s.sum = ct
+	} else {
+		s.eval.Add(s.sum, ct, s.sum)
+	}
+	s.seen[contributor] = true
+	s.contributors = append(s.contributors, contributor)
+	return nil
+}
+
+// decode controlla la struttura prima di UnmarshalBinary: stessa lunghezza,
+// metadati, grado e header dei polinomi (N e livello) del ciphertext nullo.
+func (s *Server) decode(data []byte) (*rlwe.Ciphertext, error) {
+	if len(data) != len(s.ref) {
+		return nil, fmt.Errorf("%d byte, attesi %d", len(data), len(s.ref))
+	}
+	header := s.metaLen + 1
+	if !bytes.Equal(data[:header], s.ref[:header]) {
+		return nil, errors.New("metadati o grado diversi dai parametri del server")
+	}
+	for i := 0; i < 2; i++ {
+		off := header + i*s.polyLen
+		if !bytes.Equal(data[off:off+polyHeaderLen], s.ref[off:off+polyHeaderLen]) {
+			return nil, fmt.Errorf("polinomio %d: N o livello diversi dai parametri del server", i)
+		}
+	}
+
+	ct := new(rlwe.Ciphertext)
+	if err := ct.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	q := s.params.RingQ().Modulus
+	for j, pol := range ct.Value {
+		for i, coeffs := range pol.Coeffs {
+			for k, c := range coeffs {
+				if c >= q[i] {
+					return nil, fmt.Errorf("coefficiente %d del polinomio %d non ridotto mod q_%d", k, j, i)
+				}
+			}
+		}
+	}
+	return ct, nil
+}
+
+// Contributors restituisce i contributori inclusi, in ordine di arrivo.
+func (s *Server) Contributors() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.contributors...)
+}
+
+// Sum restituisce una copia dell'aggregato e i contributori che contiene.
+func (s *Server) Sum() (*rlwe.Ciphertext, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sum == nil {
+		return nil, nil, ErrEmpty
+	}
+	return s.sum.CopyNew(), append([]string(nil), s.contributors...), nil
+}
+
+// MarshalSum è Sum serializzato con MarshalBinary, da mandare a chi ha la
+// chiave segreta.
+func (s *Server) MarshalSum() ([]byte, []string, error) {
+	ct, contributors, err := s.Sum()
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := ct.MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, contributors, nil
+}
@@ -0,0 +1,143 @@
+package bfvagg
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+)
+
+// parametri piccoli per i test, come in bfvproof
+var testLiteral = bfv.ParametersLiteral{LogN: 10, LogQ: []int{40}, LogP: []int{30}, T: 65537}
+
+func newParams(t *testing.T, lit bfv.ParametersLiteral) bfv.Parameters {
+	t.Helper()
+	params, err := bfv.NewParametersFromLiteral(lit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return params
+}
+
+func encrypt(t *testing.T, params bfv.Parameters, pk *rlwe.PublicKey, values []uint64) []byte {
+	t.Helper()
+	pt := bfv.NewEncoder(params).EncodeNew(values, params.MaxLevel())
+	data, err := bfv.NewEncryptor(params, pk).EncryptNew(pt).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSubmitSum(t *testing.T) {
+	params := newParams(t, testLiteral)
+	sk, pk := bfv.NewKeyGenerator(params).GenKeyPair()
+	s, err := NewServer(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Sum(); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("Sum senza contributi: %v", err)
+	}
+
+	tenants := map[string][]uint64{
+		"alfa":  {1, 2, 3},
+		"beta":  {10, 20, 30},
+		"gamma": {100, 200, 300},
+	}
+	for _, name := range []string{"alfa", "beta", "gamma"} {
+		if err := s.Submit(name, encrypt(t, params, pk, tenants[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// un secondo invio dello stesso contributore non cambia l'aggregato
+	if err := s.Submit("beta", encrypt(t, params, pk, []uint64{5000})); err == nil {
+		t.Fatal("accettato un secondo invio di beta")
+	}
+	if err := s.Submit("", encrypt(t, params, pk, []uint64{1})); err == nil {
+		t.Fatal("accettato un contributore senza nome")
+	}
+
+	data, contributors, err := s.MarshalSum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(contributors, []string{"alfa", "beta", "gamma"}) {
+		t.Fatalf("contributori %v", contributors)
+	}
+	ct := new(rlwe.Ciphertext)
+	if err := ct.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	res := bfv.NewEncoder(params).DecodeUintNew(bfv.NewDecryptor(params, sk).DecryptNew(ct))
+	for i, want := range []uint64{111, 222, 333, 0} {
+		if res[i] != want {
+			t.Fatalf("slot %d: %d invece di %d", i, res[i], want)
+		}
+	}
+}
+
+func TestSubmitMalformed(t *testing.T) {
+	params := newParams(t, testLiteral)
+	_, pk := bfv.NewKeyGenerator(params).GenKeyPair()
+	good := encrypt(t, params, pk, []uint64{1, 2, 3})
+
+	foreign := func(lit bfv.ParametersLiteral) []byte {
+		other := newParams(t, lit)
+		_, otherPK := bfv.NewKeyGenerator(other).GenKeyPair()
+		return encrypt(t, other, otherPK, []uint64{1, 2, 3})
+	}
+	edit := func(f func(data []byte)) []byte {
+		data := append([]byte(nil), good...)
+		f(data)
+		return data
+	}
+	meta := new(rlwe.MetaData).MarshalBinarySize()
+	header := meta + 1
+	polyLen := (len(good) - header) / 2
+
+	cases := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"vuoto", nil, "byte"},
+		{"troncato", good[:len(good)/2], "byte"},
+		{"un byte in meno", good[:len(good)-1], "byte"},
+		{"byte in coda", append(append([]byte(nil), good...), 0), "byte"},
+		{"altro N", foreign(bfv.ParametersLiteral{LogN: 11, LogQ: []int{40}, LogP: []int{30}, T: 65537}), "byte"},
+		{"altri moduli", foreign(bfv.ParametersLiteral{LogN: 10, LogQ: []int{30, 30}, LogP: []int{30}, T: 65537}), "byte"},
+		{"metadati", edit(func(d []byte) { d[0] ^= 1 }), "metadati"},
+		{"grado", edit(func(d []byte) { d[meta] = 3 }), "grado"}, // il byte è grado+1
+		{"N del polinomio 0", edit(func(d []byte) { copy(d[header:], []byte{0xff, 0xff, 0xff, 0xff}) }), "polinomio 0"},
+		{"livello del polinomio 1", edit(func(d []byte) { d[header+polyLen+polyHeaderLen-1] = 7 }), "polinomio 1"},
+		{"coefficiente non ridotto", edit(func(d []byte) {
+			for k := len(d) - 8; k < len(d); k++ {
+				d[k] = 0xff
+			}
+		}), "non ridotto"},
+	}
+
+	s, err := NewServer(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		err := s.Submit("intruso", tc.data)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: errore %v, atteso %q", tc.name, err, tc.want)
+		}
+	}
+	if _, _, err := s.Sum(); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("l'aggregato è cambiato con invii rifiutati: %v", err)
+	}
+
+	// i rifiuti non bruciano il nome: lo stesso contributore può ancora inviare
+	if err := s.Submit("intruso", good); err != nil {
+		t.Fatalf("invio valido dopo i rifiuti: %v", err)
+	}
+}
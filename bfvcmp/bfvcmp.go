@@ -0,0 +1,104 @@
+// Package bfvcmp confronta un totale cifrato BFV con una soglia in chiaro
+// senza decifrarlo: il risultato è un ciphertext che contiene solo il bit
+// totale ≥ soglia, nello slot 0, con gli altri slot a zero.
+//
+// Per il piccolo teorema di Fermat z^(t-1) mod t vale 0 se z = 0 e 1
+// altrimenti, quindi il polinomio 1 - z^(t-1) è il test di uguaglianza, e si
+// valuta con log2(t) quadrati. Con il totale x in tutti gli slot (dopo
+// innerSum) lo slot i riceve x - k_i, dove i k_i sono threshold..bound e poi
+// valori oltre bound che x non può assumere: se x ≥ threshold esattamente uno
+// slot è zero, altrimenti nessuno. La somma dei test sugli slot è il bit.
+//
+// Interpolare direttamente l'indicatore su [0, bound] costa un polinomio di
+// grado bound: con bound = 12700 su PN15QP880 sono più di 12 minuti, contro
+// 16 quadrati qui.
+//
+// Il rumore del ciphertext restituito non viene rimescolato (noise flooding)
+// e dipende da x: chi ha la chiave segreta può misurarlo e ricavarne qualcosa
+// sul totale, oltre al bit. La perdita è accettata. Coprirlo con 40 bit
+// statistici vorrebbe un errore 2^40 volte quello finale, ma su PN15QP880
+// dopo il confronto restano circa 38 bit di budget, e il campionatore
+// gaussiano di lattigo non arriva a deviazioni di centinaia di bit.
+package bfvcmp
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+)
+
+// Depth è la profondità moltiplicativa di Compare: i quadrati e i prodotti
+// di z^(t-1).
+func Depth(t uint64) int {
+	return bits.Len64(t-1) - 1 + bits.OnesCount64(t-1) - 1
+}
+
+// GaloisElements sono le chiavi di rotazione che servono a Compare.
+func GaloisElements(params bfv.Parameters) []uint64 {
+	return params.GaloisElementsForRowInnerSum()
+}
+
+// Shifts restituisce i k_i da sottrarre al totale negli slot: threshold..bound
+// e poi bound+1, bound+2, ... fino a riempire gli N slot. Servono
+// bound - threshold + 1 ≤ N e t > bound + N - (bound - threshold + 1), così
+// nessun k_i oltre bound è congruo a un totale possibile. Una soglia oltre
+// bound non è mai raggiunta: tutti i k_i stanno oltre bound e il bit è 0.
+func Shifts(params bfv.Parameters, bound, threshold uint64) ([]uint64, error) {
+	n := uint64(params.N())
+	threshold = min(threshold, bound+1)
+	if bound-threshold+1 > n {
+		return nil, fmt.Errorf("bfvcmp: %d valori sopra la soglia, %d slot", bound-threshold+1, n)
+	}
+	if bound+n-(bound-threshold+1) >= params.T() {
+		return nil, fmt.Errorf("bfvcmp: t = %d troppo piccolo per bound %d con %d slot", params.T(), bound, n)
+	}
+	k := make([]uint64, n)
+	for i := range k {
+		k[i] = threshold + uint64(i)
+	}
+	return k, nil
+}
+
+// Compare restituisce il ciphertext di [x ≥ threshold] nello slot 0, dato ct
+// con il totale x ∈ [0, bound] in tutti gli slot. evaluator deve avere la
+// chiave di rilinearizzazione e le chiavi di GaloisElements. Il rumore del
+// risultato non è rimescolato: vedi la documentazione del package.
+func Compare(params bfv.Parameters, evaluator bfv.Evaluator, encoder bfv.Encoder, ct *rlwe.Ciphertext, bound, threshold uint64) (*rlwe.Ciphertext, error) {
+	k, err := Shifts(params, bound, threshold)
+	if err != nil {
+		return nil, err
+	}
+	level := ct.Level()
+
+	// z_i = x - k_i, poi y_i = z_i^(t-1): 0 dove x = k_i, 1 altrove
+	z := evaluator.SubNew(ct, encoder.EncodeNew(k, level))
+	y := pow(evaluator, z, params.T()-1)
+
+	// Σ y_i = N - [x ≥ threshold], in tutti gli slot
+	sum := bfv.NewCiphertext(params, 1, level)
+	evaluator.InnerSum(y, sum)
+	evaluator.Neg(sum, sum)
+	evaluator.AddScalar(sum, uint64(params.N())%params.T(), sum)
+
+	// solo lo slot 0: chi decifra vede il bit e basta
+	mask := make([]uint64, params.N())
+	mask[0] = 1
+	evaluator.Mul(sum, encoder.EncodeMulNew(mask, level), sum)
+	return sum, nil
+}
+
+// pow calcola z^e con quadrati e prodotti, rilinearizzando ogni volta.
+func pow(evaluator bfv.Evaluator, z *rlwe.Ciphertext, e uint64) *rlwe.Ciphertext {
+	res := z.CopyNew()
+	for b := bits.Len64(e) - 2; b >= 0; b-- {
+		res = evaluator.MulNew(res, res)
+		evaluator.Relinearize(res, res)
+		if e>>b&1 == 1 {
+			res = evaluator.MulNew(res, z)
+			evaluator.Relinearize(res, res)
+		}
+	}
+	return res
+}
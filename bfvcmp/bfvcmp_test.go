@@ -0,0 +1,96 @@
+package bfvcmp
+
+import (
+	"testing"
+
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+)
+
+// Ogni totale in [0, bound] deve coincidere con un k_i se e solo se è sopra
+// la soglia; una soglia oltre bound non è un errore e non la raggiunge nessuno.
+func TestShifts(t *testing.T) {
+	params, err := bfv.NewParametersFromLiteral(bfv.PN12QP109)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const bound = 5050
+	for _, threshold := range []uint64{1000, 5000, bound, bound + 1, bound + 1000} {
+		k, err := Shifts(params, bound, threshold)
+		if err != nil {
+			t.Fatalf("soglia %d: %v", threshold, err)
+		}
+		if len(k) != params.N() {
+			t.Fatalf("soglia %d: %d shift, attesi %d", threshold, len(k), params.N())
+		}
+		hit := make(map[uint64]bool, len(k))
+		for _, ki := range k {
+			hit[ki%params.T()] = true
+		}
+		for x := uint64(0); x <= bound; x++ {
+			if hit[x] != (x >= threshold) {
+				t.Fatalf("soglia %d, totale %d: zero in uno slot %v", threshold, x, hit[x])
+			}
+		}
+	}
+}
+
+// cmpLiteral ha N e t di PN12QP109 ma un Q da 13 moduli: i 78 bit di Q del
+// preset perdono la decifratura prima del sedicesimo quadrato. Con N = 4096
+// un Q di 715 bit non è sicuro, va bene solo per il test.
+var cmpLiteral = bfv.ParametersLiteral{
+	LogN: bfv.PN12QP109.LogN,
+	LogQ: []int{55, 55, 55, 55, 55, 55, 55, 55, 55, 55, 55, 55, 55},
+	LogP: []int{55},
+	T:    bfv.PN12QP109.T,
+}
+
+// Totali sotto, uguali e sopra la soglia cifrati in tutti gli slot come dopo
+// innerSum: nel testo in chiaro del confronto c'è il bit nello slot 0 e
+// nient'altro.
+func TestCompare(t *testing.T) {
+	params, err := bfv.NewParametersFromLiteral(cmpLiteral)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kgen := bfv.NewKeyGenerator(params)
+	sk, pk := kgen.GenKeyPair()
+	encoder := bfv.NewEncoder(params)
+	encryptor := bfv.NewEncryptor(params, pk)
+	decryptor := bfv.NewDecryptor(params, sk)
+	evaluator := bfv.NewEvaluator(params, rlwe.EvaluationKey{
+		Rlk:  kgen.GenRelinearizationKey(sk, 1),
+		Rtks: kgen.GenRotationKeys(GaloisElements(params), sk),
+	})
+
+	const bound, threshold = 5050, 5000
+	for _, tc := range []struct {
+		total, threshold, bit uint64
+	}{
+		{0, threshold, 0},
+		{threshold - 1, threshold, 0},
+		{threshold, threshold, 1},
+		{threshold + 1, threshold, 1},
+		{bound, threshold, 1},
+		{bound, bound + 1, 0}, // soglia oltre la somma massima
+	} {
+		values := make([]uint64, params.N())
+		for i := range values {
+			values[i] = tc.total
+		}
+		ct := encryptor.EncryptNew(encoder.EncodeNew(values, params.MaxLevel()))
+		cmp, err := Compare(params, evaluator, encoder, ct, bound, tc.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := encoder.DecodeUintNew(decryptor.DecryptNew(cmp))
+		if res[0] != tc.bit {
+			t.Fatalf("totale %d, soglia %d: bit %d, atteso %d", tc.total, tc.threshold, res[0], tc.bit)
+		}
+		for i, v := range res[1:] {
+			if v != 0 {
+				t.Fatalf("totale %d: slot %d non nullo: %d", tc.total, i+1, v)
+			}
+		}
+	}
+}
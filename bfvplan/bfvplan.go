@@ -0,0 +1,311 @@
+// Package bfvplan sceglie i parametri BFV per la pipeline KPI: dato quanti
+// bit hanno i valori, quanti se ne sommano e quante moltiplicazioni servono,
+// prende il preset lattigo più piccolo che resta sicuro, con un modulo t che
+// non va in wraparound sulla somma nel caso peggiore e abbastanza budget di
+// rumore dopo tutti i passi.
+//
+// Il budget di rumore non viene stimato con formule: Choose esegue davvero i
+// passi su un ciphertext di prova e lo misura con Budget, che chi ha la chiave
+// segreta può usare anche sui ciphertext veri dopo ogni operazione.
+package bfvplan
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand/v2"
+
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/ring"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+
+	"zk-test/bfvcmp"
+)
+
+// MinBudget sono i bit di budget che devono restare dopo l'ultimo passo:
+// margine per la variabilità del rumore tra un ciphertext e l'altro, visto che
+// rotazioni e moltiplicazioni sono già misurate.
+const MinBudget = 10
+
+// Presets sono i candidati, dal più piccolo; PN11QP54 non ha il modulo P e
+// non permette rotazioni né rilinearizzazione.
+var Presets = []struct {
+	Name    string
+	Literal bfv.ParametersLiteral
+}{
+	{"PN12QP109", bfv.PN12QP109},
+	{"PN13QP218", bfv.PN13QP218},
+	{"PN14QP438", bfv.PN14QP438},
+	{"PN15QP880", bfv.PN15QP880},
+}
+
+// maxLogQP è il massimo log2(QP) a 128 bit di sicurezza classica per segreto
+// ternario, dalla tabella dell'HomomorphicEncryption.org standard.
+var maxLogQP = map[int]int{12: 109, 13: 218, 14: 438, 15: 881}
+
+// Requirements descrive la pipeline. Nel caso peggiore ogni valore vale
+// 2^ValueBits - 1, viene moltiplicato Depth volte per sé stesso e poi se ne
+// sommano Count; con InnerSum la somma è sugli slot del ciphertext, che
+// moltiplica il rumore per N anche se i valori sono meno. Con Compare il
+// totale va poi confrontato con una soglia da bfvcmp, che costa altri
+// bfvcmp.Depth(t) quadrati e vuole t oltre la somma massima più N.
+type Requirements struct {
+	ValueBits int
+	Count     int
+	Depth     int
+	InnerSum  bool
+	Compare   bool
+}
+
+// Step è il budget misurato dopo un passo della pipeline di prova.
+type Step struct {
+	Name   string
+	Budget float64
+}
+
+// Plan è la scelta di Choose.
+type Plan struct {
+	Name     string
+	Params   bfv.Parameters
+	WorstSum *big.Int // risultato massimo, < t
+	Steps    []Step
+	Rejected []string // preset scartati e perché
+}
+
+// Choose restituisce il primo preset che soddisfa req.
+func Choose(req Requirements) (*Plan, error) {
+	if req.ValueBits < 1 || req.Count < 1 || req.Depth < 0 {
+		return nil, fmt.Errorf("bfvplan: requisiti non validi %+v", req)
+	}
+	worst := WorstSum(req)
+	var rejected []string
+	for _, preset := range Presets {
+		plan, err := try(preset.Name, preset.Literal, req, worst)
+		if err != nil {
+			rejected = append(rejected, fmt.Sprintf("%s: %v", preset.Name, err))
+			continue
+		}
+		plan.Rejected = rejected
+		return plan, nil
+	}
+	return nil, fmt.Errorf("bfvplan: nessun preset adatto (%v)", rejected)
+}
+
+// WorstSum è Count·(2^ValueBits - 1)^(Depth+1), il risultato più grande che
+// la pipeline può produrre.
+func WorstSum(req Requirements) *big.Int {
+	v := new(big.Int).Lsh(big.NewInt(1), uint(req.ValueBits))
+	v.Sub(v, big.NewInt(1))
+	worst := new(big.Int).Exp(v, big.NewInt(int64(req.Depth+1)), nil)
+	return worst.Mul(worst, big.NewInt(int64(req.Count)))
+}
+
+func try(name string, lit bfv.ParametersLiteral, req Requirements, worst *big.Int) (*Plan, error) {
+	n := 1 << lit.LogN
+	if req.Count > n {
+		return nil, fmt.Errorf("%d valori, %d slot", req.Count, n)
+	}
+	need := worst
+	if req.Compare {
+		need = new(big.Int).Add(worst, big.NewInt(int64(n)))
+	}
+	t, err := plaintextModulus(lit.T, need, n)
+	if err != nil {
+		return nil, err
+	}
+	lit.T = t
+	params, err := bfv.NewParametersFromLiteral(lit)
+	if err != nil {
+		return nil, err
+	}
+	if params.LogQP() > maxLogQP[params.LogN()] {
+		return nil, fmt.Errorf("log2(QP) = %d oltre %d, non sicuro", params.LogQP(), maxLogQP[params.LogN()])
+	}
+	steps, err := simulate(params, req)
+	if err != nil {
+		return nil, err
+	}
+	if last := steps[len(steps)-1]; last.Budget < MinBudget {
+		return nil, fmt.Errorf("budget %.1f bit dopo %q, servono %d", last.Budget, last.Name, MinBudget)
+	}
+	return &Plan{Name: name, Params: params, WorstSum: worst, Steps: steps}, nil
+}
+
+// plaintextModulus tiene il t del preset se basta, altrimenti cerca il primo
+// primo t > worst con t ≡ 1 mod 2N, che serve al batching negli slot.
+func plaintextModulus(t uint64, worst *big.Int, n int) (uint64, error) {
+	if new(big.Int).SetUint64(t).Cmp(worst) > 0 {
+		return t, nil
+	}
+	if worst.BitLen() > 60 {
+		return 0, fmt.Errorf("somma massima di %d bit, t al massimo 61 bit", worst.BitLen())
+	}
+	step := uint64(2 * n)
+	t = (worst.Uint64()/step+1)*step + 1
+	for !ring.IsPrime(t) {
+		t += step
+	}
+	return t, nil
+}
+
+// simulate esegue la pipeline nel caso peggiore su valori casuali: cifratura,
+// Depth quadrati con rilinearizzazione, poi la somma. Con InnerSum è la somma
+// vera sugli N slot, con le rotazioni e le chiavi di Galois come nel demo;
+// senza è il prodotto per Count (rumore di Count ciphertext tutti uguali,
+// solo addizioni). Con Compare seguono i bfvcmp.Depth(t) quadrati del
+// confronto, la sua somma sugli slot e la maschera dello slot 0.
+func simulate(params bfv.Parameters, req Requirements) ([]Step, error) {
+	kgen := bfv.NewKeyGenerator(params)
+	sk, pk := kgen.GenKeyPair()
+	var evk rlwe.EvaluationKey
+	if req.Depth > 0 || req.Compare {
+		evk.Rlk = kgen.GenRelinearizationKey(sk, 1)
+	}
+	if req.InnerSum || req.Compare {
+		evk.Rtks = kgen.GenRotationKeys(params.GaloisElementsForRowInnerSum(), sk)
+	}
+	encoder := bfv.NewEncoder(params)
+	evaluator := bfv.NewEvaluator(params, evk)
+	t := params.T()
+
+	values := make([]uint64, params.N())
+	limit := uint64(1)<<min(req.ValueBits, 63) - 1
+	for i := range values {
+		values[i] = rand.Uint64N(limit+1) % t
+	}
+	want := append([]uint64(nil), values...)
+	ct := bfv.NewEncryptor(params, pk).EncryptNew(encoder.EncodeNew(values, params.MaxLevel()))
+
+	var steps []Step
+	measure := func(name string) error {
+		got := encoder.DecodeUintNew(bfv.NewDecryptor(params, sk).DecryptNew(ct))
+		for i := range got {
+			if got[i] != want[i] {
+				return fmt.Errorf("decifratura sbagliata dopo %q", name)
+			}
+		}
+		steps = append(steps, Step{name, Budget(params, sk, ct)})
+		return nil
+	}
+	if err := measure("cifratura"); err != nil {
+		return nil, err
+	}
+	for d := 1; d <= req.Depth; d++ {
+		ct = evaluator.MulNew(ct, ct)
+		evaluator.Relinearize(ct, ct)
+		for i := range want {
+			want[i] = mulMod(want[i], want[i], t)
+		}
+		if err := measure(fmt.Sprintf("moltiplicazione %d", d)); err != nil {
+			return nil, err
+		}
+	}
+	// innerSum somma gli slot con le rotazioni: ogni rotazione è un key switch
+	innerSum := func(name string) error {
+		sum := bfv.NewCiphertext(params, 1, ct.Level())
+		evaluator.InnerSum(ct, sum)
+		ct = sum
+		var total uint64
+		for _, v := range want {
+			total = (total + v) % t
+		}
+		for i := range want {
+			want[i] = total
+		}
+		return measure(name)
+	}
+	switch {
+	case req.InnerSum:
+		if err := innerSum(fmt.Sprintf("somma di %d slot", params.N())); err != nil {
+			return nil, err
+		}
+	case req.Count > 1:
+		addends := uint64(req.Count) % t
+		evaluator.MulScalar(ct, addends, ct)
+		for i := range want {
+			want[i] = mulMod(want[i], addends, t)
+		}
+		if err := measure(fmt.Sprintf("somma di %d", req.Count)); err != nil {
+			return nil, err
+		}
+	}
+	if req.Compare {
+		for d := 1; d <= bfvcmp.Depth(t); d++ {
+			ct = evaluator.MulNew(ct, ct)
+			evaluator.Relinearize(ct, ct)
+			for i := range want {
+				want[i] = mulMod(want[i], want[i], t)
+			}
+			if err := measure(fmt.Sprintf("confronto, quadrato %d", d)); err != nil {
+				return nil, err
+			}
+		}
+		if err := innerSum("confronto, somma degli slot"); err != nil {
+			return nil, err
+		}
+		mask := make([]uint64, params.N())
+		mask[0] = 1
+		evaluator.Mul(ct, encoder.EncodeMulNew(mask, ct.Level()), ct)
+		for i := range want[1:] {
+			want[i+1] = 0
+		}
+		if err := measure("confronto, maschera dello slot 0"); err != nil {
+			return nil, err
+		}
+	}
+	return steps, nil
+}
+
+func mulMod(a, b, t uint64) uint64 {
+	return new(big.Int).Mod(new(big.Int).Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b)), new(big.Int).SetUint64(t)).Uint64()
+}
+
+// Budget misura i bit di rumore che ct può ancora sopportare: log2(Δ/2) meno
+// log2 del rumore più grande, dove il rumore è c0 + c1·s - Δ·m. Serve la
+// chiave segreta; sotto zero la decifratura non è più affidabile.
+func Budget(params bfv.Parameters, sk *rlwe.SecretKey, ct *rlwe.Ciphertext) float64 {
+	level := ct.Level()
+	ringQ := params.RingQ()
+	encoder := bfv.NewEncoder(params)
+
+	noisy := bfv.NewDecryptor(params, sk).DecryptNew(ct)
+	clean := bfv.NewPlaintext(params, level)
+	encoder.Encode(encoder.DecodeUintNew(noisy), clean)
+	ringQ.SubLvl(level, noisy.Value, clean.Value, noisy.Value)
+
+	coeffs := make([]*big.Int, params.N())
+	for i := range coeffs {
+		coeffs[i] = new(big.Int)
+	}
+	ringQ.PolyToBigintCenteredLvl(level, noisy.Value, 1, coeffs)
+	noise := new(big.Int)
+	for _, c := range coeffs {
+		if c.CmpAbs(noise) > 0 {
+			noise.Abs(c)
+		}
+	}
+	if noise.Sign() == 0 {
+		noise.SetInt64(1)
+	}
+	q := ringQ.ModulusAtLevel[level]
+	return log2(q) - math.Log2(float64(params.T())) - 1 - log2(noise)
+}
+
+func log2(x *big.Int) float64 {
+	mant := new(big.Float)
+	exp := new(big.Float).SetInt(x).MantExp(mant)
+	f, _ := mant.Float64()
+	return float64(exp) + math.Log2(f)
+}
+
+// String riassume il piano per i log dei demo.
+func (p *Plan) String() string {
+	s := fmt.Sprintf("%s (N=%d, log2(QP)=%d, t=%d, somma massima %s)", p.Name, p.Params.N(), p.Params.LogQP(), p.Params.T(), p.WorstSum)
+	for _, st := range p.Steps {
+		s += fmt.Sprintf("\n  budget dopo %-34s %5.1f bit", st.Name+":", st.Budget)
+	}
+	for _, r := range p.Rejected {
+		s += "\n  scartato " + r
+	}
+	return s
+}
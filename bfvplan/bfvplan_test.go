@@ -0,0 +1,58 @@
+package bfvplan
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// i requisiti di default di lattigo_plan: 100 KPI da 7 bit sommati sugli slot
+var defaultReq = Requirements{ValueBits: 7, Count: 100, InnerSum: true}
+
+func TestChooseDefault(t *testing.T) {
+	plan, err := Choose(defaultReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Name != "PN12QP109" {
+		t.Fatalf("scelto %s, atteso PN12QP109", plan.Name)
+	}
+	if len(plan.Rejected) != 0 {
+		t.Fatalf("scartati %v prima del più piccolo", plan.Rejected)
+	}
+	if plan.WorstSum.Cmp(big.NewInt(100*127)) != 0 || new(big.Int).SetUint64(plan.Params.T()).Cmp(plan.WorstSum) <= 0 {
+		t.Fatalf("somma massima %s con t = %d", plan.WorstSum, plan.Params.T())
+	}
+
+	// la somma sugli slot è misurata dopo le rotazioni vere e costa budget
+	if len(plan.Steps) != 2 || plan.Steps[1].Name != "somma di 4096 slot" {
+		t.Fatalf("passi %+v", plan.Steps)
+	}
+	if enc, sum := plan.Steps[0].Budget, plan.Steps[1].Budget; sum >= enc || sum < MinBudget {
+		t.Fatalf("budget %.1f dopo la cifratura e %.1f dopo la somma", enc, sum)
+	}
+}
+
+func TestChooseRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		req  Requirements
+		want string
+	}{
+		{"requisiti non validi", Requirements{ValueBits: 0, Count: 1}, "non validi"},
+		{"troppi valori", Requirements{ValueBits: 7, Count: 1 << 16, InnerSum: true}, "slot"},
+		{"somma oltre 61 bit", Requirements{ValueBits: 40, Count: 100, Depth: 1}, "61 bit"},
+	} {
+		_, err := Choose(tc.req)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: errore %v, atteso %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestWorstSum(t *testing.T) {
+	// 3 valori da 4 bit, elevati al quadrato: 3·15²
+	if got := WorstSum(Requirements{ValueBits: 4, Count: 3, Depth: 1}); got.Int64() != 675 {
+		t.Fatalf("WorstSum = %s, attesa 675", got)
+	}
+}
@@ -0,0 +1,149 @@
+package bfvproof
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+
+	"zk-test/kpihash"
+)
+
+// parametri piccoli per i test: N = 1024 e un modulo da 40 bit bastano a
+// decifrare una somma, con circuiti di pochi secondi
+func testParams(t *testing.T) (bfv.Parameters, *rlwe.SecretKey, *Params) {
+	t.Helper()
+	params, err := bfv.NewParametersFromLiteral(bfv.ParametersLiteral{LogN: 10, LogQ: []int{40}, LogP: []int{30}, T: 65537})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, pk := bfv.NewKeyGenerator(params).GenKeyPair()
+	p, err := NewParams(params, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return params, sk, p
+}
+
+var testValues = []uint64{12, 7, 300, 41, 5}
+
+func TestEncryptionCircuit(t *testing.T) {
+	params, sk, p := testParams(t)
+	ct, w, err := Encrypt(params, p, testValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// il ciphertext si decifra nei valori
+	res := bfv.NewEncoder(params).DecodeUintNew(bfv.NewDecryptor(params, sk).DecryptNew(ct))
+	for i, v := range testValues {
+		if res[i] != v {
+			t.Fatalf("slot %d: %d invece di %d", i, res[i], v)
+		}
+	}
+
+	circuit := NewEncryptionCircuit(p, len(testValues))
+	field := ecc.BN254.ScalarField()
+	if err := test.IsSolved(circuit, w.Assignment(p, ct), field); err != nil {
+		t.Fatalf("cifratura onesta rifiutata: %v", err)
+	}
+
+	// un'altra somma
+	a := w.Assignment(p, ct)
+	a.Sum = w.Sum + 1
+	if test.IsSolved(circuit, a, field) == nil {
+		t.Fatal("accettata con una somma diversa")
+	}
+
+	// un ciphertext alterato
+	a = w.Assignment(p, ct)
+	a.C[0][0][0] = (ct.Value[0].Coeffs[0][0] + 1) % p.Q[0]
+	if test.IsSolved(circuit, a, field) == nil {
+		t.Fatal("accettata con un ciphertext alterato")
+	}
+
+	// il commitment senza sale
+	native, err := kpihash.New(kpihash.Poseidon2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsalted := native.Hash(toElements(testValues)...)
+	a = w.Assignment(p, ct)
+	a.Commitment = unsalted.String()
+	if test.IsSolved(circuit, a, field) == nil {
+		t.Fatal("accettata con il commitment senza sale")
+	}
+}
+
+// Un u con un coefficiente 2 dà un ciphertext coerente con tutte le altre
+// relazioni: lo deve fermare solo il vincolo ternario.
+func TestEncryptionNotTernary(t *testing.T) {
+	params, _, p := testParams(t)
+	_, w, err := Encrypt(params, p, testValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.U[0] = 2
+	ct := w.encrypt(params, p)
+	if test.IsSolved(NewEncryptionCircuit(p, len(testValues)), w.Assignment(p, ct), ecc.BN254.ScalarField()) == nil {
+		t.Fatal("accettato u con un coefficiente 2")
+	}
+}
+
+func TestNewParamsLargeModulus(t *testing.T) {
+	params, err := bfv.NewParametersFromLiteral(bfv.ParametersLiteral{LogN: 10, LogQ: []int{52}, LogP: []int{30}, T: 65537})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, pk := bfv.NewKeyGenerator(params).GenKeyPair()
+	if _, err := NewParams(params, pk); err == nil {
+		t.Fatal("accettato un modulo da 52 bit: Δ·m non sta in un int64")
+	}
+}
+
+// Il testo in chiaro costante è quello che innerSum lascia in ogni slot.
+func TestDecryptionCircuit(t *testing.T) {
+	params, sk, p := testParams(t)
+	const sum = 365
+	slots := make([]uint64, params.N())
+	for i := range slots {
+		slots[i] = sum
+	}
+	ct := bfv.NewEncryptor(params, sk).EncryptNew(bfv.NewEncoder(params).EncodeNew(slots, params.MaxLevel()))
+
+	w, err := Decrypt(params, p, sk, ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Sum != sum {
+		t.Fatalf("decifrato %d invece di %d", w.Sum, sum)
+	}
+
+	circuit := NewDecryptionCircuit(p)
+	field := ecc.BN254.ScalarField()
+	if err := test.IsSolved(circuit, w.Assignment(p, ct), field); err != nil {
+		t.Fatalf("decifratura onesta rifiutata: %v", err)
+	}
+
+	// la stessa decifratura annunciata con un'altra somma
+	a := w.Assignment(p, ct)
+	a.Sum = sum + 1
+	if test.IsSolved(circuit, a, field) == nil {
+		t.Fatal("accettata con una somma diversa")
+	}
+
+	// un ciphertext alterato
+	a = w.Assignment(p, ct)
+	a.C[1][0][0] = (ct.Value[1].Coeffs[0][0] + 1) % p.Q[0]
+	if test.IsSolved(circuit, a, field) == nil {
+		t.Fatal("accettata con un ciphertext alterato")
+	}
+
+	// una chiave non ternaria
+	a = w.Assignment(p, ct)
+	a.S[0] = 2
+	if test.IsSolved(circuit, a, field) == nil {
+		t.Fatal("accettata con un coefficiente 2 nella chiave")
+	}
+}
@@ -0,0 +1,243 @@
+package bfvproof
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/multicommit"
+	"github.com/consensys/gnark/std/rangecheck"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+
+	"zk-test/kpihash"
+)
+
+// limiti dei range check: bastano a tenere ogni relazione ben sotto il modulo
+// di BN254, così valgono sugli interi
+const (
+	sumBits   = 16 // Sum < 2^16 < T
+	mBits     = 17 // coefficienti del testo in chiaro, < T
+	errOffset = 32 // |e| ≤ ErrBound < 32
+	errBits   = 6
+	kOffset   = 1 << 18 // |K| ≤ N + T + 1
+	kBits     = 19
+	quotBits  = 29 // quozienti mod T dei coefficienti: < N·2^16
+)
+
+// EncryptionCircuit prova che C cifra con la chiave pubblica di Params un
+// testo in chiaro i cui primi len(Values) slot sono Values, e tutti gli altri
+// zero; Sum è la somma dei valori e Commitment il Poseidon2 (kpihash) di
+// Salt e dei valori: il sale segreto lo rende nascondente oltre che
+// vincolante. Dopo innerSum lo slot 0 decifrato è quindi proprio Sum.
+type EncryptionCircuit struct {
+	Sum        frontend.Variable        `gnark:",public"`
+	Commitment frontend.Variable        `gnark:",public"`
+	C          [2][][]frontend.Variable `gnark:",public"` // C[c][i][k], coefficienti di ct mod q_i
+
+	Salt      frontend.Variable
+	Values    []frontend.Variable
+	M         []frontend.Variable
+	U         []frontend.Variable
+	E         [2][]frontend.Variable
+	K         [2][][]frontend.Variable
+	R         [2][][]frontend.Variable
+	CoeffQuot []frontend.Variable // uno per coefficiente di M
+
+	Params *Params `gnark:"-"`
+}
+
+// NewEncryptionCircuit alloca il circuito per nbValues valori KPI; va bene
+// anche come assignment vuoto da riempire.
+func NewEncryptionCircuit(p *Params, nbValues int) *EncryptionCircuit {
+	c := &EncryptionCircuit{
+		Values:    make([]frontend.Variable, nbValues),
+		M:         make([]frontend.Variable, p.N),
+		U:         make([]frontend.Variable, p.N),
+		CoeffQuot: make([]frontend.Variable, p.N),
+		Params:    p,
+	}
+	for j := range c.C {
+		c.C[j] = newMatrix(len(p.Q), p.N)
+		c.K[j] = newMatrix(len(p.Q), p.N)
+		c.R[j] = newMatrix(len(p.Q), p.N)
+		c.E[j] = make([]frontend.Variable, p.N)
+	}
+	return c
+}
+
+func newMatrix(rows, cols int) [][]frontend.Variable {
+	m := make([][]frontend.Variable, rows)
+	for i := range m {
+		m[i] = make([]frontend.Variable, cols)
+	}
+	return m
+}
+
+func (c *EncryptionCircuit) Define(api frontend.API) error {
+	p := c.Params
+	rc := rangecheck.New(api)
+
+	// 1. somma e commitment dei valori
+	for _, v := range c.Values {
+		rc.Check(v, sumBits)
+	}
+	api.AssertIsEqual(add(api, c.Values), c.Sum)
+	rc.Check(c.Sum, sumBits)
+	h, err := kpihash.NewGadget(api, kpihash.Poseidon2)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(h.Hash(append([]frontend.Variable{c.Salt}, c.Values...)...), c.Commitment)
+
+	// 2. testo in chiaro: M_k = Σ_j Values[j]·Basis[j][k] − T·q_k, cioè i
+	// valori nei primi slot e zero negli altri. La somma di tutti gli slot è
+	// N·m_0 mod T, quello che innerSum lascia nello slot 0: con gli altri slot a
+	// zero è Sum.
+	basis := p.Basis(len(c.Values))
+	for k := range c.M {
+		terms := make([]frontend.Variable, len(c.Values))
+		for j, v := range c.Values {
+			terms[j] = api.Mul(basis[j][k], v)
+		}
+		api.AssertIsEqual(c.M[k], api.Sub(add(api, terms), api.Mul(p.T, c.CoeffQuot[k])))
+		rc.Check(c.CoeffQuot[k], quotBits)
+	}
+
+	// 3. coefficienti piccoli, U ternario
+	for k := range c.M {
+		rc.Check(c.M[k], mBits)
+		assertTernary(api, c.U[k])
+		for j := range c.E {
+			rc.Check(api.Add(c.E[j][k], errOffset), errBits)
+		}
+	}
+	for j := range c.K {
+		for i := range c.K[j] {
+			for _, v := range c.K[j][i] {
+				rc.Check(api.Add(v, kOffset), kBits)
+			}
+		}
+	}
+
+	// 4. cifratura valutata in γ, scelto dopo aver fissato witness e ciphertext
+	var committed []frontend.Variable
+	committed = append(committed, c.M...)
+	committed = append(committed, c.U...)
+	for j := range c.C {
+		committed = append(committed, c.E[j]...)
+		for i := range c.C[j] {
+			committed = append(committed, c.C[j][i]...)
+			committed = append(committed, c.K[j][i]...)
+			committed = append(committed, c.R[j][i]...)
+		}
+	}
+	multicommit.WithCommitment(api, func(api frontend.API, gamma frontend.Variable) error {
+		pow, cyclo := powers(api, gamma, p.N)
+
+		m, u := eval(api, c.M, pow), eval(api, c.U, pow)
+		for j := range c.C {
+			e := eval(api, c.E[j], pow)
+			for i, q := range p.Q {
+				// pk(γ) ha coefficienti costanti: combinazione lineare gratuita
+				pk := evalConst(api, p.PK[j][i], pow)
+				rhs := api.Add(api.Mul(pk, u), e)
+				if j == 0 {
+					rhs = api.Add(rhs, api.Mul(p.Delta[i], m))
+				}
+				rhs = api.Sub(rhs, api.Mul(q, eval(api, c.K[j][i], pow)))
+				rhs = api.Sub(rhs, api.Mul(cyclo, eval(api, c.R[j][i], pow)))
+				api.AssertIsEqual(eval(api, c.C[j][i], pow), rhs)
+			}
+		}
+		return nil
+	}, committed...)
+	return nil
+}
+
+// assertTernary vincola v a {-1, 0, 1}: v(v-1)(v+1) = 0. Un range check su
+// v+1 a 2 bit lascerebbe passare anche 2.
+func assertTernary(api frontend.API, v frontend.Variable) {
+	api.AssertIsEqual(api.Mul(v, api.Sub(v, 1), api.Add(v, 1)), 0)
+}
+
+// powers restituisce 1, γ, ..., γ^(n-1) e γ^n + 1, il fattore del quoziente
+// per X^n+1.
+func powers(api frontend.API, gamma frontend.Variable, n int) ([]frontend.Variable, frontend.Variable) {
+	pow := make([]frontend.Variable, n)
+	pow[0] = 1
+	for k := 1; k < n; k++ {
+		pow[k] = api.Mul(pow[k-1], gamma)
+	}
+	return pow, api.Add(api.Mul(pow[n-1], gamma), 1)
+}
+
+// eval valuta il polinomio di coefficienti coeffs nelle potenze pow.
+func eval(api frontend.API, coeffs, pow []frontend.Variable) frontend.Variable {
+	terms := make([]frontend.Variable, len(coeffs))
+	for k := range coeffs {
+		terms[k] = api.Mul(coeffs[k], pow[k])
+	}
+	return add(api, terms)
+}
+
+func evalConst(api frontend.API, coeffs []uint64, pow []frontend.Variable) frontend.Variable {
+	terms := make([]frontend.Variable, len(coeffs))
+	for k := range coeffs {
+		terms[k] = api.Mul(coeffs[k], pow[k])
+	}
+	return add(api, terms)
+}
+
+// add somma tutto con una sola chiamata: sommare un termine alla volta
+// ricopia ogni volta la combinazione lineare, quadratico con N = 4096.
+func add(api frontend.API, terms []frontend.Variable) frontend.Variable {
+	return api.Add(0, 0, terms...)
+}
+
+// Assignment riempie il circuito con il witness del client.
+func (w *EncryptionWitness) Assignment(p *Params, ct *rlwe.Ciphertext) *EncryptionCircuit {
+	a := PublicAssignment(p, ct, len(w.Values), w.Sum, w.Commit.String())
+	a.Salt = w.Salt
+	for j, v := range w.Values {
+		a.Values[j] = v
+	}
+	for k := range w.M {
+		a.M[k] = w.M[k]
+		a.U[k] = w.U[k]
+		for j := range w.E {
+			a.E[j][k] = w.E[j][k]
+		}
+	}
+	for j := range w.K {
+		for i := range w.K[j] {
+			for k := range w.K[j][i] {
+				a.K[j][i][k] = w.K[j][i][k]
+				a.R[j][i][k] = w.R[j][i][k]
+			}
+		}
+	}
+
+	// quozienti mod T calcolati sugli interi, come nel circuito
+	basis := p.Basis(len(w.Values))
+	for k := range w.M {
+		var lc uint64
+		for j, v := range w.Values {
+			lc += basis[j][k] * v
+		}
+		a.CoeffQuot[k] = (lc - w.M[k]) / p.T
+	}
+	return a
+}
+
+// PublicAssignment contiene solo le parti pubbliche: quello che l'auditor
+// ricostruisce dal ciphertext ricevuto, dalla somma e dal commitment dichiarati.
+func PublicAssignment(p *Params, ct *rlwe.Ciphertext, nbValues int, sum uint64, commitment string) *EncryptionCircuit {
+	a := NewEncryptionCircuit(p, nbValues)
+	a.Sum = sum
+	a.Commitment = commitment
+	for j := range a.C {
+		for i := range a.C[j] {
+			for k := range a.C[j][i] {
+				a.C[j][i][k] = ct.Value[j].Coeffs[i][k]
+			}
+		}
+	}
+	return a
+}
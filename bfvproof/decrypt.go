@@ -0,0 +1,305 @@
+package bfvproof
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/multicommit"
+	"github.com/consensys/gnark/std/rangecheck"
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+)
+
+// quozienti della decifratura: |K| ≤ N + T + 2^NoiseBits/q_i + 1
+const (
+	decKOffset = 1 << 62
+	decKBits   = 63
+)
+
+// DecryptionCircuit prova, senza rivelare la chiave segreta, che C si decifra
+// nel testo in chiaro costante Sum, cioè con Sum in ogni slot, come il
+// risultato di innerSum. La chiave S è legata alla chiave pubblica di Params:
+//
+//	pk0 + pk1·S = EPK              (mod q_i, X^N+1)
+//	C0 + C1·S = Δ·Sum + V          (mod q_i, X^N+1)
+//
+// con S ternaria, EPK piccolo e |V| < 2^NoiseBits ≤ Δ/4: è esattamente la
+// condizione perché BFV decifri Sum.
+type DecryptionCircuit struct {
+	Sum frontend.Variable        `gnark:",public"`
+	C   [2][][]frontend.Variable `gnark:",public"` // C[c][i][k], coefficienti di ct mod q_i
+
+	S   []frontend.Variable
+	EPK []frontend.Variable
+	V   []frontend.Variable
+	KPK [][]frontend.Variable // quozienti per q_i della relazione sulla chiave
+	RPK [][]frontend.Variable // e per X^N+1
+	K   [][]frontend.Variable
+	R   [][]frontend.Variable
+
+	Params *Params `gnark:"-"`
+}
+
+// NewDecryptionCircuit alloca il circuito, anche come assignment vuoto.
+func NewDecryptionCircuit(p *Params) *DecryptionCircuit {
+	c := &DecryptionCircuit{
+		S:      make([]frontend.Variable, p.N),
+		EPK:    make([]frontend.Variable, p.N),
+		V:      make([]frontend.Variable, p.N),
+		KPK:    newMatrix(len(p.Q), p.N),
+		RPK:    newMatrix(len(p.Q), p.N),
+		K:      newMatrix(len(p.Q), p.N),
+		R:      newMatrix(len(p.Q), p.N),
+		Params: p,
+	}
+	for j := range c.C {
+		c.C[j] = newMatrix(len(p.Q), p.N)
+	}
+	return c
+}
+
+func (c *DecryptionCircuit) Define(api frontend.API) error {
+	p := c.Params
+	rc := rangecheck.New(api)
+
+	// coefficienti piccoli, S ternaria
+	rc.Check(c.Sum, sumBits)
+	noiseOffset := new(big.Int).Lsh(big.NewInt(1), uint(p.NoiseBits))
+	for k := 0; k < p.N; k++ {
+		assertTernary(api, c.S[k])
+		rc.Check(api.Add(c.EPK[k], errOffset), errBits)
+		rc.Check(api.Add(c.V[k], noiseOffset), p.NoiseBits+1)
+	}
+	for i := range p.Q {
+		for k := 0; k < p.N; k++ {
+			rc.Check(api.Add(c.KPK[i][k], kOffset), kBits)
+			rc.Check(api.Add(c.K[i][k], decKOffset), decKBits)
+		}
+	}
+
+	var committed []frontend.Variable
+	committed = append(committed, c.S...)
+	committed = append(committed, c.EPK...)
+	committed = append(committed, c.V...)
+	for i := range p.Q {
+		committed = append(committed, c.C[0][i]...)
+		committed = append(committed, c.C[1][i]...)
+		committed = append(committed, c.KPK[i]...)
+		committed = append(committed, c.RPK[i]...)
+		committed = append(committed, c.K[i]...)
+		committed = append(committed, c.R[i]...)
+	}
+	multicommit.WithCommitment(api, func(api frontend.API, gamma frontend.Variable) error {
+		pow, cyclo := powers(api, gamma, p.N)
+		s, epk, v := eval(api, c.S, pow), eval(api, c.EPK, pow), eval(api, c.V, pow)
+		for i, q := range p.Q {
+			// la chiave: pk0 + pk1·s = e
+			lhs := api.Add(evalConst(api, p.PK[0][i], pow), api.Mul(evalConst(api, p.PK[1][i], pow), s))
+			rhs := api.Add(epk, api.Mul(q, eval(api, c.KPK[i], pow)), api.Mul(cyclo, eval(api, c.RPK[i], pow)))
+			api.AssertIsEqual(lhs, rhs)
+
+			// la decifratura: c0 + c1·s = Δ·Sum + v
+			lhs = api.Add(eval(api, c.C[0][i], pow), api.Mul(eval(api, c.C[1][i], pow), s))
+			rhs = api.Add(api.Mul(p.Delta[i], c.Sum), v, api.Mul(q, eval(api, c.K[i], pow)), api.Mul(cyclo, eval(api, c.R[i], pow)))
+			api.AssertIsEqual(lhs, rhs)
+		}
+		return nil
+	}, committed...)
+	return nil
+}
+
+// DecryptionWitness è il witness del possessore della chiave segreta.
+type DecryptionWitness struct {
+	Sum uint64
+	S   []int64
+	EPK []int64
+	V   []*big.Int
+	KPK [][]int64
+	RPK [][]int64
+	K   [][]*big.Int
+	R   [][]int64
+}
+
+// Decrypt decifra ct con sk come BFV e prepara il witness per
+// DecryptionCircuit. Il testo in chiaro deve essere costante, con lo stesso
+// valore in tutti gli slot.
+func Decrypt(params bfv.Parameters, p *Params, sk *rlwe.SecretKey, ct *rlwe.Ciphertext) (*DecryptionWitness, error) {
+	if ct.Degree() != 1 || ct.Level() != len(p.Q)-1 {
+		return nil, fmt.Errorf("bfvproof: serve un ciphertext di grado 1 a livello %d", len(p.Q)-1)
+	}
+
+	// la chiave segreta di lattigo è in NTT e Montgomery: riporto i
+	// coefficienti in {-1, 0, 1}
+	ringQ := params.RingQ()
+	skQ := sk.Value.Q.CopyNew()
+	ringQ.InvMForm(skQ, skQ)
+	ringQ.InvNTT(skQ, skQ)
+	w := &DecryptionWitness{S: make([]int64, p.N)}
+	for k := range w.S {
+		switch skQ.Coeffs[0][k] {
+		case 0:
+		case 1:
+			w.S[k] = 1
+		case p.Q[0] - 1:
+			w.S[k] = -1
+		default:
+			return nil, fmt.Errorf("bfvproof: chiave segreta non ternaria")
+		}
+	}
+
+	// chiave: e = pk0 + pk1·s, lo stesso intero piccolo su ogni modulo
+	w.KPK = make([][]int64, len(p.Q))
+	w.RPK = make([][]int64, len(p.Q))
+	for i, q := range p.Q {
+		lo, hi := mulTernary(p.PK[1][i], w.S)
+		w.KPK[i] = make([]int64, p.N)
+		w.RPK[i] = hi
+		for k := range lo {
+			x := int64(p.PK[0][i][k]) + lo[k] - hi[k]
+			e := centered(x, q)
+			if i == 0 {
+				w.EPK = append(w.EPK, e)
+			} else if e != w.EPK[k] {
+				return nil, fmt.Errorf("bfvproof: la chiave segreta non corrisponde alla chiave pubblica")
+			}
+			if e < -ErrBound || e > ErrBound {
+				return nil, fmt.Errorf("bfvproof: errore della chiave pubblica %d oltre %d", e, ErrBound)
+			}
+			w.KPK[i][k] = (x - e) / int64(q)
+		}
+	}
+
+	// c0 + c1·s su ogni modulo, ricomposto mod Q con il CRT
+	x := make([][]int64, len(p.Q))
+	w.R = make([][]int64, len(p.Q))
+	for i := range p.Q {
+		lo, hi := mulTernary(ct.Value[1].Coeffs[i], w.S)
+		x[i] = make([]int64, p.N)
+		w.R[i] = hi
+		for k := range lo {
+			x[i][k] = int64(ct.Value[0].Coeffs[i][k]) + lo[k] - hi[k]
+		}
+	}
+	bigQ, crt := crtBasis(p.Q)
+	halfQ := new(big.Int).Rsh(bigQ, 1)
+	bigT := new(big.Int).SetUint64(p.T)
+	delta := new(big.Int).Quo(bigQ, bigT)
+
+	y := make([]*big.Int, p.N)
+	for k := range y {
+		y[k] = new(big.Int)
+		for i, q := range p.Q {
+			r := new(big.Int).SetUint64(uint64(centered(x[i][k], q) + int64(q)))
+			y[k].Add(y[k], r.Mul(r, crt[i]))
+		}
+		y[k].Mod(y[k], bigQ)
+	}
+
+	// m_k = round(T·y/Q) mod T, come la decifratura di lattigo
+	for k := range y {
+		m := new(big.Int).Mul(y[k], bigT)
+		m.Add(m, halfQ).Quo(m, bigQ).Mod(m, bigT)
+		if k == 0 {
+			w.Sum = m.Uint64()
+		} else if m.Sign() != 0 {
+			return nil, fmt.Errorf("bfvproof: testo in chiaro non costante (coefficiente %d = %v)", k, m)
+		}
+	}
+	if w.Sum >= 1<<sumBits {
+		return nil, fmt.Errorf("bfvproof: somma %d oltre 2^%d", w.Sum, sumBits)
+	}
+
+	// rumore v = y − Δ·Sum centrato mod Q, poi i quozienti per q_i
+	bound := new(big.Int).Lsh(big.NewInt(1), uint(p.NoiseBits))
+	deltaSum := new(big.Int).Mul(delta, new(big.Int).SetUint64(w.Sum))
+	w.V = make([]*big.Int, p.N)
+	w.K = make([][]*big.Int, len(p.Q))
+	for i := range w.K {
+		w.K[i] = make([]*big.Int, p.N)
+	}
+	for k := range y {
+		// il testo in chiaro è costante: Δ·Sum solo nel coefficiente 0
+		v := new(big.Int).Set(y[k])
+		if k == 0 {
+			v.Sub(v, deltaSum)
+		}
+		v.Mod(v, bigQ)
+		if v.Cmp(halfQ) > 0 {
+			v.Sub(v, bigQ)
+		}
+		if new(big.Int).Abs(v).Cmp(bound) >= 0 {
+			return nil, fmt.Errorf("bfvproof: rumore del coefficiente %d di %d bit, oltre i %d decifrabili con certezza", k, v.BitLen(), p.NoiseBits)
+		}
+		w.V[k] = v
+		for i, q := range p.Q {
+			kq := big.NewInt(x[i][k])
+			if k == 0 {
+				kq.Sub(kq, new(big.Int).Mul(new(big.Int).SetUint64(p.Delta[i]), new(big.Int).SetUint64(w.Sum)))
+			}
+			kq.Sub(kq, v)
+			w.K[i][k] = kq.Quo(kq, new(big.Int).SetUint64(q))
+		}
+	}
+	return w, nil
+}
+
+// centered riduce x mod q in (−q/2, q/2].
+func centered(x int64, q uint64) int64 {
+	r := x % int64(q)
+	if r < 0 {
+		r += int64(q)
+	}
+	if uint64(r) > q/2 {
+		r -= int64(q)
+	}
+	return r
+}
+
+// crtBasis restituisce Q e i coefficienti del CRT (Q/q_i)·((Q/q_i)⁻¹ mod q_i).
+func crtBasis(moduli []uint64) (*big.Int, []*big.Int) {
+	bigQ := big.NewInt(1)
+	for _, q := range moduli {
+		bigQ.Mul(bigQ, new(big.Int).SetUint64(q))
+	}
+	crt := make([]*big.Int, len(moduli))
+	for i, q := range moduli {
+		bq := new(big.Int).SetUint64(q)
+		qi := new(big.Int).Quo(bigQ, bq)
+		crt[i] = new(big.Int).ModInverse(qi, bq)
+		crt[i].Mul(crt[i], qi)
+	}
+	return bigQ, crt
+}
+
+// Assignment riempie il circuito con il witness di chi ha la chiave.
+func (w *DecryptionWitness) Assignment(p *Params, ct *rlwe.Ciphertext) *DecryptionCircuit {
+	a := PublicDecryption(p, ct, w.Sum)
+	for k := 0; k < p.N; k++ {
+		a.S[k] = w.S[k]
+		a.EPK[k] = w.EPK[k]
+		a.V[k] = w.V[k]
+		for i := range p.Q {
+			a.KPK[i][k] = w.KPK[i][k]
+			a.RPK[i][k] = w.RPK[i][k]
+			a.K[i][k] = w.K[i][k]
+			a.R[i][k] = w.R[i][k]
+		}
+	}
+	return a
+}
+
+// PublicDecryption contiene solo ciphertext e somma annunciata, le parti che
+// l'auditor conosce.
+func PublicDecryption(p *Params, ct *rlwe.Ciphertext, sum uint64) *DecryptionCircuit {
+	a := NewDecryptionCircuit(p)
+	a.Sum = sum
+	for j := range a.C {
+		for i := range a.C[j] {
+			for k := range a.C[j][i] {
+				a.C[j][i][k] = ct.Value[j].Coeffs[i][k]
+			}
+		}
+	}
+	return a
+}
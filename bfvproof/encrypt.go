@@ -0,0 +1,145 @@
+package bfvproof
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+
+	"zk-test/kpihash"
+)
+
+// EncryptionWitness contiene la casualità della cifratura e i quozienti che
+// servono al circuito; resta al client.
+type EncryptionWitness struct {
+	Values []uint64     // valori KPI negli slot 0..len-1
+	Sum    uint64       // somma dei valori, < T
+	M      []uint64     // coefficienti del testo in chiaro mod T
+	U      []int64      // polinomio ternario della cifratura
+	E      [2][]int64   // errori gaussiani
+	K      [2][][]int64 // quozienti per q_i
+	R      [2][][]int64 // quozienti per X^N+1
+	Salt   fr.Element   // casuale, tiene nascosti i valori nel commitment
+	Commit fr.Element   // Poseidon2 (kpihash) di sale e valori
+}
+
+// Encrypt cifra values con la chiave pubblica di p come farebbe BFV:
+//
+//	ct0 = pk0·u + e0 + Δ·m,  ct1 = pk1·u + e1  (mod q_i, mod X^N+1)
+//
+// ma tenendo u, e e i quozienti per la prova. Uso solo la parte Q della chiave
+// (lattigo cifra su QP e poi divide per P, un arrotondamento che nel circuito
+// costerebbe un range check da 30 bit per coefficiente); la decifratura è la
+// stessa.
+func Encrypt(params bfv.Parameters, p *Params, values []uint64) (*rlwe.Ciphertext, *EncryptionWitness, error) {
+	if len(values) > p.N {
+		return nil, nil, fmt.Errorf("bfvproof: %d valori, al massimo %d slot", len(values), p.N)
+	}
+	w := &EncryptionWitness{Values: values}
+	for _, v := range values {
+		w.Sum += v
+	}
+	if w.Sum >= 1<<sumBits {
+		return nil, nil, fmt.Errorf("bfvproof: somma %d oltre 2^%d, non rappresentabile mod T=%d", w.Sum, sumBits, p.T)
+	}
+
+	encoder := bfv.NewEncoder(params)
+	w.M = encoder.EncodeRingTNew(values).Value.Coeffs[0]
+
+	var seed [32]byte
+	if _, err := crand.Read(seed[:]); err != nil {
+		return nil, nil, err
+	}
+	rng := rand.New(rand.NewChaCha8(seed))
+	w.U = make([]int64, p.N)
+	for k := range w.U {
+		w.U[k] = rng.Int64N(3) - 1
+	}
+	for c := range w.E {
+		w.E[c] = make([]int64, p.N)
+		for k := range w.E[c] {
+			w.E[c][k] = gaussian(rng)
+		}
+	}
+
+	ct := w.encrypt(params, p)
+
+	// senza sale i valori KPI, pochi bit ciascuno, si ritroverebbero dal
+	// commitment per forza bruta
+	native, err := kpihash.New(kpihash.Poseidon2)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := w.Salt.SetRandom(); err != nil {
+		return nil, nil, err
+	}
+	w.Commit = native.Hash(append([]fr.Element{w.Salt}, toElements(values)...)...)
+	return ct, w, nil
+}
+
+// encrypt calcola ct e i quozienti K e R da M, U ed E.
+func (w *EncryptionWitness) encrypt(params bfv.Parameters, p *Params) *rlwe.Ciphertext {
+	ct := bfv.NewCiphertext(params, 1, params.MaxLevel())
+	for c := range w.K {
+		w.K[c] = make([][]int64, len(p.Q))
+		w.R[c] = make([][]int64, len(p.Q))
+		for i, q := range p.Q {
+			lo, hi := mulTernary(p.PK[c][i], w.U)
+			w.K[c][i] = make([]int64, p.N)
+			w.R[c][i] = hi
+			coeffs := ct.Value[c].Coeffs[i]
+			for k := range coeffs {
+				x := lo[k] - hi[k] + w.E[c][k]
+				if c == 0 {
+					// Δ_i < q_i e m < T: NewParams limita q_i perché stia in int64
+					x += int64(p.Delta[i] * w.M[k])
+				}
+				r := x % int64(q)
+				if r < 0 {
+					r += int64(q)
+				}
+				coeffs[k] = uint64(r)
+				w.K[c][i][k] = (x - r) / int64(q)
+			}
+		}
+	}
+	return ct
+}
+
+// mulTernary calcola sugli interi a·u = lo + X^N·hi, con a a coefficienti in
+// [0, q) e u ternario: in R_q il prodotto è lo − hi.
+func mulTernary(a []uint64, u []int64) (lo, hi []int64) {
+	n := len(a)
+	full := make([]int64, 2*n)
+	for i, ui := range u {
+		if ui == 0 {
+			continue
+		}
+		for j, aj := range a {
+			full[i+j] += ui * int64(aj)
+		}
+	}
+	return full[:n], full[n:]
+}
+
+// gaussian campiona l'errore con σ = 3.2 troncato a ErrBound.
+func gaussian(rng *rand.Rand) int64 {
+	for {
+		e := int64(math.Round(rng.NormFloat64() * 3.2))
+		if e >= -ErrBound && e <= ErrBound {
+			return e
+		}
+	}
+}
+
+func toElements(values []uint64) []fr.Element {
+	res := make([]fr.Element, len(values))
+	for i, v := range values {
+		res[i].SetUint64(v)
+	}
+	return res
+}
@@ -0,0 +1,123 @@
+// Package bfvproof lega i ciphertext BFV di lattigo alle prove gnark: la
+// cifratura con la chiave pubblica viene rifatta nel circuito, così la prova
+// dimostra che il ciphertext pubblico contiene proprio i valori KPI di cui
+// prova la somma.
+//
+// Le relazioni sui polinomiali di R_q = Z_q[X]/(X^N+1) si controllano in un
+// punto γ scelto con Fiat-Shamir (commitment di gnark) su tutto il witness:
+// a(X)·b(X) ≡ c(X) mod (X^N+1, q) diventa a(γ)b(γ) = c(γ) + q·k(γ) + (γ^N+1)·r(γ)
+// con k a coefficienti piccoli (range check) e r libero. I coefficienti sono
+// tutti molto più piccoli del modulo di BN254, quindi l'identità su Fp vale
+// anche sugli interi.
+package bfvproof
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+)
+
+// ErrBound è il massimo |e| dell'errore gaussiano, 6σ con σ = 3.2 come lattigo.
+const ErrBound = 19
+
+// Params sono i parametri BFV e la chiave pubblica nella forma che serve al
+// circuito: coefficienti interi, niente NTT né Montgomery.
+type Params struct {
+	N         int
+	Q         []uint64      // moduli RNS di Q
+	T         uint64        // modulo del testo in chiaro
+	Delta     []uint64      // ⌊Q/T⌋ mod q_i
+	PK        [2][][]uint64 // PK[c][i] = componente c della chiave pubblica mod q_i
+	Roots     []uint64      // lo slot j vale m(Roots[j]) mod T
+	NoiseBits int           // rumore massimo che si decifra bene: 2^NoiseBits ≤ Δ/4
+}
+
+// NewParams ricava Params da parametri e chiave pubblica lattigo.
+func NewParams(params bfv.Parameters, pk *rlwe.PublicKey) (*Params, error) {
+	if params.MaxLevel() != len(params.Q())-1 {
+		return nil, errors.New("bfvproof: servono tutti i moduli di Q")
+	}
+	if params.T() >= 1<<mBits {
+		return nil, fmt.Errorf("bfvproof: t = %d, i range check del circuito reggono t < 2^%d", params.T(), mBits)
+	}
+	p := &Params{N: params.N(), Q: params.Q(), T: params.T()}
+
+	// Encrypt e Decrypt lavorano su int64: i prodotti per u e s ternari
+	// arrivano a N·q_i per parte, Δ_i·m a q_i·T
+	maxQ := uint64(math.MaxInt64) / (2*uint64(p.N) + p.T + ErrBound + 1)
+	for _, q := range p.Q {
+		if q > maxQ {
+			return nil, fmt.Errorf("bfvproof: modulo q = %d oltre 2^%d, i conti sugli int64 con N = %d e t = %d traboccherebbero", q, bits.Len64(maxQ)-1, p.N, p.T)
+		}
+	}
+
+	// Δ = ⌊Q/T⌋ ridotto su ogni modulo
+	bigQ := big.NewInt(1)
+	for _, q := range p.Q {
+		bigQ.Mul(bigQ, new(big.Int).SetUint64(q))
+	}
+	delta := new(big.Int).Quo(bigQ, new(big.Int).SetUint64(p.T))
+	p.Delta = make([]uint64, len(p.Q))
+	for i, q := range p.Q {
+		p.Delta[i] = new(big.Int).Mod(delta, new(big.Int).SetUint64(q)).Uint64()
+	}
+	p.NoiseBits = delta.BitLen() - 3
+
+	// la chiave pubblica di lattigo è in NTT e Montgomery su QP: tengo solo Q
+	ringQ := params.RingQ()
+	for c := range p.PK {
+		poly := pk.Value[c].Q.CopyNew()
+		if pk.IsMontgomery {
+			ringQ.InvMForm(poly, poly)
+		}
+		if pk.IsNTT {
+			ringQ.InvNTT(poly, poly)
+		}
+		p.PK[c] = make([][]uint64, len(p.Q))
+		for i := range p.Q {
+			p.PK[c][i] = append([]uint64(nil), poly.Coeffs[i][:p.N]...)
+		}
+	}
+
+	// radici degli slot: decodificando il polinomio X ogni slot vale la sua radice
+	encoder := bfv.NewEncoder(params)
+	x := bfv.NewPlaintextRingT(params)
+	x.Value.Coeffs[0][1] = 1
+	p.Roots = make([]uint64, p.N)
+	encoder.Decode(x, p.Roots)
+	return p, nil
+}
+
+// SlotValue restituisce m(Roots[j]) mod T, il valore dello slot j.
+func (p *Params) SlotValue(m []uint64, j int) uint64 {
+	var res, pow uint64 = 0, 1
+	for k := range m {
+		res = (res + m[k]*pow) % p.T
+		pow = pow * p.Roots[j] % p.T
+	}
+	return res
+}
+
+// Basis restituisce i coefficienti dei testi in chiaro con un 1 nello slot j
+// e zero altrove, per j < n: m_k = N⁻¹·Roots[j]^(-k) mod T. Un testo in
+// chiaro con valori v_j nei primi n slot ha m_k = Σ_j v_j·Basis[j][k] mod T.
+func (p *Params) Basis(n int) [][]uint64 {
+	t := new(big.Int).SetUint64(p.T)
+	nInv := new(big.Int).ModInverse(big.NewInt(int64(p.N)), t).Uint64()
+	basis := make([][]uint64, n)
+	for j := range basis {
+		rootInv := new(big.Int).ModInverse(new(big.Int).SetUint64(p.Roots[j]), t).Uint64()
+		basis[j] = make([]uint64, p.N)
+		w := nInv
+		for k := range basis[j] {
+			basis[j][k] = w
+			w = w * rootInv % p.T
+		}
+	}
+	return basis
+}
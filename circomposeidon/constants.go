@@ -0,0 +1,163 @@
+package circomposeidon
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// Le costanti di circomlib (poseidon_constants.js) vengono dallo script di
+// riferimento di Poseidon, generate_parameters_grain: round constant e matrice
+// MDS di Cauchy estratte dall'LFSR Grain inizializzato con i parametri
+// (campo primo, S-box x^5, 254 bit, t, RF, RP). Le rigenero qui invece di
+// copiare migliaia di righe di numeri; i vettori di test in poseidon.go
+// controllano che coincidano con circomlib.
+
+const fieldBits = 254
+
+// parametri di una larghezza t
+type params struct {
+	t, rf, rp int
+	c         []fr.Element   // (rf+rp)·t round constant
+	m         [][]fr.Element // matrice MDS t×t
+}
+
+var (
+	paramsMu    sync.Mutex
+	paramsCache = map[int]*params{}
+)
+
+// getParams restituisce (e mette in cache) i parametri per t = nInputs+1.
+func getParams(nInputs int) (*params, error) {
+	if nInputs < 1 || nInputs > len(partialRounds) {
+		return nil, fmt.Errorf("poseidon circom: %d input, supportati da 1 a %d", nInputs, len(partialRounds))
+	}
+	t := nInputs + 1
+
+	paramsMu.Lock()
+	defer paramsMu.Unlock()
+	if p, ok := paramsCache[t]; ok {
+		return p, nil
+	}
+	p := generate(t, fullRounds, partialRounds[t-2])
+	paramsCache[t] = p
+	return p, nil
+}
+
+// grain è l'LFSR a 80 bit dello script di riferimento.
+type grain struct {
+	state [80]uint8
+}
+
+func newGrain(t, rf, rp int) *grain {
+	var g grain
+	bits := g.state[:0]
+	push := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, uint8(v>>i)&1)
+		}
+	}
+	push(1, 2) // campo primo
+	push(0, 4) // S-box x^alpha
+	push(fieldBits, 12)
+	push(t, 12)
+	push(rf, 10)
+	push(rp, 10)
+	push(1<<30-1, 30)
+
+	for i := 0; i < 160; i++ {
+		g.step()
+	}
+	return &g
+}
+
+func (g *grain) step() uint8 {
+	s := &g.state
+	b := s[62] ^ s[51] ^ s[38] ^ s[23] ^ s[13] ^ s[0]
+	copy(s[:], s[1:])
+	s[79] = b
+	return b
+}
+
+// bit: i bit escono a coppie, il primo decide se tenere il secondo
+func (g *grain) bit() uint8 {
+	for {
+		keep := g.step()
+		b := g.step()
+		if keep == 1 {
+			return b
+		}
+	}
+}
+
+// bits legge n bit, il primo è il più significativo.
+func (g *grain) bits(n int) *big.Int {
+	v := new(big.Int)
+	for i := 0; i < n; i++ {
+		v.Lsh(v, 1)
+		if g.bit() == 1 {
+			v.SetBit(v, 0, 1)
+		}
+	}
+	return v
+}
+
+func generate(t, rf, rp int) *params {
+	g := newGrain(t, rf, rp)
+	p := &params{t: t, rf: rf, rp: rp}
+
+	// round constant: rejection sampling sotto il modulo
+	modulus := fr.Modulus()
+	p.c = make([]fr.Element, (rf+rp)*t)
+	for i := range p.c {
+		v := g.bits(fieldBits)
+		for v.Cmp(modulus) >= 0 {
+			v = g.bits(fieldBits)
+		}
+		p.c[i].SetBigInt(v)
+	}
+
+	// matrice di Cauchy M[i][j] = 1/(x_i + y_j) con x, y tutti distinti
+	for {
+		xy := make([]fr.Element, 2*t)
+		for i := range xy {
+			xy[i].SetBigInt(g.bits(fieldBits))
+		}
+		if !distinct(xy) {
+			continue
+		}
+		m, ok := cauchy(xy[:t], xy[t:])
+		if ok {
+			p.m = m
+			return p
+		}
+	}
+}
+
+func distinct(v []fr.Element) bool {
+	for i := range v {
+		for j := i + 1; j < len(v); j++ {
+			if v[i].Equal(&v[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func cauchy(xs, ys []fr.Element) ([][]fr.Element, bool) {
+	m := make([][]fr.Element, len(xs))
+	for i := range xs {
+		m[i] = make([]fr.Element, len(ys))
+		for j := range ys {
+			m[i][j].Add(&xs[i], &ys[j])
+			if m[i][j].IsZero() {
+				return nil, false
+			}
+			m[i][j].Inverse(&m[i][j])
+		}
+	}
+	return m, true
+}
@@ -0,0 +1,48 @@
+package circomposeidon
+
+import (
+	"github.com/consensys/gnark/frontend"
+)
+
+// HashGadget è Hash dentro un circuito gnark. Le costanti sono le stesse del
+// nativo: i round parziali costano un solo x^5, quindi Poseidon(2) sono circa
+// 240 vincoli R1CS.
+func HashGadget(api frontend.API, inputs ...frontend.Variable) (frontend.Variable, error) {
+	p, err := getParams(len(inputs))
+	if err != nil {
+		return nil, err
+	}
+
+	state := make([]frontend.Variable, p.t)
+	state[0] = 0
+	copy(state[1:], inputs)
+	tmp := make([]frontend.Variable, p.t)
+
+	for r := 0; r < p.rf+p.rp; r++ {
+		for i := range state {
+			state[i] = api.Add(state[i], p.c[r*p.t+i])
+		}
+		if p.full(r) {
+			for i := range state {
+				state[i] = sboxGadget(api, state[i])
+			}
+		} else {
+			state[0] = sboxGadget(api, state[0])
+		}
+		// la moltiplicazione per costanti non genera vincoli
+		for i := range tmp {
+			tmp[i] = api.Mul(p.m[i][0], state[0])
+			for j := 1; j < p.t; j++ {
+				tmp[i] = api.Add(tmp[i], api.Mul(p.m[i][j], state[j]))
+			}
+		}
+		copy(state, tmp)
+	}
+	return state[0], nil
+}
+
+func sboxGadget(api frontend.API, x frontend.Variable) frontend.Variable {
+	x2 := api.Mul(x, x)
+	x4 := api.Mul(x2, x2)
+	return api.Mul(x4, x)
+}
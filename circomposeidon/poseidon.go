@@ -0,0 +1,97 @@
+// Package circomposeidon implementa Poseidon (la versione originale, non
+// Poseidon2) compatibile con circomlib, sia nativo sia come gadget gnark.
+//
+// Con lo stesso hash di poseidon.circom i partner che usano circom/snarkjs
+// possono ricalcolare le nostre root e i nostri commitment: Hash(x) è
+// Poseidon(1) di circomlib, Hash(l, r) è Poseidon(2), quello dei Merkle tree.
+//
+// Parametri di circomlib su BN254: S-box x^5, t = nInputs+1, 8 round completi
+// e round parziali che dipendono da t, stato iniziale [0, input...], digest
+// state[0].
+package circomposeidon
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+const fullRounds = 8
+
+// round parziali di circomlib per t = 2..17
+var partialRounds = []int{56, 57, 56, 60, 60, 63, 64, 63, 60, 66, 60, 65, 70, 60, 64, 68}
+
+// TestVectors sono output di poseidon di circomlibjs, usati da Check.
+var TestVectors = []struct {
+	Inputs []uint64
+	Digest string
+}{
+	{[]uint64{1}, "18586133768512220936620570745912940619677854269274689475585506675881198879027"},
+	{[]uint64{1, 2}, "7853200120776062878684798364095072458815029376092732009249414926327459813530"},
+	{[]uint64{1, 2, 3, 4}, "18821383157269793795438455681495246036402687001665670618754263018637548127333"},
+}
+
+// Hash calcola Poseidon(inputs) come circomlib.
+func Hash(inputs ...fr.Element) (fr.Element, error) {
+	p, err := getParams(len(inputs))
+	if err != nil {
+		return fr.Element{}, err
+	}
+
+	state := make([]fr.Element, p.t)
+	copy(state[1:], inputs)
+	tmp := make([]fr.Element, p.t)
+
+	for r := 0; r < p.rf+p.rp; r++ {
+		for i := range state {
+			state[i].Add(&state[i], &p.c[r*p.t+i])
+		}
+		if p.full(r) {
+			for i := range state {
+				sbox(&state[i])
+			}
+		} else {
+			sbox(&state[0])
+		}
+		for i := range tmp {
+			tmp[i].SetZero()
+			for j := range state {
+				var v fr.Element
+				v.Mul(&p.m[i][j], &state[j])
+				tmp[i].Add(&tmp[i], &v)
+			}
+		}
+		copy(state, tmp)
+	}
+	return state[0], nil
+}
+
+// full dice se il round r è completo: i primi e gli ultimi rf/2.
+func (p *params) full(r int) bool {
+	return r < p.rf/2 || r >= p.rf/2+p.rp
+}
+
+func sbox(x *fr.Element) {
+	var x2 fr.Element
+	x2.Square(x)
+	x2.Square(&x2)
+	x.Mul(x, &x2)
+}
+
+// Check confronta Hash con i vettori di circomlib.
+func Check() error {
+	for _, tv := range TestVectors {
+		inputs := make([]fr.Element, len(tv.Inputs))
+		for i, v := range tv.Inputs {
+			inputs[i].SetUint64(v)
+		}
+		h, err := Hash(inputs...)
+		if err != nil {
+			return err
+		}
+		if h.String() != tv.Digest {
+			return fmt.Errorf("poseidon circom %v = %s, circomlib %s", tv.Inputs, h.String(), tv.Digest)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package circomposeidon
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+// vectors sono i vettori di poseidon di circomlibjs (test/poseidon.js), più
+// quelli di TestVectors.
+var vectors = append([]struct {
+	Inputs []uint64
+	Digest string
+}{
+	{[]uint64{3, 4}, "14763215145315200506921711489642608356394854266165572616578112107564877678998"},
+	{[]uint64{1, 2, 0, 0, 0}, "1018317224307729531995786483840663576608797660851238720571059489595066344487"},
+	{[]uint64{3, 4, 5, 10, 23}, "13034429309846638789535561449942021891039729847501137143363028890275222221409"},
+}, TestVectors...)
+
+func elements(v []uint64) []fr.Element {
+	res := make([]fr.Element, len(v))
+	for i := range v {
+		res[i].SetUint64(v[i])
+	}
+	return res
+}
+
+func TestHash(t *testing.T) {
+	for _, tv := range vectors {
+		h, err := Hash(elements(tv.Inputs)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.String() != tv.Digest {
+			t.Errorf("poseidon%v = %s, circomlib %s", tv.Inputs, h.String(), tv.Digest)
+		}
+	}
+	if err := Check(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type hashCircuit struct {
+	Inputs []frontend.Variable
+	Digest frontend.Variable `gnark:",public"`
+}
+
+func (c *hashCircuit) Define(api frontend.API) error {
+	h, err := HashGadget(api, c.Inputs...)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(h, c.Digest)
+	return nil
+}
+
+func TestHashGadget(t *testing.T) {
+	for _, tv := range vectors {
+		t.Run(fmt.Sprint(tv.Inputs), func(t *testing.T) {
+			circuit := &hashCircuit{Inputs: make([]frontend.Variable, len(tv.Inputs))}
+			assignment := &hashCircuit{Inputs: make([]frontend.Variable, len(tv.Inputs)), Digest: tv.Digest}
+			for i, v := range tv.Inputs {
+				assignment.Inputs[i] = v
+			}
+			if err := test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()); err != nil {
+				t.Fatal(err)
+			}
+
+			// digest sbagliato
+			assignment.Digest = 0
+			if test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()) == nil {
+				t.Fatal("digest sbagliato accettato")
+			}
+		})
+	}
+}
+
+func TestParams(t *testing.T) {
+	if _, err := Hash(); err == nil {
+		t.Fatal("Poseidon senza input accettato")
+	}
+	if _, err := Hash(make([]fr.Element, len(partialRounds)+1)...); err == nil {
+		t.Fatal("Poseidon con troppi input accettato")
+	}
+}
@@ -0,0 +1,105 @@
+// Package evm porta le prove dei circuiti KPI su chain EVM: genera il contratto
+// verificatore Solidity di gnark dalla verifying key, codifica la calldata di una
+// prova con i suoi input pubblici (ExpectedSum, Root, ...) e la esegue su una EVM
+// in memoria di go-ethereum.
+package evm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	plonk_bn254 "github.com/consensys/gnark/backend/plonk/bn254"
+	"github.com/consensys/gnark/backend/solidity"
+	"golang.org/x/crypto/sha3"
+
+	"zk-test/zkbackend"
+)
+
+const word = 32
+
+// solidityExporter è implementato dalle verifying key Groth16 e PLONK BN254.
+type solidityExporter interface {
+	ExportSolidity(w io.Writer, exportOpts ...solidity.ExportOption) error
+}
+
+// WriteVerifier scrive in path il contratto verificatore per vk.
+func WriteVerifier(path string, vk zkbackend.Serializable) error {
+	exporter, ok := vk.(solidityExporter)
+	if !ok {
+		return fmt.Errorf("verifying key %T non esportabile in Solidity", vk)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := exporter.ExportSolidity(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Calldata codifica in ABI la chiamata al verificatore:
+//   - Groth16: verifyProof(uint256[8] proof, uint256[N] input)
+//   - PLONK:   Verify(bytes proof, uint256[] public_inputs)
+func Calldata(b zkbackend.Backend, proof zkbackend.Proof, public fr.Vector) ([]byte, error) {
+	switch b {
+	case zkbackend.Groth16:
+		p, ok := proof.(*groth16_bn254.Proof)
+		if !ok {
+			return nil, fmt.Errorf("prova %T non è groth16 BN254", proof)
+		}
+		if len(p.Commitments) > 0 {
+			return nil, errors.New("calldata per prove con commitment non supportata")
+		}
+		data := selector(fmt.Sprintf("verifyProof(uint256[8],uint256[%d])", len(public)))
+		data = append(data, p.MarshalSolidity()...)
+		return append(data, encodeVector(public)...), nil
+
+	case zkbackend.Plonk:
+		p, ok := proof.(*plonk_bn254.Proof)
+		if !ok {
+			return nil, fmt.Errorf("prova %T non è plonk BN254", proof)
+		}
+		raw := p.MarshalSolidity()
+		padded := (len(raw) + word - 1) / word * word
+
+		// testa: offset di bytes e di uint256[], poi le due code
+		data := selector("Verify(bytes,uint256[])")
+		data = append(data, encodeUint(2*word)...)
+		data = append(data, encodeUint(uint64(2*word+word+padded))...)
+		data = append(data, encodeUint(uint64(len(raw)))...)
+		data = append(data, raw...)
+		data = append(data, make([]byte, padded-len(raw))...)
+		data = append(data, encodeUint(uint64(len(public)))...)
+		return append(data, encodeVector(public)...), nil
+	}
+	return nil, fmt.Errorf("backend sconosciuto %q", b)
+}
+
+// selector restituisce i primi 4 byte di keccak256 della firma.
+func selector(signature string) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(signature))
+	return h.Sum(nil)[:4]
+}
+
+func encodeUint(v uint64) []byte {
+	b := make([]byte, word)
+	binary.BigEndian.PutUint64(b[word-8:], v)
+	return b
+}
+
+func encodeVector(v fr.Vector) []byte {
+	res := make([]byte, 0, len(v)*word)
+	for i := range v {
+		b := v[i].Bytes()
+		res = append(res, b[:]...)
+	}
+	return res
+}
@@ -0,0 +1,126 @@
+package evm
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+
+	"zk-test/zkbackend"
+)
+
+// go test ./evm -update rigenera chiavi e Verifier.sol in testdata; poi va
+// rieseguito testdata/solc.sh per il bytecode.
+var update = flag.Bool("update", false, "rigenera testdata/groth16 e testdata/plonk")
+
+// sumCircuit ha due input pubblici come i circuiti KPI (ExpectedSum, Root).
+type sumCircuit struct {
+	A   frontend.Variable `gnark:",secret"`
+	B   frontend.Variable `gnark:",secret"`
+	Sum frontend.Variable `gnark:",public"`
+	Mul frontend.Variable `gnark:",public"`
+}
+
+func (c *sumCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Add(c.A, c.B), c.Sum)
+	api.AssertIsEqual(api.Mul(c.A, c.B), c.Mul)
+	return nil
+}
+
+// system legge le chiavi in testdata/<b>; con -update le rigenera insieme a
+// Verifier.sol e cancella il bytecode compilato per le chiavi vecchie.
+func system(t *testing.T, b zkbackend.Backend) (*zkbackend.System, string) {
+	t.Helper()
+	dir := filepath.Join("testdata", string(b))
+	if !*update {
+		s, err := zkbackend.Load(ecc.BN254, b, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s, dir
+	}
+
+	ccs, err := zkbackend.Compile(ecc.BN254, b, &sumCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// per PLONK un SRS di sviluppo, che non serve tenere
+	s, err := zkbackend.Setup(b, ccs, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteVerifier(filepath.Join(dir, "Verifier.sol"), s.VerifyingKey); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(dir, "Verifier.bin"))
+	return s, dir
+}
+
+// creation usa il bytecode registrato da testdata/solc.sh, altrimenti compila
+// Verifier.sol con solc.
+func creation(t *testing.T, b zkbackend.Backend, dir string) []byte {
+	t.Helper()
+	code, err := ReadBin(filepath.Join(dir, "Verifier.bin"))
+	if os.IsNotExist(err) {
+		code, err = Compile(b, filepath.Join(dir, "Verifier.sol"))
+		if errors.Is(err, ErrNoSolc) {
+			t.Skip("Verifier.bin mancante e solc non installato: eseguire testdata/solc.sh")
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// Il Verifier.sol di gnark compilato viene deployato ed eseguito sulla EVM:
+// deve accettare la prova e rifiutarla con un input pubblico alterato.
+func TestVerifierContract(t *testing.T) {
+	for _, b := range []zkbackend.Backend{zkbackend.Groth16, zkbackend.Plonk} {
+		t.Run(string(b), func(t *testing.T) {
+			s, dir := system(t, b)
+			chain, err := Deploy(creation(t, b, dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w, err := frontend.NewWitness(&sumCircuit{A: 3, B: 4, Sum: 7, Mul: 12}, ecc.BN254.ScalarField())
+			if err != nil {
+				t.Fatal(err)
+			}
+			proof, err := s.Prove(w)
+			if err != nil {
+				t.Fatal(err)
+			}
+			public := fr.Vector{fr.NewElement(7), fr.NewElement(12)}
+			calldata, err := Calldata(b, proof, public)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := chain.Verify(b, calldata); err != nil {
+				t.Fatal(err)
+			}
+
+			for i := range public {
+				bad := make(fr.Vector, len(public))
+				copy(bad, public)
+				bad[i].SetUint64(9999)
+				calldata, err := Calldata(b, proof, bad)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := chain.Verify(b, calldata); err == nil {
+					t.Fatalf("input[%d] alterato accettato dal contratto", i)
+				}
+			}
+		})
+	}
+}
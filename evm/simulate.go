@@ -0,0 +1,59 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+
+	"zk-test/zkbackend"
+)
+
+// Chain è una EVM in memoria con il verificatore già deployato.
+type Chain struct {
+	cfg      *runtime.Config
+	Verifier common.Address
+}
+
+// Deploy esegue il bytecode di creazione del verificatore (Compile o
+// solc --bin Verifier.sol) su una EVM vuota.
+func Deploy(creation []byte) (*Chain, error) {
+	cfg := newConfig()
+	_, address, _, err := runtime.Create(creation, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("deploy del verificatore: %w", err)
+	}
+	return &Chain{cfg: cfg, Verifier: address}, nil
+}
+
+// Call invia la calldata al verificatore.
+func (c *Chain) Call(calldata []byte) (ret []byte, gasUsed uint64, err error) {
+	ret, left, err := runtime.Call(c.Verifier, calldata, c.cfg)
+	return ret, c.cfg.GasLimit - left, err
+}
+
+// Verify esegue la calldata sul verificatore e restituisce nil solo se il
+// contratto accetta la prova. Il contratto Groth16 di gnark fa revert se la
+// prova non è valida, quello PLONK restituisce un bool.
+func (c *Chain) Verify(b zkbackend.Backend, calldata []byte) (gasUsed uint64, err error) {
+	ret, gas, err := c.Call(calldata)
+	if err != nil {
+		return gas, fmt.Errorf("revert del verificatore: %w", err)
+	}
+	if b == zkbackend.Plonk && (len(ret) != word || ret[word-1] != 1) {
+		return gas, errors.New("prova rifiutata dal verificatore")
+	}
+	return gas, nil
+}
+
+// gas massimo per chiamata, come il limite di un blocco mainnet
+const gasLimit = 30_000_000
+
+// newConfig crea una EVM con stato vuoto in memoria e tutti i fork attivi.
+func newConfig() *runtime.Config {
+	db, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	return &runtime.Config{State: db, GasLimit: gasLimit}
+}
@@ -0,0 +1,68 @@
+package evm
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"zk-test/zkbackend"
+)
+
+// ErrNoSolc indica che solc non è nel PATH: il bytecode va passato a mano.
+var ErrNoSolc = errors.New("solc non trovato nel PATH")
+
+// contracts è il nome del contratto nel Verifier.sol di gnark per ogni backend.
+var contracts = map[zkbackend.Backend]string{
+	zkbackend.Groth16: "Verifier",
+	zkbackend.Plonk:   "PlonkVerifier",
+}
+
+// Compile compila con solc il Verifier.sol scritto da WriteVerifier e
+// restituisce il bytecode di creazione del contratto.
+func Compile(b zkbackend.Backend, solPath string) ([]byte, error) {
+	name, ok := contracts[b]
+	if !ok {
+		return nil, fmt.Errorf("backend sconosciuto %q", b)
+	}
+	solc, err := exec.LookPath("solc")
+	if err != nil {
+		return nil, ErrNoSolc
+	}
+	out, err := exec.Command(solc, "--optimize", "--combined-json", "bin", solPath).Output()
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			return nil, fmt.Errorf("solc: %s", exit.Stderr)
+		}
+		return nil, err
+	}
+
+	// {"contracts": {"<file>:<contratto>": {"bin": "..."}}}
+	var res struct {
+		Contracts map[string]struct {
+			Bin string `json:"bin"`
+		} `json:"contracts"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, fmt.Errorf("output di solc: %w", err)
+	}
+	for id, c := range res.Contracts {
+		if strings.HasSuffix(id, ":"+name) {
+			return hex.DecodeString(c.Bin)
+		}
+	}
+	return nil, fmt.Errorf("contratto %s non trovato nell'output di solc", name)
+}
+
+// ReadBin legge un bytecode in hex, per esempio l'output di solc --bin.
+func ReadBin(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+}
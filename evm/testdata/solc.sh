@@ -0,0 +1,15 @@
+#!/bin/sh
+# Bytecode dei verificatori per evm_test.go: compila groth16/Verifier.sol e
+# plonk/Verifier.sol (scritti da go test ./evm -update) in Verifier.bin.
+# Serve solc 0.8.x nel PATH; i Verifier.bin committati sono di solc 0.8.30.
+set -e
+cd "$(dirname "$0")"
+work=$(mktemp -d)
+trap 'rm -rf "$work"' EXIT
+
+solc --version | tail -1
+for b in groth16 plonk; do
+    solc --optimize --bin -o "$work/$b" "$b/Verifier.sol"
+    cp "$work/$b"/*.bin "$b/Verifier.bin"
+    echo "$b/Verifier.bin"
+done
@@ -5,29 +5,56 @@ go 1.25.0
 require (
     github.com/consensys/gnark v0.14.0
     github.com/consensys/gnark-crypto v0.19.2
+    github.com/ethereum/go-ethereum v1.17.7
     github.com/mysteryon88/gnark-to-snarkjs v1.0.2
+    github.com/tuneinsight/lattigo/v4 v4.1.1
+    golang.org/x/crypto v0.55.0
 )
 
 require (
+    github.com/ProjectZKM/Ziren/crates/go-runtime/zkvm_runtime v0.0.0-20251001021608-1fe7b43fc4d6 // indirect
+    github.com/StackExchange/wmi v1.2.1 // indirect
+    github.com/VictoriaMetrics/fastcache v1.13.0 // indirect
     github.com/bits-and-blooms/bitset v1.24.0 // indirect
     github.com/blang/semver/v4 v4.0.0 // indirect
-    github.com/consensys/bavard v0.2.1 // indirect
-    github.com/davecgh/go-spew v1.1.1 // indirect
+    github.com/cespare/xxhash/v2 v2.3.0 // indirect
+    github.com/crate-crypto/go-eth-kzg v1.5.0 // indirect
+    github.com/deckarep/golang-set/v2 v2.6.0 // indirect
+    github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
+    github.com/emicklei/dot v1.6.2 // indirect
+    github.com/ethereum/c-kzg-4844/v2 v2.1.8 // indirect
+    github.com/ethereum/go-bigmodexpfix v0.0.0-20250911101455-f9e208c548ab // indirect
+    github.com/ferranbt/fastssz v0.1.4 // indirect
     github.com/fxamacker/cbor/v2 v2.9.0 // indirect
+    github.com/go-logr/logr v1.4.4 // indirect
+    github.com/go-logr/stdr v1.2.2 // indirect
+    github.com/go-ole/go-ole v1.3.0 // indirect
+    github.com/gofrs/flock v0.12.1 // indirect
+    github.com/golang/snappy v1.0.1-0.20260716114414-9ae09f520e93 // indirect
     github.com/google/pprof v0.0.0-20250820193118-f64d9cf942d6 // indirect
+    github.com/holiman/bloomfilter/v2 v2.0.3 // indirect
+    github.com/holiman/uint256 v1.3.2 // indirect
     github.com/ingonyama-zk/icicle-gnark/v3 v3.2.2 // indirect
+    github.com/klauspost/cpuid/v2 v2.0.9 // indirect
     github.com/mattn/go-colorable v0.1.14 // indirect
     github.com/mattn/go-isatty v0.0.20 // indirect
-    github.com/mmcloughlin/addchain v0.4.0 // indirect
-    github.com/pmezard/go-difflib v1.0.0 // indirect
+    github.com/minio/sha256-simd v1.0.0 // indirect
+    github.com/mitchellh/mapstructure v1.4.1 // indirect
     github.com/ronanh/intcomp v1.1.1 // indirect
     github.com/rs/zerolog v1.34.0 // indirect
-    github.com/stretchr/testify v1.10.0 // indirect
-    github.com/tuneinsight/lattigo/v4 v4.1.1 // indirect
+    github.com/shirou/gopsutil v3.21.4-0.20210419000835-c7a38de76ee5+incompatible // indirect
+    github.com/stretchr/testify v1.12.1 // indirect
+    github.com/supranational/blst v0.3.16 // indirect
+    github.com/tklauser/go-sysconf v0.3.12 // indirect
+    github.com/tklauser/numcpus v0.6.1 // indirect
     github.com/x448/float16 v0.8.4 // indirect
-    golang.org/x/crypto v0.41.0 // indirect
-    golang.org/x/sync v0.16.0 // indirect
-    golang.org/x/sys v0.35.0 // indirect
-    gopkg.in/yaml.v3 v3.0.1 // indirect
-    rsc.io/tmplfunc v0.0.3 // indirect
-)
\ No newline at end of file
+    go.opentelemetry.io/auto/sdk v1.2.1 // indirect
+    go.opentelemetry.io/otel v1.46.0 // indirect
+    go.opentelemetry.io/otel/metric v1.46.0 // indirect
+    go.opentelemetry.io/otel/trace v1.46.0 // indirect
+    go.yaml.in/yaml/v3 v3.0.5 // indirect
+    golang.org/x/exp v0.0.0-20250819193227-8b4c13bb791b // indirect
+    golang.org/x/sync v0.22.0 // indirect
+    golang.org/x/sys v0.47.0 // indirect
+    gopkg.in/yaml.v2 v2.4.0 // indirect
+)
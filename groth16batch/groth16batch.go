@@ -0,0 +1,133 @@
+// Package groth16batch verifica insieme più prove Groth16 su BN254 con la
+// stessa verification key, combinandole con coefficienti casuali in un'unica
+// multi-pairing. Se il batch viene rifiutato individua le prove non valide
+// verificandole una per una.
+package groth16batch
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+)
+
+// BatchError riporta gli indici delle prove che non passano la verifica singola
+// quando il batch viene rifiutato.
+type BatchError struct {
+	Failed []int
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch rifiutato: prove non valide %v", e.Failed)
+}
+
+// Verify verifica tutte le coppie (proof, public witness) contro la stessa vk.
+//
+// Ogni prova soddisfa e(A, B) = e(α, β)·e(L, γ)·e(C, δ) con L = K₀ + Σ wᵢ·Kᵢ.
+// Con coefficienti casuali rⱼ le equazioni vengono combinate in
+//
+//	Π e(rⱼ·Aⱼ, Bⱼ) · e(-Σ rⱼ·Lⱼ, γ) · e(-Σ rⱼ·Cⱼ, δ) · e(-(Σ rⱼ)·α, β) = 1
+//
+// cioè n+3 pairing invece di 4n. Se il batch fallisce ogni prova viene
+// verificata singolarmente per individuare quelle non valide.
+func Verify(vk *groth16_bn254.VerifyingKey, proofs []*groth16_bn254.Proof, publicWitnesses []fr.Vector) error {
+	if len(proofs) != len(publicWitnesses) {
+		return fmt.Errorf("%d prove ma %d witness pubblici", len(proofs), len(publicWitnesses))
+	}
+	if len(proofs) == 0 {
+		return nil
+	}
+	if len(vk.CommitmentKeys) > 0 {
+		return errors.New("batch verification non supportata per circuiti con commitment")
+	}
+	nbPublic := len(vk.G1.K) - 1
+	for j := range publicWitnesses {
+		if len(publicWitnesses[j]) != nbPublic {
+			return fmt.Errorf("witness %d: dimensione %d, attesa %d", j, len(publicWitnesses[j]), nbPublic)
+		}
+	}
+
+	// le prove con punti fuori dal sottogruppo vengono scartate subito
+	var invalid []int
+	for j, proof := range proofs {
+		if !proof.Ar.IsInSubGroup() || !proof.Bs.IsInSubGroup() || !proof.Krs.IsInSubGroup() {
+			invalid = append(invalid, j)
+		}
+	}
+	if len(invalid) > 0 {
+		return &BatchError{Failed: invalid}
+	}
+
+	n := len(proofs)
+	r := make([]fr.Element, n)
+	for j := range r {
+		if _, err := r[j].SetRandom(); err != nil {
+			return err
+		}
+	}
+
+	// Σ rⱼ·Lⱼ = (Σ rⱼ)·K₀ + Σᵢ (Σⱼ rⱼ·wⱼᵢ)·Kᵢ
+	var rSum fr.Element
+	scalarsK := make([]fr.Element, len(vk.G1.K))
+	for j := 0; j < n; j++ {
+		rSum.Add(&rSum, &r[j])
+		for i := range publicWitnesses[j] {
+			var t fr.Element
+			t.Mul(&r[j], &publicWitnesses[j][i])
+			scalarsK[i+1].Add(&scalarsK[i+1], &t)
+		}
+	}
+	scalarsK[0] = rSum
+
+	var kSum, cSum bn254.G1Affine
+	if _, err := kSum.MultiExp(vk.G1.K, scalarsK, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+
+	krs := make([]bn254.G1Affine, n)
+	for j := range proofs {
+		krs[j] = proofs[j].Krs
+	}
+	if _, err := cSum.MultiExp(krs, r, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+
+	var alphaSum bn254.G1Affine
+	var rSumBig big.Int
+	alphaSum.ScalarMultiplication(&vk.G1.Alpha, rSum.BigInt(&rSumBig))
+
+	P := make([]bn254.G1Affine, 0, n+3)
+	Q := make([]bn254.G2Affine, 0, n+3)
+	for j := range proofs {
+		var rA bn254.G1Affine
+		var rj big.Int
+		rA.ScalarMultiplication(&proofs[j].Ar, r[j].BigInt(&rj))
+		P = append(P, rA)
+		Q = append(Q, proofs[j].Bs)
+	}
+	kSum.Neg(&kSum)
+	cSum.Neg(&cSum)
+	alphaSum.Neg(&alphaSum)
+	P = append(P, kSum, cSum, alphaSum)
+	Q = append(Q, vk.G2.Gamma, vk.G2.Delta, vk.G2.Beta)
+
+	ok, err := bn254.PairingCheck(P, Q)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	// batch rifiutato: cerco le prove colpevoli una per una
+	for j := range proofs {
+		if err := groth16_bn254.Verify(proofs[j], vk, publicWitnesses[j]); err != nil {
+			invalid = append(invalid, j)
+		}
+	}
+	return &BatchError{Failed: invalid}
+}
@@ -0,0 +1,91 @@
+package groth16batch
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// squareCircuit dimostra di conoscere X con X² = Y.
+type squareCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *squareCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(c.X, c.X), c.Y)
+	return nil
+}
+
+// batch restituisce n prove valide di X = j+2 sulla stessa vk.
+func batch(t *testing.T, n int) (*groth16_bn254.VerifyingKey, []*groth16_bn254.Proof, []fr.Vector) {
+	t.Helper()
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &squareCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proofs := make([]*groth16_bn254.Proof, n)
+	publicWitnesses := make([]fr.Vector, n)
+	for j := range proofs {
+		x := j + 2
+		witness, err := frontend.NewWitness(&squareCircuit{X: x, Y: x * x}, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicWitness, err := witness.Public()
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := groth16.Prove(ccs, pk, witness)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proofs[j] = proof.(*groth16_bn254.Proof)
+		publicWitnesses[j] = publicWitness.Vector().(fr.Vector)
+	}
+	return vk.(*groth16_bn254.VerifyingKey), proofs, publicWitnesses
+}
+
+func TestVerify(t *testing.T) {
+	const n, bad = 6, 3
+	vk, proofs, publicWitnesses := batch(t, n)
+	if err := Verify(vk, proofs, publicWitnesses); err != nil {
+		t.Fatalf("batch valido rifiutato: %v", err)
+	}
+	if err := Verify(vk, nil, nil); err != nil {
+		t.Fatalf("batch vuoto rifiutato: %v", err)
+	}
+
+	// un segnale pubblico alterato: il fallback deve indicare solo la prova bad
+	publicWitnesses[bad][0].SetUint64(9999)
+	err := Verify(vk, proofs, publicWitnesses)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("atteso BatchError, ottenuto %v", err)
+	}
+	if !slices.Equal(batchErr.Failed, []int{bad}) {
+		t.Fatalf("prove non valide %v, attesa [%d]", batchErr.Failed, bad)
+	}
+
+	// due prove scambiate tra loro: ognuna è valida, ma non per il proprio witness
+	vk, proofs, publicWitnesses = batch(t, n)
+	proofs[1], proofs[4] = proofs[4], proofs[1]
+	if err := Verify(vk, proofs, publicWitnesses); !errors.As(err, &batchErr) || !slices.Equal(batchErr.Failed, []int{1, 4}) {
+		t.Fatalf("prove scambiate: %v, attese [1 4]", err)
+	}
+
+	if Verify(vk, proofs, publicWitnesses[:n-1]) == nil {
+		t.Fatal("accettato un witness in meno")
+	}
+}
@@ -0,0 +1,44 @@
+// Package kpicommit è il circuito da 128 slot di
+// zsnark_Poseidon_linear_commitment: la somma dei KPI ×1000 con un hash
+// pubblico per ogni valore al posto della radice di Merkle. Sta fuori dal main
+// perché zsnark_snarkjs_verify confronta il manifest con il suo schema.
+package kpicommit
+
+import (
+	"github.com/consensys/gnark/frontend"
+
+	"zk-test/kpihash"
+)
+
+const MaxValues = 128 // provo a usare 128 come valore esponenziale di 2, let s try!
+
+// Circuit prova che la somma dei valori è ExpectedSum e che Hashes[i] è
+// l'hash foglia di Values[i].
+type Circuit struct {
+	Hashes      [MaxValues]frontend.Variable `gnark:",public" kpi:"hash"`
+	ExpectedSum frontend.Variable            `gnark:",public" kpi:"sum,scale=1000"`
+
+	Values [MaxValues]frontend.Variable `gnark:",secret"`
+
+	Hash string `gnark:"-"` // kpihash.Poseidon2 o kpihash.Circom
+}
+
+func (c *Circuit) Define(api frontend.API) error {
+	h, err := kpihash.NewGadget(api, c.Hash)
+	if err != nil {
+		return err
+	}
+	totalSum := frontend.Variable(0)
+
+	for i := 0; i < MaxValues; i++ {
+		// Accumulo la somma
+		totalSum = api.Add(totalSum, c.Values[i])
+
+		// Verifico l'hash del singolo KPI (Mapping Lineare)
+		// Questo vincolo assicura la provenienza del dato
+		api.AssertIsEqual(h.Leaf(c.Values[i]), c.Hashes[i])
+	}
+
+	api.AssertIsEqual(totalSum, c.ExpectedSum)
+	return nil
+}
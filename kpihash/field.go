@@ -0,0 +1,197 @@
+package kpihash
+
+import (
+	"fmt"
+	"hash"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
+	mimc_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/mimc"
+	poseidon2_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/poseidon2"
+	fr_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	mimc_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/mimc"
+	poseidon2_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/poseidon2"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	gnark_hash "github.com/consensys/gnark-crypto/hash"
+)
+
+// Field è Native su una curva qualunque, con i valori come *big.Int ridotti
+// nel campo scalare: serve ai main che scelgono la curva con -curve.
+type Field interface {
+	Leaf(v *big.Int) *big.Int
+	Node(left, right *big.Int) *big.Int
+	Hash(inputs ...*big.Int) *big.Int
+}
+
+// NewField restituisce l'hash nativo di tipo kind sul campo scalare di curve.
+// Su BN254 è lo stesso di New; circom esiste solo su BN254, come circomlib.
+func NewField(curve ecc.ID, kind string) (Field, error) {
+	if curve == ecc.BN254 {
+		h, err := New(kind)
+		if err != nil {
+			return nil, err
+		}
+		return bn254Field{h}, nil
+	}
+	if kind == Circom {
+		return nil, fmt.Errorf("hash circom solo su bn254, non su %s", curve)
+	}
+	if kind != Poseidon2 && kind != MiMC {
+		return nil, fmt.Errorf("hash sconosciuto %q (%v)", kind, Kinds)
+	}
+
+	full, partial, err := poseidon2Rounds(curve.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+	switch curve {
+	case ecc.BLS12_381:
+		return newField[fr_bls12381.Element](kind, curve,
+			poseidon2_bls12381.NewPermutation(compressWidth, full, partial),
+			poseidon2_bls12381.NewPermutation(spongeWidth, full, partial),
+			func() hash.Hash { return mimc_bls12381.NewMiMC() }), nil
+	case ecc.BLS12_377:
+		return newField[fr_bls12377.Element](kind, curve,
+			poseidon2_bls12377.NewPermutation(compressWidth, full, partial),
+			poseidon2_bls12377.NewPermutation(spongeWidth, full, partial),
+			func() hash.Hash { return mimc_bls12377.NewMiMC() }), nil
+	}
+	return nil, fmt.Errorf("curva %s non supportata", curve)
+}
+
+// poseidon2Rounds sono i round della compressione width 2 e della sponge
+// width 3 sul campo field, dallo script dei round di Poseidon2
+// (calc_round_numbers.py, 128 bit, con il margine di 2 round completi e
+// +7.5% di parziali) per l'S-box della curva: x^5 su BN254 e BLS12-381, x^17
+// su BLS12-377. Su questi campi le due width danno gli stessi round;
+// kpihash_test.go rifà lo script per entrambe.
+func poseidon2Rounds(field *big.Int) (full, partial int, err error) {
+	switch {
+	case field.Cmp(ecc.BN254.ScalarField()) == 0:
+		return 8, 56, nil
+	case field.Cmp(ecc.BLS12_381.ScalarField()) == 0:
+		return 8, 56, nil
+	case field.Cmp(ecc.BLS12_377.ScalarField()) == 0:
+		return 8, 31, nil
+	}
+	return 0, 0, fmt.Errorf("poseidon2: campo %s non supportato", field)
+}
+
+type bn254Field struct {
+	h Native
+}
+
+func (f bn254Field) Leaf(v *big.Int) *big.Int {
+	return f.out(f.h.Leaf(f.in(v)))
+}
+
+func (f bn254Field) Node(left, right *big.Int) *big.Int {
+	return f.out(f.h.Node(f.in(left), f.in(right)))
+}
+
+func (f bn254Field) Hash(inputs ...*big.Int) *big.Int {
+	e := make([]fr.Element, len(inputs))
+	for i := range inputs {
+		e[i] = f.in(inputs[i])
+	}
+	return f.out(f.h.Hash(e...))
+}
+
+func (bn254Field) in(v *big.Int) fr.Element {
+	var e fr.Element
+	e.SetBigInt(v)
+	return e
+}
+
+func (bn254Field) out(e fr.Element) *big.Int {
+	return e.BigInt(new(big.Int))
+}
+
+// field è poseidon2 o mimc sulle curve BLS12, con la stessa costruzione di
+// BN254 (vedi poseidon2Native e mimcNative) sui fr.Element della curva.
+
+type element[E any] interface {
+	*E
+	SetBigInt(v *big.Int) *E
+	BigInt(res *big.Int) *big.Int
+	SetBytes(b []byte) *E
+	SetUint64(v uint64) *E
+	Marshal() []byte
+	Add(x, y *E) *E
+}
+
+type permutation[E any] interface {
+	Permutation(input []E) error
+	Compress(left, right []byte) ([]byte, error)
+	BlockSize() int
+}
+
+type field[E any, P element[E]] struct {
+	kind     string
+	compress permutation[E]
+	sponge   permutation[E]
+	mimc     func() hash.Hash
+	iv       []byte // LeafIV sul campo della curva
+}
+
+func newField[E any, P element[E]](kind string, curve ecc.ID, compress, sponge permutation[E], mimc func() hash.Hash) field[E, P] {
+	f := field[E, P]{kind: kind, compress: compress, sponge: sponge, mimc: mimc}
+	f.iv = f.bytes(LeafIV(curve.ScalarField()))
+	return f
+}
+
+func (f field[E, P]) bytes(v *big.Int) []byte {
+	var e E
+	P(&e).SetBigInt(v)
+	return P(&e).Marshal()
+}
+
+func (f field[E, P]) fromBytes(b []byte) *big.Int {
+	var e E
+	P(&e).SetBytes(b)
+	return P(&e).BigInt(new(big.Int))
+}
+
+func (f field[E, P]) Leaf(v *big.Int) *big.Int {
+	if f.kind == MiMC {
+		return f.Hash(v)
+	}
+	md := gnark_hash.NewMerkleDamgardHasher(f.compress, f.iv)
+	md.Write(f.bytes(v))
+	return f.fromBytes(md.Sum(nil))
+}
+
+func (f field[E, P]) Node(left, right *big.Int) *big.Int {
+	if f.kind == MiMC {
+		return f.Hash(left, right)
+	}
+	out, err := f.compress.Compress(f.bytes(left), f.bytes(right))
+	if err != nil {
+		panic(err) // width 2 e input canonici, non può capitare
+	}
+	return f.fromBytes(out)
+}
+
+func (f field[E, P]) Hash(inputs ...*big.Int) *big.Int {
+	if f.kind == MiMC {
+		m := f.mimc()
+		for _, v := range inputs {
+			m.Write(f.bytes(v))
+		}
+		return f.fromBytes(m.Sum(nil))
+	}
+	state := make([]E, spongeWidth)
+	P(&state[spongeWidth-1]).SetUint64(uint64(len(inputs)))
+	for i := 0; i == 0 || i < len(inputs); i += spongeWidth - 1 {
+		for j := 0; j < spongeWidth-1 && i+j < len(inputs); j++ {
+			var e E
+			P(&e).SetBigInt(inputs[i+j])
+			P(&state[j]).Add(&state[j], &e)
+		}
+		if err := f.sponge.Permutation(state); err != nil {
+			panic(err)
+		}
+	}
+	return P(&state[0]).BigInt(new(big.Int))
+}
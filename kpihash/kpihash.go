@@ -0,0 +1,312 @@
+// Package kpihash raccoglie gli hash usati da foglie, nodi e commitment dei
+// circuiti KPI, con la stessa interfaccia in nativo e nel circuito, così un
+// main sceglie l'hash con -hash e albero e vincoli restano allineati.
+//
+//   - poseidon2: Poseidon2 di gnark, compressione con feed-forward per i nodi,
+//     Merkle-Damgård con un IV proprio per le foglie e sponge width 3 per più
+//     input
+//   - circom:    Poseidon di circomlib, Poseidon(1) per le foglie,
+//     Poseidon(2) per i nodi e Poseidon(n) per più input, ricalcolabile da
+//     circom/snarkjs
+//   - mimc:      MiMC di gnark, un blocco per elemento, come zsnark_MiMC
+//
+// New lavora su BN254 con fr.Element; NewField dà gli stessi hash anche su
+// BLS12-381 e BLS12-377 (tranne circom), con i parametri di gnark-crypto
+// della curva.
+package kpihash
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	poseidon2_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	gnark_hash "github.com/consensys/gnark-crypto/hash"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash"
+	gnark_mimc "github.com/consensys/gnark/std/hash/mimc"
+	gnark_poseidon2 "github.com/consensys/gnark/std/permutation/poseidon2"
+
+	"zk-test/circomposeidon"
+)
+
+const (
+	Poseidon2 = "poseidon2"
+	Circom    = "circom"
+	MiMC      = "mimc"
+)
+
+// Kinds elenca gli hash disponibili.
+var Kinds = []string{Poseidon2, Circom, MiMC}
+
+// Native calcola foglie e nodi fuori dal circuito.
+type Native interface {
+	Leaf(v fr.Element) fr.Element
+	Node(left, right fr.Element) fr.Element
+	Hash(inputs ...fr.Element) fr.Element // foglie con più input
+}
+
+// Gadget è lo stesso hash dentro il circuito.
+type Gadget interface {
+	Leaf(v frontend.Variable) frontend.Variable
+	Node(left, right frontend.Variable) frontend.Variable
+	Hash(inputs ...frontend.Variable) frontend.Variable
+}
+
+// Flag registra -hash, da chiamare prima di flag.Parse.
+func Flag() *string {
+	return flag.String("hash", Poseidon2, "hash di foglie e nodi: poseidon2, circom (Poseidon di circomlib) oppure mimc")
+}
+
+// New restituisce l'hash nativo di tipo kind.
+func New(kind string) (Native, error) {
+	switch kind {
+	case Poseidon2:
+		return newPoseidon2Native(), nil
+	case Circom:
+		if err := circomposeidon.Check(); err != nil {
+			return nil, err
+		}
+		return circomNative{}, nil
+	case MiMC:
+		return mimcNative{}, nil
+	}
+	return nil, fmt.Errorf("hash sconosciuto %q (%v)", kind, Kinds)
+}
+
+// NewGadget restituisce l'hash di tipo kind per il circuito.
+func NewGadget(api frontend.API, kind string) (Gadget, error) {
+	switch kind {
+	case Poseidon2:
+		return newPoseidon2Gadget(api)
+	case Circom:
+		if api.Compiler().Field().Cmp(ecc.BN254.ScalarField()) != 0 {
+			return nil, fmt.Errorf("hash circom solo su bn254")
+		}
+		return circomGadget{api}, nil
+	case MiMC:
+		h, err := gnark_mimc.NewMiMC(api)
+		if err != nil {
+			return nil, err
+		}
+		return mimcGadget{&h}, nil
+	}
+	return nil, fmt.Errorf("hash sconosciuto %q (%v)", kind, Kinds)
+}
+
+// poseidon2 con i round dello script di Poseidon2 per 128 bit sul campo della
+// curva (vedi poseidon2Rounds), non con i 6/50 di default di gnark-crypto:
+//   - Node: compressione con feed-forward, perm(l, r)[1] + r, come Compress
+//   - Leaf: Merkle-Damgård sulla stessa compressione con IV LeafIV, cioè
+//     Node(LeafIV, v). Con IV 0 la foglia v era Node(0, v); LeafIV non ha
+//     preimmagini note, quindi una foglia non può passare per un nodo
+//   - Hash: sponge width 3 (rate 2, capacity 1) per foglie con più input; la
+//     capacity parte dal numero di input, così non serve padding
+
+// leafTag è la stringa da cui viene LeafIV.
+const leafTag = "zk-test/kpihash poseidon2 leaf"
+
+// LeafIV è l'IV delle foglie poseidon2 sul campo field: sha256 di leafTag
+// ridotto nel campo, un valore di cui nessuno conosce una preimmagine. Serve
+// anche a zkstark, che rifà le foglie nella traccia.
+func LeafIV(field *big.Int) *big.Int {
+	h := sha256.Sum256([]byte(leafTag))
+	return new(big.Int).Mod(new(big.Int).SetBytes(h[:]), field)
+}
+
+const (
+	compressWidth = 2
+	spongeWidth   = 3
+)
+
+type poseidon2Native struct {
+	compress *poseidon2_bn254.Permutation
+	sponge   *poseidon2_bn254.Permutation
+	iv       []byte
+}
+
+func newPoseidon2Native() poseidon2Native {
+	full, partial, _ := poseidon2Rounds(ecc.BN254.ScalarField())
+	var iv fr.Element
+	iv.SetBigInt(LeafIV(fr.Modulus()))
+	return poseidon2Native{
+		compress: poseidon2_bn254.NewPermutation(compressWidth, full, partial),
+		sponge:   poseidon2_bn254.NewPermutation(spongeWidth, full, partial),
+		iv:       iv.Marshal(),
+	}
+}
+
+func (h poseidon2Native) Leaf(v fr.Element) fr.Element {
+	md := gnark_hash.NewMerkleDamgardHasher(h.compress, h.iv)
+	b := v.Marshal()
+	md.Write(b)
+	var res fr.Element
+	res.SetBytes(md.Sum(nil))
+	return res
+}
+
+func (h poseidon2Native) Node(left, right fr.Element) fr.Element {
+	l, r := left.Marshal(), right.Marshal()
+	out, err := h.compress.Compress(l, r)
+	if err != nil {
+		panic(err) // width 2 e input canonici, non può capitare
+	}
+	var res fr.Element
+	res.SetBytes(out)
+	return res
+}
+
+func (h poseidon2Native) Hash(inputs ...fr.Element) fr.Element {
+	state := make([]fr.Element, spongeWidth)
+	state[spongeWidth-1].SetUint64(uint64(len(inputs)))
+	for i := 0; i == 0 || i < len(inputs); i += spongeWidth - 1 {
+		for j := 0; j < spongeWidth-1 && i+j < len(inputs); j++ {
+			state[j].Add(&state[j], &inputs[i+j])
+		}
+		if err := h.sponge.Permutation(state); err != nil {
+			panic(err)
+		}
+	}
+	return state[0]
+}
+
+type poseidon2Gadget struct {
+	api      frontend.API
+	compress *gnark_poseidon2.Permutation
+	sponge   *gnark_poseidon2.Permutation
+}
+
+func newPoseidon2Gadget(api frontend.API) (poseidon2Gadget, error) {
+	// gli stessi round del nativo per la curva del circuito
+	full, partial, err := poseidon2Rounds(api.Compiler().Field())
+	if err != nil {
+		return poseidon2Gadget{}, err
+	}
+	compress, err := gnark_poseidon2.NewPoseidon2FromParameters(api, compressWidth, full, partial)
+	if err != nil {
+		return poseidon2Gadget{}, err
+	}
+	sponge, err := gnark_poseidon2.NewPoseidon2FromParameters(api, spongeWidth, full, partial)
+	if err != nil {
+		return poseidon2Gadget{}, err
+	}
+	return poseidon2Gadget{api, compress, sponge}, nil
+}
+
+func (h poseidon2Gadget) Leaf(v frontend.Variable) frontend.Variable {
+	md := hash.NewMerkleDamgardHasher(h.api, h.compress, LeafIV(h.api.Compiler().Field()))
+	md.Write(v)
+	return md.Sum()
+}
+
+func (h poseidon2Gadget) Node(left, right frontend.Variable) frontend.Variable {
+	return h.compress.Compress(left, right)
+}
+
+func (h poseidon2Gadget) Hash(inputs ...frontend.Variable) frontend.Variable {
+	state := make([]frontend.Variable, spongeWidth)
+	for j := range state {
+		state[j] = 0
+	}
+	state[spongeWidth-1] = len(inputs)
+	for i := 0; i == 0 || i < len(inputs); i += spongeWidth - 1 {
+		for j := 0; j < spongeWidth-1 && i+j < len(inputs); j++ {
+			state[j] = h.api.Add(state[j], inputs[i+j])
+		}
+		if err := h.sponge.Permutation(state); err != nil {
+			panic(err)
+		}
+	}
+	return state[0]
+}
+
+// circom: 1 e 2 input sono sempre supportati, l'errore non può capitare;
+// Hash accetta fino a 16 input come circomlib
+
+type circomNative struct{}
+
+func (circomNative) Leaf(v fr.Element) fr.Element {
+	h, _ := circomposeidon.Hash(v)
+	return h
+}
+
+func (circomNative) Node(left, right fr.Element) fr.Element {
+	h, _ := circomposeidon.Hash(left, right)
+	return h
+}
+
+func (circomNative) Hash(inputs ...fr.Element) fr.Element {
+	h, err := circomposeidon.Hash(inputs...)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+type circomGadget struct {
+	api frontend.API
+}
+
+func (h circomGadget) Leaf(v frontend.Variable) frontend.Variable {
+	res, _ := circomposeidon.HashGadget(h.api, v)
+	return res
+}
+
+func (h circomGadget) Node(left, right frontend.Variable) frontend.Variable {
+	res, _ := circomposeidon.HashGadget(h.api, left, right)
+	return res
+}
+
+func (h circomGadget) Hash(inputs ...frontend.Variable) frontend.Variable {
+	res, err := circomposeidon.HashGadget(h.api, inputs...)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// mimc: il nativo scrive ogni elemento come blocco da 32 byte big-endian, il
+// gadget lo stesso elemento come variabile
+
+type mimcNative struct{}
+
+func (h mimcNative) Leaf(v fr.Element) fr.Element {
+	return h.Hash(v)
+}
+
+func (h mimcNative) Node(left, right fr.Element) fr.Element {
+	return h.Hash(left, right)
+}
+
+func (mimcNative) Hash(inputs ...fr.Element) fr.Element {
+	m := mimc.NewMiMC()
+	for i := range inputs {
+		b := inputs[i].Marshal()
+		m.Write(b)
+	}
+	var res fr.Element
+	res.SetBytes(m.Sum(nil))
+	return res
+}
+
+type mimcGadget struct {
+	h *gnark_mimc.MiMC
+}
+
+func (g mimcGadget) Leaf(v frontend.Variable) frontend.Variable {
+	return g.Hash(v)
+}
+
+func (g mimcGadget) Node(left, right frontend.Variable) frontend.Variable {
+	return g.Hash(left, right)
+}
+
+func (g mimcGadget) Hash(inputs ...frontend.Variable) frontend.Variable {
+	g.h.Reset()
+	g.h.Write(inputs...)
+	return g.h.Sum()
+}
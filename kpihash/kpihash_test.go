@@ -0,0 +1,242 @@
+package kpihash
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	poseidon2_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/poseidon2"
+	poseidon2_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/poseidon2"
+	poseidon2_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+// go test ./kpihash -rounds 50 per più input casuali
+var rounds = flag.Int("rounds", 5, "input casuali per ogni hash, curva e numero di input")
+
+var curves = []ecc.ID{ecc.BN254, ecc.BLS12_381, ecc.BLS12_377}
+
+// maxInputs è il numero massimo di input provati con Hash.
+const maxInputs = 5
+
+// hashCircuit vincola Leaf, Node e Hash del gadget ai valori del nativo.
+type hashCircuit struct {
+	Inputs [maxInputs]frontend.Variable
+	Leaf   frontend.Variable `gnark:",public"`
+	Node   frontend.Variable `gnark:",public"`
+	Hash   frontend.Variable `gnark:",public"`
+
+	Kind     string `gnark:"-"`
+	NbInputs int    `gnark:"-"` // input usati da Hash
+}
+
+func (c *hashCircuit) Define(api frontend.API) error {
+	h, err := NewGadget(api, c.Kind)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(h.Leaf(c.Inputs[0]), c.Leaf)
+	api.AssertIsEqual(h.Node(c.Inputs[0], c.Inputs[1]), c.Node)
+	api.AssertIsEqual(h.Hash(c.Inputs[:c.NbInputs]...), c.Hash)
+	return nil
+}
+
+// sample restituisce input casuali nel campo di curve; i primi tentativi
+// usano 0, 1 e -1.
+func sample(t *testing.T, curve ecc.ID, round int) [maxInputs]*big.Int {
+	q := curve.ScalarField()
+	var inputs [maxInputs]*big.Int
+	for i := range inputs {
+		switch round {
+		case 0:
+			inputs[i] = big.NewInt(0)
+		case 1:
+			inputs[i] = big.NewInt(1)
+		case 2:
+			inputs[i] = new(big.Int).Sub(q, big.NewInt(1))
+		default:
+			v, err := rand.Int(rand.Reader, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inputs[i] = v
+		}
+	}
+	return inputs
+}
+
+// Nativo e gadget devono dare lo stesso digest per ogni hash e curva (circom
+// solo su BN254), e il circuito deve rifiutare un digest alterato.
+func TestNativeGadget(t *testing.T) {
+	for _, curve := range curves {
+		for _, kind := range Kinds {
+			if kind == Circom && curve != ecc.BN254 {
+				continue
+			}
+			t.Run(fmt.Sprintf("%s/%s", kind, curve), func(t *testing.T) {
+				native, err := NewField(curve, kind)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for nbInputs := 1; nbInputs <= maxInputs; nbInputs++ {
+					for r := 0; r < 3+*rounds; r++ {
+						inputs := sample(t, curve, r)
+						circuit := hashCircuit{Kind: kind, NbInputs: nbInputs}
+						digest := native.Hash(inputs[:nbInputs]...)
+						assignment := hashCircuit{
+							Leaf: native.Leaf(inputs[0]),
+							Node: native.Node(inputs[0], inputs[1]),
+							Hash: digest,
+						}
+						for i := range inputs {
+							assignment.Inputs[i] = inputs[i]
+						}
+						if err := test.IsSolved(&circuit, &assignment, curve.ScalarField()); err != nil {
+							t.Fatalf("input %v: nativo e gadget divergono: %v", inputs[:nbInputs], err)
+						}
+
+						bad := new(big.Int).Add(digest, big.NewInt(1))
+						assignment.Hash = bad.Mod(bad, curve.ScalarField())
+						if test.IsSolved(&circuit, &assignment, curve.ScalarField()) == nil {
+							t.Fatalf("input %v: il circuito accetta un digest alterato", inputs[:nbInputs])
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+// Una foglia non deve coincidere con il nodo che ha 0 come figlio sinistro,
+// come succedeva con il Merkle-Damgård poseidon2 a IV 0.
+func TestLeafNotNode(t *testing.T) {
+	for _, curve := range curves {
+		for _, kind := range Kinds {
+			if kind == Circom && curve != ecc.BN254 {
+				continue
+			}
+			t.Run(fmt.Sprintf("%s/%s", kind, curve), func(t *testing.T) {
+				h, err := NewField(curve, kind)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, v := range sample(t, curve, 3) {
+					if h.Leaf(v).Cmp(h.Node(big.NewInt(0), v)) == 0 {
+						t.Fatalf("Leaf(%s) = Node(0, %s)", v, v)
+					}
+				}
+			})
+		}
+	}
+}
+
+// Su BN254 New e NewField sono lo stesso hash.
+func TestNewField(t *testing.T) {
+	for _, kind := range Kinds {
+		native, err := New(kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		field, err := NewField(ecc.BN254, kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := big.NewInt(42)
+		leaf := native.Leaf(bn254Field{}.in(v))
+		if got := field.Leaf(v); got.Cmp(bn254Field{}.out(leaf)) != 0 {
+			t.Fatalf("%s: New e NewField divergono", kind)
+		}
+	}
+
+	if _, err := NewField(ecc.BLS12_381, Circom); err == nil {
+		t.Fatal("circom accettato su bls12_381")
+	}
+	if _, err := New("sha256"); err == nil {
+		t.Fatal("hash sconosciuto accettato")
+	}
+}
+
+// roundNumbers rifà find_FD_round_numbers di calc_round_numbers.py
+// (Poseidon/Poseidon2, HorizenLabs) con costo R_F·t + R_P e margine di
+// sicurezza: restituisce R_F e R_P per il primo p, la width t e l'S-box x^alpha
+// a m bit.
+func roundNumbers(p *big.Int, t, alpha, m int) (rf, rp int) {
+	fp, _ := new(big.Float).SetInt(p).Float64()
+	log2p := math.Log2(fp)
+	n := math.Ceil(log2p)
+	T, A, M := float64(t), float64(alpha), float64(m)
+	logA := func(x float64) float64 { return math.Log(x) / math.Log(A) }
+
+	sat := func(rfT, rpT int) bool {
+		RF, RP := float64(rfT), float64(rpT)
+		rf1 := 10.0 // statistico
+		if M <= math.Floor(log2p-(A-1)/2)*(T+1) {
+			rf1 = 6
+		}
+		rf2 := 1 + math.Ceil(logA(2)*math.Min(M, n)) + math.Ceil(logA(T)) - RP // interpolazione
+		rf3 := logA(2)*math.Min(M, log2p) - RP                                 // Gröbner 1
+		rf4 := T - 1 + logA(2)*math.Min(M/(T+1), log2p/2) - RP                 // Gröbner 2
+		rf5 := (T - 2 + M/(2*math.Log2(A)) - RP) / (T - 1)                     // Gröbner 3
+		rfMax := math.Ceil(rf1)
+		for _, r := range []float64{rf2, rf3, rf4, rf5} {
+			rfMax = math.Max(rfMax, math.Ceil(r))
+		}
+		// eprint 2023/537
+		r := math.Floor(T / 3)
+		over := (RF-1)*T + RP + r + r*(RF/2) + RP + A
+		under := r*(RF/2) + RP + A
+		lo, _ := math.Lgamma(over + 1)
+		lu, _ := math.Lgamma(under + 1)
+		ld, _ := math.Lgamma(over - under + 1)
+		binomLog := (lo - lu - ld) / math.Ln2
+		return RF >= rfMax && math.Ceil(2*binomLog) >= M
+	}
+
+	minCost, maxCostRF := math.Inf(1), 0
+	for rpStart := 1; rpStart < 500; rpStart++ {
+		rpT := rpStart // come nello script, il margine su R_P resta per i R_F successivi
+		for rfT := 4; rfT < 100; rfT += 2 {
+			if !sat(rfT, rpT) {
+				continue
+			}
+			rfM := rfT + 2
+			rpT = int(math.Ceil(float64(rpT) * 1.075))
+			cost := float64(rfM*t + rpT)
+			if cost < minCost || (cost == minCost && rfM < maxCostRF) {
+				rf, rp, minCost, maxCostRF = rfM, rpT, cost, rfM
+			}
+		}
+	}
+	return rf, rp
+}
+
+// I round di compressione e sponge sono quelli dello script sul campo e con
+// l'S-box di ogni curva.
+func TestPoseidon2Rounds(t *testing.T) {
+	degree := map[ecc.ID]int{
+		ecc.BN254:     poseidon2_bn254.DegreeSBox(),
+		ecc.BLS12_381: poseidon2_bls12381.DegreeSBox(),
+		ecc.BLS12_377: poseidon2_bls12377.DegreeSBox(),
+	}
+	// lo script sulla sponge width 3 di BN254 di HorizenLabs
+	if rf, rp := roundNumbers(ecc.BN254.ScalarField(), 3, 5, 128); rf != 8 || rp != 56 {
+		t.Fatalf("script su bn254: %d/%d, HorizenLabs 8/56", rf, rp)
+	}
+	for _, curve := range curves {
+		full, partial, err := poseidon2Rounds(curve.ScalarField())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, width := range []int{compressWidth, spongeWidth} {
+			rf, rp := roundNumbers(curve.ScalarField(), width, degree[curve], 128)
+			if full != rf || partial != rp {
+				t.Errorf("%s width %d: %d/%d, lo script dà %d/%d (x^%d)", curve, width, full, partial, rf, rp, degree[curve])
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Package kpiinput carica i valori KPI in JSON, {"values": [1.3, 2.3, ...]},
+// per i demo SNARK e per la pipeline CKKS: da file con -input, altrimenti dal
+// JSON d'esempio del main.
+package kpiinput
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Example è il JSON d'esempio usato dai demo quando manca -input.
+const Example = `{"values": [1.3, 2.3, 4.234]}`
+
+// Data sono i valori KPI letti dal JSON. Weights è facoltativo: pesi per la
+// somma pesata CKKS, i demo SNARK lo ignorano.
+type Data struct {
+	Values  []float64 `json:"values"`
+	Weights []float64 `json:"weights,omitempty"`
+}
+
+// Flag registra -input, da chiamare prima di flag.Parse.
+func Flag() *string {
+	return flag.String("input", "", `file JSON con i valori KPI, {"values": [...]}; vuoto = dati d'esempio`)
+}
+
+// Load legge i valori dal file path, o da example se path è vuoto.
+func Load(path, example string) (*Data, error) {
+	b := []byte(example)
+	if path != "" {
+		var err error
+		if b, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return Parse(b)
+}
+
+// Parse decodifica il JSON e scarta valori non finiti, che né lo scaling dei
+// circuiti né CKKS sanno gestire.
+func Parse(b []byte) (*Data, error) {
+	var data Data
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, fmt.Errorf("kpiinput: %w", err)
+	}
+	if data.Weights != nil && len(data.Weights) != len(data.Values) {
+		return nil, fmt.Errorf("kpiinput: %d pesi per %d valori", len(data.Weights), len(data.Values))
+	}
+	if err := finite("valore", data.Values); err != nil {
+		return nil, err
+	}
+	if err := finite("peso", data.Weights); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func finite(what string, v []float64) error {
+	for i := range v {
+		if math.IsNaN(v[i]) || math.IsInf(v[i], 0) {
+			return fmt.Errorf("kpiinput: %s %d non finito (%v)", what, i, v[i])
+		}
+	}
+	return nil
+}
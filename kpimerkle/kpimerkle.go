@@ -0,0 +1,129 @@
+// Package kpimerkle è il circuito da 128 slot con albero di Merkle di
+// zsnark_Poseidon_merkle_tree: la somma dei KPI ×1000 con la prova che ogni
+// valore è una foglia della radice pubblica. Lo usano il main, il servizio
+// di prova zsnark_prover_service e, con kpihash.MiMC, zsnark_MiMC e
+// zsnark_MiMC_recursive.
+package kpimerkle
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+
+	"zk-test/kpihash"
+)
+
+const (
+	MaxValues = 128 // provo a usare 128 come valore esponenziale di 2, let s try!
+	TreeDepth = 7
+)
+
+// Circuit prova che la somma dei MaxValues valori è ExpectedSum e che ogni
+// valore è una foglia dell'albero con radice Root.
+type Circuit struct {
+	Root        frontend.Variable                       `gnark:",public" kpi:"root"`
+	ExpectedSum frontend.Variable                       `gnark:",public" kpi:"sum,scale=1000"`
+	Values      [MaxValues]frontend.Variable            `gnark:",secret"`
+	Paths       [MaxValues][TreeDepth]frontend.Variable `gnark:",secret"`
+	IsRight     [MaxValues][TreeDepth]frontend.Variable `gnark:",secret"` // 1 se il path è a destra, 0 se a sinistra
+
+	Hash string `gnark:"-"` // kpihash.Poseidon2, kpihash.Circom o kpihash.MiMC
+}
+
+func (c *Circuit) Define(api frontend.API) error {
+	// 1. Hash di foglie e nodi (Poseidon2 width 2 oppure Poseidon di circomlib)
+	h, err := kpihash.NewGadget(api, c.Hash)
+	if err != nil {
+		return err
+	}
+
+	var totalSum frontend.Variable = 0
+
+	for idx := 0; idx < MaxValues; idx++ {
+		totalSum = api.Add(totalSum, c.Values[idx])
+
+		// 2. Hash della foglia
+		currentHash := h.Leaf(c.Values[idx])
+
+		for idxTree := 0; idxTree < TreeDepth; idxTree++ {
+			left := api.Select(c.IsRight[idx][idxTree], currentHash, c.Paths[idx][idxTree])
+			right := api.Select(c.IsRight[idx][idxTree], c.Paths[idx][idxTree], currentHash)
+
+			// 3. Hash del nodo
+			currentHash = h.Node(left, right)
+		}
+		api.AssertIsEqual(currentHash, c.Root)
+	}
+
+	api.AssertIsEqual(totalSum, c.ExpectedSum)
+	return nil
+}
+
+// Assign costruisce albero e assignment per values (scalati ×1000, al massimo
+// MaxValues, il resto a zero) con l'hash hashKind sul campo di curve.
+func Assign(curve ecc.ID, hashKind string, values []float64) (*Circuit, int64, error) {
+	if len(values) > MaxValues {
+		return nil, 0, fmt.Errorf("%d valori, il circuito ne accetta al massimo %d", len(values), MaxValues)
+	}
+
+	// 1. Stesso hash del circuito, sul campo della curva
+	hasher, err := kpihash.NewField(curve, hashKind)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var scaledValues [MaxValues]int64
+	leaves := make([]*big.Int, MaxValues)
+
+	for idxValue := 0; idxValue < MaxValues; idxValue++ {
+		if idxValue < len(values) {
+			scaledValues[idxValue] = int64(math.Round(values[idxValue] * 1000))
+		}
+
+		e := new(big.Int).Mod(big.NewInt(scaledValues[idxValue]), curve.ScalarField())
+
+		// 2. Hash della foglia
+		leaves[idxValue] = hasher.Leaf(e)
+	}
+
+	// 3. Costruzione dell'albero
+	tree := make([][]*big.Int, TreeDepth+1)
+	tree[0] = leaves
+	for idxTree := 0; idxTree < TreeDepth; idxTree++ {
+		var level []*big.Int
+		for idxTreeLevel := 0; idxTreeLevel < len(tree[idxTree]); idxTreeLevel += 2 {
+			// 4. Hash del nodo
+			node := hasher.Node(tree[idxTree][idxTreeLevel], tree[idxTree][idxTreeLevel+1])
+			level = append(level, node)
+		}
+		tree[idxTree+1] = level
+	}
+	root := tree[TreeDepth][0]
+
+	assignment := &Circuit{Hash: hashKind}
+	assignment.Root = root
+	var sum int64 = 0
+
+	for i := 0; i < MaxValues; i++ {
+		assignment.Values[i] = scaledValues[i]
+		sum += scaledValues[i]
+
+		currIdx := i
+		for d := 0; d < TreeDepth; d++ {
+			if currIdx%2 == 0 {
+				assignment.Paths[i][d] = tree[d][currIdx+1]
+				assignment.IsRight[i][d] = 1
+			} else {
+				assignment.Paths[i][d] = tree[d][currIdx-1]
+				assignment.IsRight[i][d] = 0
+			}
+			currIdx /= 2
+		}
+	}
+	assignment.ExpectedSum = sum
+
+	return assignment, sum, nil
+}
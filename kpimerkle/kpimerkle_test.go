@@ -0,0 +1,84 @@
+package kpimerkle
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+
+	"zk-test/kpihash"
+)
+
+// I valori di kpiinput.Example danno le radici dei public.json committati in
+// zsnark_MiMC/testSnarkJS e zsnark_Poseidon_merkle_tree/testSnarkJS.
+var exampleValues = []float64{1.3, 2.3, 4.234}
+
+func TestAssignFixtures(t *testing.T) {
+	for _, tc := range []struct{ hash, root string }{
+		{kpihash.MiMC, "21710444782070151014346071782773464690998502725104587009253144985714294277976"},
+		{kpihash.Poseidon2, "12251260059971874866007414654491144822262243408445360264632743031179541619625"},
+	} {
+		assignment, sum, err := Assign(ecc.BN254, tc.hash, exampleValues)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum != 7834 || assignment.ExpectedSum != int64(7834) {
+			t.Fatalf("%s: somma %d, assegnata %v, attesa 7834", tc.hash, sum, assignment.ExpectedSum)
+		}
+		if root := assignment.Root.(*big.Int).String(); root != tc.root {
+			t.Fatalf("%s: radice %s, quella della fixture è %s", tc.hash, root, tc.root)
+		}
+	}
+}
+
+// L'assignment di Assign soddisfa il circuito su ogni curva, anche con tutti
+// i MaxValues slot pieni e con valori negativi; ExpectedSum, un valore o un
+// path alterati no.
+func TestAssignSolved(t *testing.T) {
+	full := make([]float64, MaxValues)
+	for i := range full {
+		full[i] = float64(i) - 10.5
+	}
+	for _, curve := range []ecc.ID{ecc.BN254, ecc.BLS12_381, ecc.BLS12_377} {
+		for _, values := range [][]float64{exampleValues, full} {
+			assignment, sum, err := Assign(curve, kpihash.MiMC, values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			circuit := &Circuit{Hash: kpihash.MiMC}
+			if err := test.IsSolved(circuit, assignment, curve.ScalarField()); err != nil {
+				t.Fatalf("%s, %d valori: assignment rifiutato: %v", curve, len(values), err)
+			}
+
+			assignment.ExpectedSum = sum + 1
+			if test.IsSolved(circuit, assignment, curve.ScalarField()) == nil {
+				t.Fatalf("%s: accettata la somma %d", curve, sum+1)
+			}
+			assignment.ExpectedSum = sum
+
+			// somma invariata, ma le foglie non sono più quelle della radice
+			assignment.Values[0] = assignment.Values[0].(int64) + 1
+			assignment.Values[1] = assignment.Values[1].(int64) - 1
+			if test.IsSolved(circuit, assignment, curve.ScalarField()) == nil {
+				t.Fatalf("%s: accettati valori scambiati a somma invariata", curve)
+			}
+			assignment.Values[0] = assignment.Values[0].(int64) - 1
+			assignment.Values[1] = assignment.Values[1].(int64) + 1
+
+			assignment.IsRight[0][0] = 0
+			if test.IsSolved(circuit, assignment, curve.ScalarField()) == nil {
+				t.Fatalf("%s: accettato un path con il lato sbagliato", curve)
+			}
+		}
+	}
+}
+
+func TestAssignTooMany(t *testing.T) {
+	if _, _, err := Assign(ecc.BN254, kpihash.MiMC, make([]float64, MaxValues+1)); err == nil {
+		t.Fatalf("accettati %d valori", MaxValues+1)
+	}
+	if _, _, err := Assign(ecc.BN254, "sha3", exampleValues); err == nil {
+		t.Fatal("accettato un hash sconosciuto")
+	}
+}
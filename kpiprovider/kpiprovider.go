@@ -0,0 +1,104 @@
+// Package kpiprovider è il circuito di zsnark_Poseidon_multi_provider: la
+// somma dei KPI di più provider, ognuno con il proprio sotto-albero kpihash
+// firmato EdDSA. Sta fuori dal main perché zsnark_snarkjs_verify confronta il
+// manifest con il suo schema.
+package kpiprovider
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	gnark_hash "github.com/consensys/gnark-crypto/hash"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	gnark_mimc "github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/std/signature/eddsa"
+
+	"zk-test/kpihash"
+	"zk-test/zkbackend"
+)
+
+const (
+	NumProviders   = 4  // numero di controllate che firmano il proprio sotto-albero
+	ProviderValues = 32 // slot per provider, 4 * 32 = 128 come negli altri circuiti
+	ProviderDepth  = 5
+)
+
+// Ogni provider committa i propri valori in un sotto-albero (kpihash) e firma la sotto-root.
+// L'aggregatore dimostra che la somma globale corrisponde ai sotto-alberi firmati.
+type Circuit struct {
+	SubRoots    [NumProviders]frontend.Variable                 `gnark:",public" kpi:"root"`
+	PublicKeys  [NumProviders]eddsa.PublicKey                   `gnark:",public" kpi:"pubkey"`
+	ExpectedSum frontend.Variable                               `gnark:",public" kpi:"sum,scale=1000"`
+	Signatures  [NumProviders]eddsa.Signature                   `gnark:",secret"`
+	Values      [NumProviders][ProviderValues]frontend.Variable `gnark:",secret"`
+
+	Hash string `gnark:"-"` // kpihash.Poseidon2 o kpihash.Circom
+}
+
+func (c *Circuit) Define(api frontend.API) error {
+	// Stesso hash del merkle tree
+	hasher, err := kpihash.NewGadget(api, c.Hash)
+	if err != nil {
+		return err
+	}
+	// EdDSA sulla twisted Edwards della curva del circuito (Baby Jubjub su
+	// BN254, Jubjub su BLS12-381)
+	ed, _, err := Edwards(zkbackend.CurveOf(api.Compiler().Field()))
+	if err != nil {
+		return err
+	}
+	curve, err := twistededwards.NewEdCurve(api, ed)
+	if err != nil {
+		return err
+	}
+	// La firma EdDSA usa MiMC come hash del messaggio (come in gnark-crypto)
+	h, err := gnark_mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	var totalSum frontend.Variable = 0
+
+	for p := 0; p < NumProviders; p++ {
+		// 1. Ricostruisco il sotto-albero del provider dalle foglie
+		level := make([]frontend.Variable, ProviderValues)
+		for idx := 0; idx < ProviderValues; idx++ {
+			totalSum = api.Add(totalSum, c.Values[p][idx])
+
+			level[idx] = hasher.Leaf(c.Values[p][idx])
+		}
+		for len(level) > 1 {
+			next := make([]frontend.Variable, len(level)/2)
+			for idx := range next {
+				next[idx] = hasher.Node(level[2*idx], level[2*idx+1])
+			}
+			level = next
+		}
+		api.AssertIsEqual(level[0], c.SubRoots[p])
+
+		// 2. La sotto-root deve essere firmata dal provider
+		h.Reset()
+		if err := eddsa.Verify(curve, c.Signatures[p], c.SubRoots[p], c.PublicKeys[p], &h); err != nil {
+			return err
+		}
+	}
+
+	api.AssertIsEqual(totalSum, c.ExpectedSum)
+	return nil
+}
+
+// Edwards restituisce la twisted Edwards e il MiMC usati per le firme sulla
+// curva del circuito.
+func Edwards(curve ecc.ID) (tedwards.ID, gnark_hash.Hash, error) {
+	switch curve {
+	case ecc.BN254:
+		return tedwards.BN254, gnark_hash.MIMC_BN254, nil
+	case ecc.BLS12_381:
+		return tedwards.BLS12_381, gnark_hash.MIMC_BLS12_381, nil
+	case ecc.BLS12_377:
+		return tedwards.BLS12_377, gnark_hash.MIMC_BLS12_377, nil
+	}
+	return 0, 0, fmt.Errorf("eddsa non supportato su %s", curve)
+}
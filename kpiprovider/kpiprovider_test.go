@@ -0,0 +1,177 @@
+package kpiprovider
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/signature"
+	native_eddsa "github.com/consensys/gnark-crypto/signature/eddsa"
+	"github.com/consensys/gnark/test"
+
+	"zk-test/kpihash"
+)
+
+// signed è il sotto-albero di un provider con la firma, come lo consegna
+// all'aggregatore nel main.
+type signed struct {
+	values    [ProviderValues]int64
+	subRoot   *big.Int
+	publicKey []byte
+	signature []byte
+}
+
+// sign costruisce con hasher il sotto-albero di values e firma la sotto-root
+// con signer, come Provider.Commit nel main.
+func sign(t *testing.T, curve ecc.ID, hasher kpihash.Field, signer signature.Signer, values [ProviderValues]int64) signed {
+	t.Helper()
+	level := make([]*big.Int, ProviderValues)
+	for i, v := range values {
+		level[i] = hasher.Leaf(big.NewInt(v))
+	}
+	for len(level) > 1 {
+		next := make([]*big.Int, len(level)/2)
+		for i := range next {
+			next[i] = hasher.Node(level[2*i], level[2*i+1])
+		}
+		level = next
+	}
+	return signed{
+		values:    values,
+		subRoot:   level[0],
+		publicKey: signer.Public().Bytes(),
+		signature: signMessage(t, curve, signer, level[0]),
+	}
+}
+
+// signMessage firma la sotto-root in 32 byte big-endian con il MiMC della curva.
+func signMessage(t *testing.T, curve ecc.ID, signer signature.Signer, subRoot *big.Int) []byte {
+	t.Helper()
+	_, mimc, err := Edwards(curve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := signer.Sign(subRoot.FillBytes(make([]byte, 32)), mimc.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+// providers restituisce NumProviders sotto-alberi firmati ognuno dal proprio
+// signer, e i signer.
+func providers(t *testing.T, curve ecc.ID, hasher kpihash.Field) ([]signed, []signature.Signer) {
+	t.Helper()
+	ed, _, err := Edwards(curve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subs := make([]signed, NumProviders)
+	signers := make([]signature.Signer, NumProviders)
+	for p := range subs {
+		if signers[p], err = native_eddsa.New(ed, rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+		var values [ProviderValues]int64
+		for i := 0; i < 3+p; i++ {
+			values[i] = int64(1000*(p+1) + 17*i)
+		}
+		subs[p] = sign(t, curve, hasher, signers[p], values)
+	}
+	return subs, signers
+}
+
+func assign(t *testing.T, curve ecc.ID, kind string, subs []signed) *Circuit {
+	t.Helper()
+	ed, _, err := Edwards(curve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &Circuit{Hash: kind}
+	var sum int64
+	for p, s := range subs {
+		a.SubRoots[p] = s.subRoot
+		a.PublicKeys[p].Assign(ed, s.publicKey)
+		a.Signatures[p].Assign(ed, s.signature)
+		for i, v := range s.values {
+			a.Values[p][i] = v
+			sum += v
+		}
+	}
+	a.ExpectedSum = sum
+	return a
+}
+
+// Sotto-alberi firmati dai rispettivi provider e la loro somma: il circuito
+// è soddisfatto su ogni curva con EdDSA.
+func TestValid(t *testing.T) {
+	for _, curve := range []ecc.ID{ecc.BN254, ecc.BLS12_381, ecc.BLS12_377} {
+		t.Run(curve.String(), func(t *testing.T) {
+			hasher, err := kpihash.NewField(curve, kpihash.Poseidon2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			subs, _ := providers(t, curve, hasher)
+			if err := test.IsSolved(&Circuit{Hash: kpihash.Poseidon2}, assign(t, curve, kpihash.Poseidon2, subs), curve.ScalarField()); err != nil {
+				t.Fatalf("assegnazione valida rifiutata: %v", err)
+			}
+		})
+	}
+}
+
+func TestTampered(t *testing.T) {
+	const curve, kind = ecc.BN254, kpihash.Poseidon2
+	hasher, err := kpihash.NewField(curve, kind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subs, signers := providers(t, curve, hasher)
+	ed, _, err := Edwards(curve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outsider, err := native_eddsa.New(ed, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		tamper func(subs []signed, a *Circuit)
+	}{
+		{"firma falsa", func(subs []signed, a *Circuit) {
+			// firma di un estraneo messa al posto di quella del provider 1
+			a.Signatures[1].Assign(ed, signMessage(t, curve, outsider, subs[1].subRoot))
+		}},
+		{"firma di un'altra sotto-root", func(subs []signed, a *Circuit) {
+			a.Signatures[1].Assign(ed, signMessage(t, curve, signers[1], subs[0].subRoot))
+		}},
+		{"chiave di un altro provider", func(subs []signed, a *Circuit) {
+			// il provider 2 firma, ma risulta la chiave del provider 3
+			a.PublicKeys[2].Assign(ed, subs[3].publicKey)
+		}},
+		{"chiave di un estraneo", func(subs []signed, a *Circuit) {
+			a.PublicKeys[2].Assign(ed, outsider.Public().Bytes())
+		}},
+		{"sotto-root alterata", func(subs []signed, a *Circuit) {
+			a.SubRoots[0] = new(big.Int).Add(subs[0].subRoot, big.NewInt(1))
+		}},
+		{"valore alterato", func(subs []signed, a *Circuit) {
+			// la somma segue il valore, ma il sotto-albero firmato no
+			a.Values[3][0] = subs[3].values[0] + 1
+			a.ExpectedSum = a.ExpectedSum.(int64) + 1
+		}},
+		{"somma alterata", func(subs []signed, a *Circuit) {
+			a.ExpectedSum = a.ExpectedSum.(int64) + 1
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assign(t, curve, kind, subs)
+			tc.tamper(subs, a)
+			if test.IsSolved(&Circuit{Hash: kind}, a, curve.ScalarField()) == nil {
+				t.Fatal("assegnazione alterata accettata")
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Package latcommit è l'impegno post-quantum dei KPI su reticoli, alternativa
+// agli Hashes[i] Poseidon di zsnark_Poseidon_linear_commitment: un impegno
+// Ajtai/BDLOP al vettore dei KPI nell'anello R_q = Z_q[X]/(X^N+1) di lattigo,
+// e una prova Fiat-Shamir (Lyubashevsky, con rejection sampling) che il
+// vettore impegnato ha coefficienti corti e somma pubblica S.
+//
+// I KPI v_0..v_(N-1) sono i coefficienti del polinomio m e l'impegno è
+//
+//	t = A_1·r + a_2·m    A_1 = [I | A'] di Rows righe, r ternario
+//
+// nascosto per MLWE (A_1·r è un campione MLWE) e vincolante per MSIS su
+// [A_1 | a_2] con vettori corti. Le matrici vengono da un seed pubblico:
+// niente setup fidato.
+//
+// La somma dei coefficienti non è lineare sull'anello, ma è il termine noto
+// di j·m con j = Σ X^(-i): la prova usa polinomi di mascheramento g_i con
+// termine noto nullo, impegnati in stile BDLOP, e rivela
+// h_i = g_i + γ_i·(j·m - S), che hanno termine noto nullo solo se la somma è
+// S. Con una sola sfida γ ∈ Z_q chi prova una somma falsa cambia r' finché
+// γ = -g_0/(Σm - S), circa q hash; con SumChallenges sfide indipendenti, come
+// in LNS21, ne servono q^SumChallenges, almeno 2^128.
+//
+// Come tutte le prove Lyubashevsky la prova è rilassata: il verifier si
+// convince che chi prova conosce un'apertura corta di c̄·t per una differenza
+// di sfide c̄, con coefficienti entro i limiti gammaR/gammaM e non entro
+// 2^ValueBits. Un range esatto richiederebbe le prove quadratiche (LNP22).
+// q ha 50 bit: con un segreto MLWE di 2·N coefficienti è il massimo che la
+// tabella dell'HE standard (quella di secreport) ammette per 128 bit classici
+// e quantistici. Il binding e gli altri parametri sono d'esempio, non stimati
+// con il lattice estimator.
+package latcommit
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/tuneinsight/lattigo/v4/ring"
+	"github.com/tuneinsight/lattigo/v4/utils"
+)
+
+const (
+	LogN      = 10
+	Rows      = 12       // righe di A_1, per il binding
+	RandLen   = Rows + 2 // r ∈ R^RandLen, 2 polinomi di segreto MLWE
+	ValueBits = 20       // |v_i| < 2^ValueBits, KPI ×1000 fino a circa 1000
+	Kappa     = 60       // coefficienti ±1 della sfida c
+
+	LogQ = 50 // bit del modulo q, vedi sopra per l'hiding
+
+	// SumChallenges sono le sfide γ_i del controllo della somma: ognuna vale
+	// almeno LogQ-1 bit perché q > 2^(LogQ-1)
+	SumChallenges = (128 + LogQ - 2) / (LogQ - 1)
+
+	// limiti dei mascheramenti: z = y + c·s viene rifiutato se esce da
+	// gamma - Kappa·|s|, così z non dice nulla su s
+	gammaR      = 1 << 22
+	gammaM      = 1 << 38
+	maxAttempts = 100
+)
+
+// N è il numero di coefficienti, cioè di KPI per impegno.
+const N = 1 << LogN
+
+// Key sono le matrici pubbliche, tutte in forma NTT.
+type Key struct {
+	seed []byte
+	r    *ring.Ring
+	q    uint64
+
+	a1 [][]*ring.Poly // Rows × (RandLen-Rows), la parte A' di A_1
+	a2 []*ring.Poly   // Rows
+	b  [][]*ring.Poly // SumChallenges × RandLen, le righe BDLOP per i g_i
+	j  *ring.Poly     // Σ X^(-i)
+}
+
+// NewKey deriva le matrici da seed; chi verifica deve usare lo stesso seed.
+func NewKey(seed []byte) (*Key, error) {
+	q := ring.GenerateNTTPrimes(LogQ, 2*N, 1)[0]
+	r, err := ring.NewRing(N, []uint64{q})
+	if err != nil {
+		return nil, err
+	}
+	prng, err := utils.NewKeyedPRNG(append([]byte("latcommit-key"), seed...))
+	if err != nil {
+		return nil, err
+	}
+	uniform := ring.NewUniformSampler(prng, r)
+	k := &Key{seed: append([]byte(nil), seed...), r: r, q: q}
+	k.a1 = make([][]*ring.Poly, Rows)
+	for i := range k.a1 {
+		k.a1[i] = make([]*ring.Poly, RandLen-Rows)
+		for l := range k.a1[i] {
+			k.a1[i][l] = uniform.ReadNew()
+		}
+	}
+	k.a2 = make([]*ring.Poly, Rows)
+	for i := range k.a2 {
+		k.a2[i] = uniform.ReadNew()
+	}
+	k.b = make([][]*ring.Poly, SumChallenges)
+	for i := range k.b {
+		k.b[i] = make([]*ring.Poly, RandLen)
+		for l := range k.b[i] {
+			k.b[i][l] = uniform.ReadNew()
+		}
+	}
+
+	// X^(-i) = -X^(N-i): il termine noto di j·m è Σ m_i
+	k.j = r.NewPoly()
+	k.j.Coeffs[0][0] = 1
+	for i := 1; i < N; i++ {
+		k.j.Coeffs[0][i] = q - 1
+	}
+	r.NTT(k.j, k.j)
+	return k, nil
+}
+
+// Commitment è t = A_1·r + a_2·m, Rows polinomi in forma NTT.
+type Commitment struct {
+	T []*ring.Poly
+}
+
+// Opening è il segreto dell'impegno, in forma coefficienti; resta a chi
+// impegna.
+type Opening struct {
+	R []*ring.Poly
+	M *ring.Poly
+}
+
+// Commit impegna values (al massimo N, |v| < 2^ValueBits).
+func (k *Key) Commit(values []int64) (*Commitment, *Opening, error) {
+	if len(values) > N {
+		return nil, nil, fmt.Errorf("latcommit: %d valori, al massimo %d", len(values), N)
+	}
+	o := &Opening{M: k.r.NewPoly()}
+	for i, v := range values {
+		if v <= -1<<ValueBits || v >= 1<<ValueBits {
+			return nil, nil, fmt.Errorf("latcommit: il valore %d (%d) supera 2^%d", i, v, ValueBits)
+		}
+		o.M.Coeffs[0][i] = k.mod(v)
+	}
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		return nil, nil, err
+	}
+	o.R = k.ternary(prng)
+	c := &Commitment{T: k.commit(k.ntt(o.R...), k.ntt(o.M)[0])}
+	return c, o, nil
+}
+
+// commit calcola A_1·r + a_2·m in forma NTT; m nil vale zero.
+func (k *Key) commit(r []*ring.Poly, m *ring.Poly) []*ring.Poly {
+	t := make([]*ring.Poly, Rows)
+	for i := range t {
+		t[i] = r[i].CopyNew()
+		for l, a := range k.a1[i] {
+			k.r.MulCoeffsAndAdd(a, r[Rows+l], t[i])
+		}
+		if m != nil {
+			k.r.MulCoeffsAndAdd(k.a2[i], m, t[i])
+		}
+	}
+	return t
+}
+
+// inner è <b_i, r> in forma NTT.
+func (k *Key) inner(i int, r []*ring.Poly) *ring.Poly {
+	res := k.r.NewPoly()
+	for l := range r {
+		k.r.MulCoeffsAndAdd(k.b[i][l], r[l], res)
+	}
+	return res
+}
+
+func (k *Key) ternary(prng utils.PRNG) []*ring.Poly {
+	ts := ring.NewTernarySampler(prng, k.r, 1.0/3, false)
+	r := make([]*ring.Poly, RandLen)
+	for i := range r {
+		r[i] = ts.ReadNew()
+	}
+	return r
+}
+
+// ntt restituisce copie in forma NTT.
+func (k *Key) ntt(p ...*ring.Poly) []*ring.Poly {
+	res := make([]*ring.Poly, len(p))
+	for i := range p {
+		res[i] = k.r.NewPoly()
+		k.r.NTT(p[i], res[i])
+	}
+	return res
+}
+
+// mod porta un intero con segno in [0, q).
+func (k *Key) mod(v int64) uint64 {
+	if v < 0 {
+		return k.q - uint64(-v)%k.q
+	}
+	return uint64(v) % k.q
+}
+
+// centered restituisce il massimo |coefficiente| con i coefficienti in (-q/2, q/2].
+func (k *Key) centered(p *ring.Poly) uint64 {
+	var m uint64
+	for _, c := range p.Coeffs[0] {
+		if c > k.q/2 {
+			c = k.q - c
+		}
+		m = max(m, c)
+	}
+	return m
+}
+
+// hash tiene il transcript Fiat-Shamir: seed, impegno, somma e i polinomi
+// inviati, in ordine.
+type hash struct {
+	data []byte
+}
+
+func (k *Key) newHash(c *Commitment, sum int64) *hash {
+	h := &hash{data: append([]byte("latcommit-sum-v2"), k.seed...)}
+	h.polys(c.T...)
+	h.data = binary.BigEndian.AppendUint64(h.data, k.mod(sum))
+	return h
+}
+
+func (h *hash) polys(p ...*ring.Poly) {
+	for _, x := range p {
+		for _, c := range x.Coeffs[0] {
+			h.data = binary.BigEndian.AppendUint64(h.data, c)
+		}
+	}
+}
+
+func (h *hash) sum(label string) [32]byte {
+	return sha256.Sum256(append(append([]byte(nil), h.data...), label...))
+}
+
+// gammas sono le SumChallenges sfide in Z_q per il termine noto.
+func (k *Key) gammas(h *hash) []uint64 {
+	res := make([]uint64, SumChallenges)
+	for i := range res {
+		a, b := h.sum(fmt.Sprintf("gamma-%d-0", i)), h.sum(fmt.Sprintf("gamma-%d-1", i))
+		x := new(big.Int).SetBytes(append(a[:], b[:]...))
+		res[i] = x.Mod(x, new(big.Int).SetUint64(k.q)).Uint64()
+	}
+	return res
+}
+
+// challenge espande il seed nella sfida c, ternaria con Kappa coefficienti
+// non nulli, in forma NTT.
+func (k *Key) challenge(seed [32]byte) (*ring.Poly, error) {
+	prng, err := utils.NewKeyedPRNG(seed[:])
+	if err != nil {
+		return nil, err
+	}
+	c := ring.NewTernarySamplerWithHammingWeight(prng, k.r, Kappa, false).ReadNew()
+	k.r.NTT(c, c)
+	return c, nil
+}
+
+var errReject = errors.New("latcommit: prova rifiutata")
@@ -0,0 +1,175 @@
+package latcommit
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+var testValues = []int64{1300, 2300, 4234, 3870, -5120, 6450, 1 << (ValueBits - 1)}
+
+func testSum() int64 {
+	var s int64
+	for _, v := range testValues {
+		s += v
+	}
+	return s
+}
+
+func testCommit(t *testing.T) (*Key, *Commitment, *Opening, *Proof) {
+	t.Helper()
+	k, err := NewKey([]byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, o, err := k.Commit(testValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := k.ProveSum(c, o, testSum())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k, c, o, p
+}
+
+// Impegno e prova passano per i byte, con una chiave derivata di nuovo dallo
+// stesso seed come farebbe il verifier.
+func TestRoundTrip(t *testing.T) {
+	k, c, _, p := testCommit(t)
+	verifier, err := NewKey([]byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := verifier.UnmarshalCommitment(k.MarshalCommitment(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := verifier.UnmarshalProof(k.MarshalProof(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.VerifySum(c2, testSum(), p2); err != nil {
+		t.Fatalf("prova onesta rifiutata: %v", err)
+	}
+
+	// un'altra chiave dà altre matrici
+	other, err := NewKey([]byte("altro seed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.VerifySum(c2, testSum(), p2) == nil {
+		t.Fatal("prova accettata con un altro seed")
+	}
+}
+
+func TestWrongSum(t *testing.T) {
+	k, c, o, p := testCommit(t)
+	for _, sum := range []int64{testSum() + 1, testSum() - 1, 0} {
+		if err := k.VerifySum(c, sum, p); !errors.Is(err, errReject) {
+			t.Fatalf("somma %d: %v invece del rifiuto", sum, err)
+		}
+	}
+	if _, err := k.ProveSum(c, o, testSum()+1); err == nil {
+		t.Fatal("provata una somma falsa")
+	}
+}
+
+// La prova è legata al suo impegno: un altro vettore con la stessa somma non
+// passa.
+func TestOtherCommitment(t *testing.T) {
+	k, c, _, p := testCommit(t)
+	other := append([]int64(nil), testValues...)
+	other[0]++
+	other[1]--
+	oc, oo, err := k.Commit(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.VerifySum(oc, testSum(), p); !errors.Is(err, errReject) {
+		t.Fatalf("prova accettata su un altro impegno: %v", err)
+	}
+	// gli stessi valori reimpegnati con un r nuovo danno un altro impegno
+	rc, _, err := k.Commit(testValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.VerifySum(rc, testSum(), p) == nil {
+		t.Fatal("prova accettata su un reimpegno degli stessi valori")
+	}
+	if _, err := k.ProveSum(c, oo, testSum()); err == nil {
+		t.Fatal("provata un'apertura che non corrisponde all'impegno")
+	}
+}
+
+func TestTamperedProof(t *testing.T) {
+	k, c, _, p := testCommit(t)
+	data := k.MarshalProof(p)
+
+	// un bit in ogni parte della prova: sfida, TG, H e Z
+	for _, off := range []int{0, 32 + 8*N*3 + 7, 32 + 8*N*nbTG + 8*5 + 7, len(data) - 1} {
+		flipped := append([]byte(nil), data...)
+		flipped[off] ^= 1
+		q, err := k.UnmarshalProof(flipped)
+		if err != nil {
+			continue // coefficiente non più ridotto mod q
+		}
+		if k.VerifySum(c, testSum(), q) == nil {
+			t.Fatalf("accettata con il byte %d cambiato", off)
+		}
+	}
+
+	// un h_i con termine noto non nullo, per ogni sfida
+	for i := 0; i < SumChallenges; i++ {
+		q, err := k.UnmarshalProof(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.H[i].Coeffs[0][0] = 1
+		if err := k.VerifySum(c, testSum(), q); !errors.Is(err, errReject) {
+			t.Fatalf("h_%d con termine noto 1: %v", i, err)
+		}
+	}
+
+	// z oltre il limite del rejection sampling
+	q, _ := k.UnmarshalProof(data)
+	q.Z[zm].Coeffs[0][0] = bound(zm, true) + 1
+	if err := k.VerifySum(c, testSum(), q); !errors.Is(err, errReject) {
+		t.Fatalf("z oltre il limite: %v", err)
+	}
+
+	if _, err := k.UnmarshalProof(data[:len(data)-8]); err == nil {
+		t.Fatal("accettata una prova troncata")
+	}
+}
+
+func TestCommitRange(t *testing.T) {
+	k, err := NewKey([]byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []int64{1 << ValueBits, -1 << ValueBits} {
+		if _, _, err := k.Commit([]int64{v}); err == nil {
+			t.Fatalf("impegnato %d, oltre 2^%d", v, ValueBits)
+		}
+	}
+	if _, _, err := k.Commit(make([]int64, N+1)); err == nil {
+		t.Fatalf("impegnati %d valori", N+1)
+	}
+}
+
+// Chi prova una somma falsa deve indovinare tutte le γ_i insieme: l'errore
+// q^(-SumChallenges) deve stare sotto 2^-128, e una sfida in meno no.
+func TestSumSoundness(t *testing.T) {
+	k, err := NewKey([]byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bits := math.Log2(float64(k.q))
+	if got := float64(SumChallenges) * bits; got < 128 {
+		t.Fatalf("errore della somma 2^-%.1f con %d sfide, servono 128 bit", got, SumChallenges)
+	}
+	if float64(SumChallenges-1)*bits >= 128 {
+		t.Fatalf("%d sfide bastano già, %d sono troppe", SumChallenges-1, SumChallenges)
+	}
+}
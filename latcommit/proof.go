@@ -0,0 +1,275 @@
+package latcommit
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/tuneinsight/lattigo/v4/ring"
+	"github.com/tuneinsight/lattigo/v4/utils"
+)
+
+// Proof dimostra che l'impegno apre a un vettore corto con somma S.
+//
+//	TG  t' = A_1·r' e t_g,i = <b_i, r'> + g_i, l'impegno BDLOP dei g_i (forma NTT)
+//	H   h_i = g_i + γ_i·(j·m - S), con termine noto nullo
+//	Z   z = y + c·(r, m, r'), forma coefficienti
+//	C   il seed della sfida c
+type Proof struct {
+	TG []*ring.Poly
+	H  []*ring.Poly
+	Z  []*ring.Poly
+	C  [32]byte
+}
+
+const (
+	nbTG = Rows + SumChallenges
+	nbZ  = 2*RandLen + 1
+	zm   = RandLen // indice di z_m in Z, prima ci sono z_r e dopo z_r'
+)
+
+// ProveSum prova che c, aperto da o, ha somma sum. Rifiuta un'apertura che
+// non corrisponde all'impegno o alla somma.
+func (k *Key) ProveSum(c *Commitment, o *Opening, sum int64) (*Proof, error) {
+	rN, mN := k.ntt(o.R...), k.ntt(o.M)[0]
+	t := k.commit(rN, mN)
+	for i := range t {
+		if !k.r.Equal(t[i], c.T[i]) {
+			return nil, errors.New("latcommit: l'apertura non corrisponde all'impegno")
+		}
+	}
+	var s uint64
+	for _, v := range o.M.Coeffs[0] {
+		s = (s + v) % k.q
+	}
+	if s != k.mod(sum) {
+		return nil, errors.New("latcommit: la somma dei valori non è quella richiesta")
+	}
+
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		return nil, err
+	}
+
+	// g_i uniformi con termine noto nullo, impegnati con r' fresco: riusare r
+	// farebbe uscire j·m da due prove sullo stesso impegno
+	rp := k.ternary(prng)
+	rpN := k.ntt(rp...)
+	uniform := ring.NewUniformSampler(prng, k.r)
+	g := make([]*ring.Poly, SumChallenges)
+	p := &Proof{TG: k.commit(rpN, nil)}
+	for i := range g {
+		g[i] = uniform.ReadNew()
+		g[i].Coeffs[0][0] = 0
+		tg := k.inner(i, rpN)
+		k.r.Add(tg, k.ntt(g[i])[0], tg)
+		p.TG = append(p.TG, tg)
+	}
+
+	h := k.newHash(c, sum)
+	h.polys(p.TG...)
+	gammas := k.gammas(h)
+	jm := k.r.NewPoly()
+	k.r.MulCoeffs(k.j, mN, jm)
+	k.r.InvNTT(jm, jm)
+	jm.Coeffs[0][0] = (jm.Coeffs[0][0] + k.q - k.mod(sum)) % k.q
+	p.H = make([]*ring.Poly, SumChallenges)
+	for i := range p.H {
+		p.H[i] = k.r.NewPoly()
+		k.r.MulScalar(jm, gammas[i], p.H[i])
+		k.r.Add(p.H[i], g[i], p.H[i])
+	}
+	h.polys(p.H...)
+
+	secretN := append(append(append([]*ring.Poly(nil), rN...), mN), rpN...)
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		y := make([]*ring.Poly, nbZ)
+		for i := range y {
+			y[i] = k.bounded(prng, bound(i, false))
+		}
+		yN := k.ntt(y...)
+		w := k.relations(yN, gammas)
+		hh := &hash{data: append([]byte(nil), h.data...)}
+		hh.polys(w...)
+		p.C = hh.sum("c")
+		cN, err := k.challenge(p.C)
+		if err != nil {
+			return nil, err
+		}
+
+		// z = y + c·s, tenuto solo se resta nei limiti
+		p.Z = make([]*ring.Poly, nbZ)
+		ok := true
+		for i := range p.Z {
+			p.Z[i] = k.r.NewPoly()
+			k.r.MulCoeffs(cN, secretN[i], p.Z[i])
+			k.r.InvNTT(p.Z[i], p.Z[i])
+			k.r.Add(p.Z[i], y[i], p.Z[i])
+			if k.centered(p.Z[i]) > bound(i, true) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("latcommit: rejection sampling non riuscito in %d tentativi", maxAttempts)
+}
+
+// bound è il limite del mascheramento di Z[i], o di z se accepted.
+func bound(i int, accepted bool) uint64 {
+	if i == zm {
+		if accepted {
+			return gammaM - Kappa<<ValueBits
+		}
+		return gammaM
+	}
+	if accepted {
+		return gammaR - Kappa
+	}
+	return gammaR
+}
+
+// relations applica le relazioni lineari a un vettore (r, m, r') in forma
+// NTT: A_1·r + a_2·m, A_1·r' e <b_i, r'> - γ_i·j·m per ogni sfida.
+func (k *Key) relations(v []*ring.Poly, gammas []uint64) []*ring.Poly {
+	w := k.commit(v[:RandLen], v[zm])
+	w = append(w, k.commit(v[zm+1:], nil)...)
+	jm := k.r.NewPoly()
+	k.r.MulCoeffs(k.j, v[zm], jm)
+	for i, gamma := range gammas {
+		last := k.inner(i, v[zm+1:])
+		k.r.MulScalarAndSub(jm, gamma, last)
+		w = append(w, last)
+	}
+	return w
+}
+
+// bounded campiona coefficienti uniformi in [-bound, bound].
+func (k *Key) bounded(prng utils.PRNG, bound uint64) *ring.Poly {
+	p := k.r.NewPoly()
+	buf := make([]byte, 8*N)
+	prng.Read(buf)
+	for i := range p.Coeffs[0] {
+		x := binary.BigEndian.Uint64(buf[8*i:]) % (2*bound + 1)
+		p.Coeffs[0][i] = k.mod(int64(x) - int64(bound))
+	}
+	return p
+}
+
+// VerifySum controlla che c impegni un vettore corto con somma sum.
+func (k *Key) VerifySum(c *Commitment, sum int64, p *Proof) error {
+	if len(c.T) != Rows || len(p.TG) != nbTG || len(p.H) != SumChallenges || len(p.Z) != nbZ {
+		return errors.New("latcommit: prova malformata")
+	}
+	for i := range p.H {
+		if p.H[i].Coeffs[0][0] != 0 {
+			return fmt.Errorf("%w: h_%d ha termine noto non nullo, la somma non è %d", errReject, i, sum)
+		}
+	}
+	for i := range p.Z {
+		if k.centered(p.Z[i]) > bound(i, true) {
+			return fmt.Errorf("%w: z[%d] fuori dai limiti", errReject, i)
+		}
+	}
+
+	h := k.newHash(c, sum)
+	h.polys(p.TG...)
+	gammas := k.gammas(h)
+	h.polys(p.H...)
+	cN, err := k.challenge(p.C)
+	if err != nil {
+		return err
+	}
+
+	// w = relazioni(z) - c·(t, t', t_g,i - h_i - γ_i·S)
+	w := k.relations(k.ntt(p.Z...), gammas)
+	rhs := append(append([]*ring.Poly(nil), c.T...), p.TG[:Rows]...)
+	for i, gamma := range gammas {
+		last := k.r.NewPoly()
+		last.Coeffs[0][0] = k.mod(sum)
+		k.r.MulScalar(last, gamma, last)
+		k.r.Add(last, p.H[i], last)
+		k.r.NTT(last, last)
+		k.r.Sub(p.TG[Rows+i], last, last)
+		rhs = append(rhs, last)
+	}
+	tmp := k.r.NewPoly()
+	for i := range w {
+		k.r.MulCoeffs(cN, rhs[i], tmp)
+		k.r.Sub(w[i], tmp, w[i])
+	}
+	h.polys(w...)
+	if h.sum("c") != p.C {
+		return fmt.Errorf("%w: la sfida non corrisponde", errReject)
+	}
+	return nil
+}
+
+// Serializzazione: polinomi come N coefficienti uint64 big-endian, in ordine;
+// la lunghezza è fissa e ogni coefficiente deve essere ridotto mod q.
+
+// MarshalCommitment serializza l'impegno.
+func (k *Key) MarshalCommitment(c *Commitment) []byte {
+	return appendPolys(nil, c.T...)
+}
+
+// UnmarshalCommitment legge un impegno non fidato.
+func (k *Key) UnmarshalCommitment(data []byte) (*Commitment, error) {
+	polys, err := k.readPolys(data, Rows)
+	if err != nil {
+		return nil, err
+	}
+	return &Commitment{T: polys}, nil
+}
+
+// MarshalProof serializza la prova: seed della sfida, poi TG, H e Z.
+func (k *Key) MarshalProof(p *Proof) []byte {
+	data := append([]byte(nil), p.C[:]...)
+	data = appendPolys(data, p.TG...)
+	data = appendPolys(data, p.H...)
+	return appendPolys(data, p.Z...)
+}
+
+// UnmarshalProof legge una prova non fidata.
+func (k *Key) UnmarshalProof(data []byte) (*Proof, error) {
+	if len(data) < 32 {
+		return nil, errors.New("latcommit: prova troncata")
+	}
+	p := &Proof{}
+	copy(p.C[:], data)
+	polys, err := k.readPolys(data[32:], nbTG+SumChallenges+nbZ)
+	if err != nil {
+		return nil, err
+	}
+	p.TG, p.H, p.Z = polys[:nbTG], polys[nbTG:nbTG+SumChallenges], polys[nbTG+SumChallenges:]
+	return p, nil
+}
+
+func appendPolys(data []byte, p ...*ring.Poly) []byte {
+	for _, x := range p {
+		for _, c := range x.Coeffs[0] {
+			data = binary.BigEndian.AppendUint64(data, c)
+		}
+	}
+	return data
+}
+
+func (k *Key) readPolys(data []byte, n int) ([]*ring.Poly, error) {
+	if len(data) != n*N*8 {
+		return nil, fmt.Errorf("latcommit: %d byte, attesi %d", len(data), n*N*8)
+	}
+	polys := make([]*ring.Poly, n)
+	for i := range polys {
+		polys[i] = k.r.NewPoly()
+		for l := range polys[i].Coeffs[0] {
+			c := binary.BigEndian.Uint64(data[(i*N+l)*8:])
+			if c >= k.q {
+				return nil, fmt.Errorf("latcommit: coefficiente non ridotto nel polinomio %d", i)
+			}
+			polys[i].Coeffs[0][l] = c
+		}
+	}
+	return polys, nil
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"math/bits"
+	"os"
+	"slices"
 
 	"github.com/tuneinsight/lattigo/v4/bfv"
 	"github.com/tuneinsight/lattigo/v4/rlwe"
@@ -10,24 +14,28 @@ import (
 	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
+
+	"zk-test/bfvcmp"
+	"zk-test/bfvplan"
+	"zk-test/bfvproof"
 )
 
-// --- ZKP: Prova che la somma degli elementi non superi un limite ---
-type SumCheckCircuit struct {
-	Inputs []frontend.Variable `gnark:",secret"`
-	Sum    frontend.Variable   `gnark:",public"`
-}
-
-func (c *SumCheckCircuit) Define(api frontend.API) error {
-	var res frontend.Variable = 0
-	for _, v := range c.Inputs {
-		res = api.Add(res, v)
-	}
-	api.AssertIsEqual(res, c.Sum)
-	return nil
-}
+// Di default il key holder decifra solo il bit totale ≥ -cap, calcolato
+// sul ciphertext aggregato; con -total decifra il totale e ne prova la
+// decifratura, come prima del confronto cifrato. Il controllo dei valori
+// cifrati e il budget di rumore dopo ogni passo decifrano valori e somme
+// parziali: girano solo con -debug.
+//
+//	go run ./lattigo
+//	go run ./lattigo -total
+//	go run ./lattigo -debug
 
 func main() {
+	total := flag.Bool("total", false, "decifra il totale con le prove di cifratura e decifratura invece del solo bit")
+	kpiCap := flag.Uint64("cap", 5000, "soglia del confronto cifrato")
+	debug := flag.Bool("debug", false, "decifra valori e somme parziali per controllo e budget di rumore (rivela il totale)")
+	flag.Parse()
+
 	// 1. SIMULAZIONE INPUT DA JSON (100 Valori KPI)
 	kpiValues := make([]uint64, 100)
 	var expectedSum uint64
@@ -37,59 +45,300 @@ func main() {
 	}
 
 	// 2. SETUP LATTIGO (BFV)
-	params, _ := bfv.NewParametersFromLiteral(bfv.PN15QP880)
+	// per il solo totale bastano somme e rotazioni e il planner sceglie
+	// PN12QP109 (N=4096, due moduli), che è anche quello che la prova di
+	// cifratura può permettersi; il confronto con la soglia costa profondità 16
+	// e porta a PN15QP880
+	var maxValue uint64
+	for _, v := range kpiValues {
+		maxValue = max(maxValue, v)
+	}
+	plan, err := bfvplan.Choose(bfvplan.Requirements{
+		ValueBits: bits.Len64(maxValue),
+		Count:     len(kpiValues),
+		InnerSum:  true,
+		Compare:   !*total,
+	})
+	if err != nil {
+		fmt.Printf("Errore parametri: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("[Setup] Parametri BFV: %s\n", plan)
+	params := plan.Params
 	kgen := bfv.NewKeyGenerator(params)
 	sk, pk := kgen.GenKeyPair()
 
-	// Ci serve la Rotation Key per sommare gli slot tra loro
-	galEls := params.GaloisElementsForColumnRotation()
-	gks := kgen.GenGaloisKeys(galEls, sk)
+	// Ci servono le Rotation Key solo per le rotazioni di innerSum e del
+	// confronto, la chiave di rilinearizzazione solo per il confronto
+	galEls := innerSumGaloisElements(params)
+	var evk rlwe.EvaluationKey
+	if !*total {
+		galEls = append(galEls, bfvcmp.GaloisElements(params)...)
+		evk.Rlk = kgen.GenRelinearizationKey(sk, 1)
+	}
+	slices.Sort(galEls)
+	evk.Rtks = kgen.GenRotationKeys(slices.Compact(galEls), sk)
 
-	encryptor := bfv.NewEncryptor(params, pk)
+	decryptor := bfv.NewDecryptor(params, sk)
 	encoder := bfv.NewEncoder(params)
-	evaluator := bfv.NewEvaluator(params, rlwe.EvaluationKey{GalEls: galEls, Gks: gks})
+	evaluator := bfv.NewEvaluator(params, evk)
 
-	// 3. COMMITMENT (Batching di 100 valori in 1 Ciphertext)
-	slots := make([]uint64, params.N())
-	copy(slots, kpiValues)
+	if *total {
+		totalWithProofs(params, pk, sk, encoder, decryptor, evaluator, kpiValues, expectedSum, *debug)
+		return
+	}
 
-	pt := bfv.NewPlaintext(params, params.MaxLevel())
-	encoder.Encode(slots, pt)
-	ct := encryptor.EncryptNew(pt)
-	fmt.Printf("[Client] Committati %d valori in un singolo Ciphertext\n", len(kpiValues))
+	// 3. COMMITMENT (Batching di 100 valori in 1 Ciphertext)
+	// i circuiti di bfvproof reggono N=4096: su PN15QP880 sarebbero decine di
+	// milioni di vincoli, quindi qui il ciphertext non ha prova di cifratura
+	ct := bfv.NewEncryptor(params, pk).EncryptNew(encoder.EncodeNew(kpiValues, params.MaxLevel()))
+	if *debug {
+		if err := checkDecrypt(params, encoder, decryptor, ct, kpiValues); err != nil {
+			fmt.Printf("Errore cifratura: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("[Client] Committati %d valori in un singolo Ciphertext (senza prova di cifratura, vedi -total)\n", len(kpiValues))
 
 	// 4. CALCOLO OMOMORFICO (Sommatoria interna tramite rotazioni)
-	// Questa è la tecnica "Full Sum" per sommare tutti gli slot tra loro
-	for i := 1; i < params.N(); i <<= 1 {
-		rotated := evaluator.RotateRowsNew(ct)
-		evaluator.Add(ct, rotated, ct)
+	// il key holder non vede il totale: il budget dopo ogni passo solo con -debug
+	result := ct.CopyNew()
+	innerSum(params, evaluator, result, noiseReport(params, sk, ct, result, *debug))
+	fmt.Printf("[Server] Sommatoria omomorfica completata (%d rotazioni)\n", len(innerSumGaloisElements(params)))
+
+	// 5. CONFRONTO CIFRATO CON LA SOGLIA
+	// sullo stesso ciphertext aggregato e con le stesse chiavi
+	cmp, err := bfvcmp.Compare(params, evaluator, encoder, result, plan.WorstSum.Uint64(), *kpiCap)
+	if err != nil {
+		fmt.Printf("Errore confronto: %v\n", err)
+		os.Exit(1)
 	}
-	fmt.Println("[Server] Sommatoria omomorfica completata (Log2 n rotazioni)")
+	fmt.Printf("[Server] Confronto cifrato con la soglia %d (profondità %d)\n", *kpiCap, bfvcmp.Depth(params.T()))
 
-	// 5. ZKP (Gnark) - Prova di coerenza
-	fmt.Println("[Client] Generazione prova ZK per la sommatoria...")
-	circuit := &SumCheckCircuit{Inputs: make([]frontend.Variable, 100)}
-	ccs, _ := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
-	pkZK, vkZK, _ := groth16.Setup(ccs)
+	// 6. DECIFRATURA DEL SOLO BIT
+	// nel testo in chiaro c'è solo il bit nello slot 0, il totale resta cifrato
+	res := encoder.DecodeUintNew(decryptor.DecryptNew(cmp))
+	for i, v := range res[1:] {
+		if v != 0 {
+			fmt.Printf("[Key holder] Errore: slot %d non nullo: %d\n", i+1, v)
+			os.Exit(1)
+		}
+	}
+	if res[0] > 1 {
+		fmt.Printf("[Key holder] Errore: bit decifrato %d\n", res[0])
+		os.Exit(1)
+	}
+	fmt.Printf("[Key holder] Bit decifrato: %d\n", res[0])
 
-	assignment := &SumCheckCircuit{Sum: expectedSum}
-	for i := 0; i < 100; i++ {
-		assignment.Inputs[i] = kpiValues[i]
+	exceeds := res[0] == 1
+	fmt.Printf("\n[Risultato Finale] Sommatoria KPI sopra la soglia %d: %v (totale non decifrato)\n", *kpiCap, exceeds)
+	if exceeds != (expectedSum >= *kpiCap) {
+		fmt.Printf("Errore: %d ≥ %d è %v\n", expectedSum, *kpiCap, expectedSum >= *kpiCap)
+		os.Exit(1)
 	}
+}
 
-	witness, _ := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
-	pubWitness, _ := witness.Public()
-	proof, _ := groth16.Prove(ccs, pkZK, witness)
+// totalWithProofs è il percorso -total: il client cifra con la prova di
+// cifratura, il server somma e il key holder decifra il totale provando che
+// la decifratura è corretta.
+func totalWithProofs(params bfv.Parameters, pk *rlwe.PublicKey, sk *rlwe.SecretKey, encoder bfv.Encoder, decryptor rlwe.Decryptor, evaluator bfv.Evaluator, kpiValues []uint64, expectedSum uint64, debug bool) {
+	// 3. COMMITMENT (Batching di 100 valori in 1 Ciphertext)
+	// il client cifra da sé tenendo la casualità, che gli serve per la prova
+	bp, err := bfvproof.NewParams(params, pk)
+	if err != nil {
+		panic(err)
+	}
+	ct, encWitness, err := bfvproof.Encrypt(params, bp, kpiValues)
+	if err != nil {
+		panic(err)
+	}
+	if debug {
+		if err := checkDecrypt(params, encoder, decryptor, ct, kpiValues); err != nil {
+			fmt.Printf("Errore cifratura: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("[Client] Committati %d valori in un singolo Ciphertext (Poseidon2 salato dei valori: %s)\n", len(kpiValues), encWitness.Commit.String())
 
-	// 6. VERIFICA E DECRYPT
-	if err := groth16.Verify(proof, vkZK, pubWitness); err == nil {
-		fmt.Println("[Auditor] ✓ Prova ZK valida: la somma corrisponde ai dati di dettaglio.")
+	// 4. ZKP (Gnark) - il ciphertext cifra i valori del commitment e la loro somma
+	fmt.Println("[Client] Generazione prova ZK di cifratura e sommatoria...")
+	circuit := bfvproof.NewEncryptionCircuit(bp, len(kpiValues))
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("[Client] Circuito: %d vincoli\n", ccs.GetNbConstraints())
+	pkZK, vkZK, err := groth16.Setup(ccs)
+	if err != nil {
+		panic(err)
+	}
+	witness, err := frontend.NewWitness(encWitness.Assignment(bp, ct), ecc.BN254.ScalarField())
+	if err != nil {
+		panic(err)
+	}
+	proof, err := groth16.Prove(ccs, pkZK, witness)
+	if err != nil {
+		panic(err)
 	}
 
-	decryptor := bfv.NewDecryptor(params, sk)
-	resPt := decryptor.DecryptNew(ct)
+	// 5. CALCOLO OMOMORFICO (Sommatoria interna tramite rotazioni)
+	// il server lavora su una copia: l'auditor verifica la prova sul ciphertext inviato
+	// budget di rumore misurato da chi ha la chiave dopo ogni passo, solo con -debug
+	result := ct.CopyNew()
+	innerSum(params, evaluator, result, noiseReport(params, sk, ct, result, debug))
+	fmt.Printf("[Server] Sommatoria omomorfica completata (%d rotazioni)\n", len(innerSumGaloisElements(params)))
+
+	// 6. VERIFICA DELLA CIFRATURA
+	// l'auditor ricostruisce il witness pubblico da ciphertext, somma e commitment
+	commitment := encWitness.Commit.String()
+	if err := verify(bp, vkZK, proof, ct, len(kpiValues), expectedSum, commitment); err != nil {
+		fmt.Printf("[Auditor] ✗ Prova ZK non valida: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("[Auditor] ✓ Prova ZK valida: il ciphertext cifra i valori del commitment, la cui somma è quella dichiarata.")
+
+	// controprove: la stessa prova non vale per un'altra somma né per un altro ciphertext
+	if verify(bp, vkZK, proof, ct, len(kpiValues), expectedSum+1, commitment) == nil {
+		fmt.Println("[Auditor] Errore: prova accettata con una somma diversa")
+		os.Exit(1)
+	}
+	other := ct.CopyNew()
+	other.Value[0].Coeffs[0][0] = (other.Value[0].Coeffs[0][0] + 1) % bp.Q[0]
+	if verify(bp, vkZK, proof, other, len(kpiValues), expectedSum, commitment) == nil {
+		fmt.Println("[Auditor] Errore: prova accettata con un ciphertext alterato")
+		os.Exit(1)
+	}
+	fmt.Println("[Auditor] ✓ Prova rifiutata con somma o ciphertext alterati")
+
+	// 7. DECIFRATURA VERIFICABILE
+	// chi ha la chiave segreta decifra il risultato e prova che la somma
+	// annunciata è la decifratura corretta, senza rivelare la chiave
+	fmt.Println("[Key holder] Decifratura e prova ZK di decifratura...")
+	decWitness, err := bfvproof.Decrypt(params, bp, sk, result)
+	if err != nil {
+		panic(err)
+	}
 	resSlots := make([]uint64, params.N())
-	encoder.Decode(resPt, resSlots)
+	encoder.Decode(decryptor.DecryptNew(result), resSlots)
+	if resSlots[0] != decWitness.Sum {
+		fmt.Printf("Errore decifratura: lattigo %d, bfvproof %d\n", resSlots[0], decWitness.Sum)
+		os.Exit(1)
+	}
+	decCircuit := bfvproof.NewDecryptionCircuit(bp)
+	decCcs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, decCircuit)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("[Key holder] Circuito: %d vincoli\n", decCcs.GetNbConstraints())
+	decPK, decVK, err := groth16.Setup(decCcs)
+	if err != nil {
+		panic(err)
+	}
+	decFull, err := frontend.NewWitness(decWitness.Assignment(bp, result), ecc.BN254.ScalarField())
+	if err != nil {
+		panic(err)
+	}
+	decProof, err := groth16.Prove(decCcs, decPK, decFull)
+	if err != nil {
+		panic(err)
+	}
+	announced := decWitness.Sum
+
+	// l'auditor ha solo il ciphertext aggregato, la chiave pubblica e la somma annunciata
+	if err := verifyDecryption(bp, decVK, decProof, result, announced); err != nil {
+		fmt.Printf("[Auditor] ✗ Prova di decifratura non valida: %v\n", err)
+		os.Exit(1)
+	}
+	if verifyDecryption(bp, decVK, decProof, result, announced+1) == nil {
+		fmt.Println("[Auditor] Errore: prova di decifratura accettata con un'altra somma")
+		os.Exit(1)
+	}
+	fmt.Println("[Auditor] ✓ Prova di decifratura valida: la somma annunciata è la decifratura del ciphertext aggregato.")
+
+	fmt.Printf("\n[Risultato Finale] Sommatoria KPI Decifrata: %d (Provata: %d)\n", announced, expectedSum)
+	if announced != expectedSum {
+		os.Exit(1)
+	}
+}
+
+// verifyDecryption controlla la prova di decifratura: witness pubblico dal
+// ciphertext aggregato e dalla somma annunciata.
+func verifyDecryption(bp *bfvproof.Params, vk groth16.VerifyingKey, proof groth16.Proof, ct *rlwe.Ciphertext, sum uint64) error {
+	assignment := bfvproof.PublicDecryption(bp, ct, sum)
+	pubWitness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		return err
+	}
+	return groth16.Verify(proof, vk, pubWitness)
+}
+
+// verify controlla la prova con il witness pubblico che l'auditor ricava dal
+// ciphertext ricevuto e da somma e commitment dichiarati dal client.
+func verify(bp *bfvproof.Params, vk groth16.VerifyingKey, proof groth16.Proof, ct *rlwe.Ciphertext, nbValues int, sum uint64, commitment string) error {
+	assignment := bfvproof.PublicAssignment(bp, ct, nbValues, sum, commitment)
+	pubWitness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		return err
+	}
+	return groth16.Verify(proof, vk, pubWitness)
+}
 
-	fmt.Printf("\n[Risultato Finale] Sommatoria KPI Decifrata: %d (Attesa: %d)\n", resSlots[0], expectedSum)
+// noiseReport stampa il budget di rumore di ct e restituisce il callback di
+// innerSum che lo stampa per result dopo ogni passo. Il budget decifra il
+// ciphertext, quindi senza debug non stampa nulla e restituisce nil.
+func noiseReport(params bfv.Parameters, sk *rlwe.SecretKey, ct, result *rlwe.Ciphertext, debug bool) func(step string) {
+	if !debug {
+		return nil
+	}
+	fmt.Printf("[Key holder] Budget di rumore dopo cifratura: %.1f bit\n", bfvplan.Budget(params, sk, ct))
+	return func(step string) {
+		fmt.Printf("[Key holder] Budget di rumore dopo %s: %.1f bit\n", step, bfvplan.Budget(params, sk, result))
+	}
+}
+
+// checkDecrypt controlla che ct si decifri nei valori, con gli altri slot a zero.
+func checkDecrypt(params bfv.Parameters, encoder bfv.Encoder, decryptor rlwe.Decryptor, ct *rlwe.Ciphertext, values []uint64) error {
+	res := make([]uint64, params.N())
+	encoder.Decode(decryptor.DecryptNew(ct), res)
+	for i := range res {
+		var want uint64
+		if i < len(values) {
+			want = values[i]
+		}
+		if res[i] != want {
+			return fmt.Errorf("slot %d: %d invece di %d", i, res[i], want)
+		}
+	}
+	return nil
+}
+
+// Gli N slot BFV sono una matrice 2 × N/2: RotateColumns ruota le due righe
+// insieme, RotateRows scambia le righe.
+
+// innerSumGaloisElements sono le rotazioni di colonna per 1, 2, 4, ..., N/4 e
+// lo scambio delle righe: le sole chiavi che servono a innerSum.
+func innerSumGaloisElements(params bfv.Parameters) []uint64 {
+	var galEls []uint64
+	for k := 1; k < params.N()/2; k <<= 1 {
+		galEls = append(galEls, params.GaloisElementForColumnRotationBy(k))
+	}
+	return append(galEls, params.GaloisElementForRowRotation())
+}
+
+// innerSum mette in ogni slot di ct la somma di tutti gli slot: log2(N/2)
+// rotazioni di colonna sommano ciascuna riga, lo scambio delle righe somma le
+// due metà. Se after non è nil viene chiamata dopo ogni rotazione e somma.
+func innerSum(params bfv.Parameters, evaluator bfv.Evaluator, ct *rlwe.Ciphertext, after func(step string)) {
+	for k := 1; k < params.N()/2; k <<= 1 {
+		rotated := evaluator.RotateColumnsNew(ct, k)
+		evaluator.Add(ct, rotated, ct)
+		if after != nil {
+			after(fmt.Sprintf("rotazione di %d", k))
+		}
+	}
+	rotated := evaluator.RotateRowsNew(ct)
+	evaluator.Add(ct, rotated, ct)
+	if after != nil {
+		after("scambio delle righe")
+	}
 }
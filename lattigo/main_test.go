@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+)
+
+// innerSum su vettori casuali: ogni slot decifrato deve essere la somma in
+// chiaro modulo t. Basta il preset più piccolo, le rotazioni sono le stesse.
+func TestInnerSum(t *testing.T) {
+	params, err := bfv.NewParametersFromLiteral(bfv.PN12QP109)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kgen := bfv.NewKeyGenerator(params)
+	sk, pk := kgen.GenKeyPair()
+	encoder := bfv.NewEncoder(params)
+	encryptor := bfv.NewEncryptor(params, pk)
+	decryptor := bfv.NewDecryptor(params, sk)
+	evaluator := bfv.NewEvaluator(params, rlwe.EvaluationKey{Rtks: kgen.GenRotationKeys(innerSumGaloisElements(params), sk)})
+
+	mod := params.T()
+	for r := 0; r < 3; r++ {
+		values := make([]uint64, params.N())
+		var sum uint64
+		for i := range values {
+			values[i] = rand.Uint64N(mod)
+			sum = (sum + values[i]) % mod
+		}
+
+		ct := encryptor.EncryptNew(encoder.EncodeNew(values, params.MaxLevel()))
+		var steps int
+		innerSum(params, evaluator, ct, func(string) { steps++ })
+		if steps != len(innerSumGaloisElements(params)) {
+			t.Fatalf("%d passi, attese %d rotazioni", steps, len(innerSumGaloisElements(params)))
+		}
+
+		res := encoder.DecodeUintNew(decryptor.DecryptNew(ct))
+		for i := range res {
+			if res[i] != sum {
+				t.Fatalf("vettore %d, slot %d: %d invece di %d", r, i, res[i], sum)
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+
+	"zk-test/bfvagg"
+)
+
+// Aggregazione cross-tenant: ogni consociata cifra i propri KPI con la chiave
+// pubblica del gruppo e manda i byte di MarshalBinary al server (bfvagg), che
+// somma i ciphertext senza decifrarli. Chi ha la chiave segreta decifra solo
+// l'aggregato: nello slot j c'è il totale di gruppo del KPI j. Il main
+// controlla i totali, l'elenco dei contributori e che invii duplicati o
+// malformati vengano rifiutati; se qualcosa non torna esce con codice 1.
+//
+//	go run ./lattigo_aggregation -tenants 20 -kpis 100
+
+// maxValue è il limite dei KPI d'esempio: tenants·maxValue deve restare sotto
+// t, altrimenti i totali vanno in wraparound.
+const maxValue = 1000
+
+func main() {
+	tenants := flag.Int("tenants", 20, "numero di consociate")
+	kpis := flag.Int("kpis", 100, "KPI per consociata")
+	flag.Parse()
+
+	// 1. SETUP: la chiave pubblica è condivisa, la segreta resta alla capogruppo
+	params, err := bfv.NewParametersFromLiteral(bfv.PN12QP109)
+	if err != nil {
+		panic(err)
+	}
+	if *tenants < 1 || *kpis < 1 || *kpis > params.N() {
+		fmt.Printf("servono almeno una consociata e tra 1 e %d KPI\n", params.N())
+		os.Exit(1)
+	}
+	if uint64(*tenants)*maxValue >= params.T() {
+		fmt.Printf("%d consociate: i totali superano t = %d\n", *tenants, params.T())
+		os.Exit(1)
+	}
+	kgen := bfv.NewKeyGenerator(params)
+	sk, pk := kgen.GenKeyPair()
+
+	server, err := bfvagg.NewServer(params)
+	if err != nil {
+		panic(err)
+	}
+
+	// 2. CONSOCIATE: cifrano e inviano in parallelo
+	values := make([][]uint64, *tenants)
+	want := make([]uint64, *kpis)
+	for i := range values {
+		r := rand.New(rand.NewPCG(uint64(i), 0))
+		values[i] = make([]uint64, *kpis)
+		for j := range values[i] {
+			values[i][j] = r.Uint64N(maxValue)
+			want[j] += values[i][j]
+		}
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, *tenants)
+	sizes := make([]int, *tenants)
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data, err := encrypt(params, pk, values[i])
+			if err == nil {
+				err = server.Submit(tenant(i), data)
+			}
+			errs[i] = err
+			sizes[i] = len(data)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			fmt.Printf("Errore invio: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("[Consociate] %d ciphertext da %d KPI inviati (%d byte ciascuno)\n", *tenants, *kpis, sizes[0])
+
+	// 3. INVII DA RIFIUTARE: l'aggregato non deve cambiare
+	failed := false
+	dup, err := encrypt(params, pk, values[0])
+	if err != nil {
+		panic(err)
+	}
+	other, err := bfv.NewParametersFromLiteral(bfv.PN13QP218)
+	if err != nil {
+		panic(err)
+	}
+	_, otherPK := bfv.NewKeyGenerator(other).GenKeyPair()
+	foreign, err := encrypt(other, otherPK, values[0])
+	if err != nil {
+		panic(err)
+	}
+	// stessa lunghezza, ma l'header del primo polinomio dichiara N = 2^32-1:
+	// senza controlli UnmarshalBinary proverebbe ad allocarlo
+	forged := append([]byte(nil), dup...)
+	hdr := new(rlwe.MetaData).MarshalBinarySize() + 1
+	copy(forged[hdr:], []byte{0xff, 0xff, 0xff, 0xff})
+	unreduced := append([]byte(nil), dup...)
+	for k := len(unreduced) - 8; k < len(unreduced); k++ {
+		unreduced[k] = 0xff
+	}
+	rejects := []struct {
+		name        string
+		contributor string
+		data        []byte
+	}{
+		{"invio duplicato", tenant(0), dup},
+		{"ciphertext troncato", "intruso", dup[:len(dup)/2]},
+		{"parametri diversi", "intruso", foreign},
+		{"header falsato", "intruso", forged},
+		{"coefficiente non ridotto", "intruso", unreduced},
+	}
+	for _, r := range rejects {
+		if err := server.Submit(r.contributor, r.data); err != nil {
+			fmt.Printf("[Server] Rifiutato %s: %v\n", r.name, err)
+		} else {
+			fmt.Printf("ERRORE: %s accettato\n", r.name)
+			failed = true
+		}
+	}
+
+	// 4. AGGREGATO: il server lo manda serializzato con i contributori inclusi
+	data, contributors, err := server.MarshalSum()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("[Server] Aggregato di %d contributori: %v\n", len(contributors), contributors)
+	if len(contributors) != *tenants {
+		fmt.Printf("ERRORE: %d contributori, attesi %d\n", len(contributors), *tenants)
+		failed = true
+	}
+
+	// 5. DECIFRATURA dalla capogruppo
+	sum := new(rlwe.Ciphertext)
+	if err := sum.UnmarshalBinary(data); err != nil {
+		panic(err)
+	}
+	encoder := bfv.NewEncoder(params)
+	got := encoder.DecodeUintNew(bfv.NewDecryptor(params, sk).DecryptNew(sum))
+	for j := range got {
+		var w uint64
+		if j < len(want) {
+			w = want[j]
+		}
+		if got[j] != w {
+			fmt.Printf("ERRORE slot %d: %d, atteso %d\n", j, got[j], w)
+			failed = true
+			break
+		}
+	}
+	fmt.Printf("[Capogruppo] Totali di gruppo dei primi KPI: %v (attesi %v)\n", got[:min(5, *kpis)], want[:min(5, *kpis)])
+	if failed {
+		os.Exit(1)
+	}
+	fmt.Println("[Capogruppo] Tutti i totali corrispondono")
+}
+
+func tenant(i int) string {
+	return fmt.Sprintf("consociata-%02d", i)
+}
+
+// encrypt cifra i KPI negli slot e restituisce i byte da inviare; encoder ed
+// encryptor non sono concorrenti, ogni consociata usa i propri.
+func encrypt(params bfv.Parameters, pk *rlwe.PublicKey, values []uint64) ([]byte, error) {
+	pt := bfv.NewPlaintext(params, params.MaxLevel())
+	bfv.NewEncoder(params).Encode(values, pt)
+	return bfv.NewEncryptor(params, pk).EncryptNew(pt).MarshalBinary()
+}
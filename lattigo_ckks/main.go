@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/tuneinsight/lattigo/v4/ckks"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+
+	"zk-test/kpiinput"
+)
+
+// Aggregazione cifrata dei KPI reali con CKKS, alternativa a lattigo/ (BFV):
+// niente scaling a interi né wraparound mod t, i valori float64 vanno negli
+// slot così come sono. Somma, media e somma pesata vengono decifrate e
+// confrontate con il calcolo in chiaro; se l'errore misurato supera -maxerr
+// esce con codice 1. L'errore è misurato sul dato decifrato, non è un limite
+// a priori: con altri valori o altre chiavi può essere diverso.
+//
+//	go run ./lattigo_ckks -input kpi.json
+//
+// stesso JSON dei demo SNARK, con "weights" facoltativo per la somma pesata.
+
+// exampleData sono i valori usati senza -input, con i pesi.
+const exampleData = `{
+	"values":  [1.3, 2.3, 4.234, 3.87, 5.12, 6.45, 7.01, 6.88],
+	"weights": [0.5, 0.5, 1, 1, 1, 1.5, 1.5, 2]
+}`
+
+func main() {
+	input := kpiinput.Flag()
+	maxErr := flag.Float64("maxerr", 1e-3, "soglia sull'errore assoluto misurato sui risultati decifrati")
+	flag.Parse()
+
+	data, err := kpiinput.Load(*input, exampleData)
+	if err != nil {
+		panic(err)
+	}
+	if len(data.Values) == 0 {
+		fmt.Println("Nessun valore KPI")
+		os.Exit(1)
+	}
+	weights := data.Weights
+	if weights == nil {
+		weights = make([]float64, len(data.Values))
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+
+	// 1. SETUP LATTIGO (CKKS)
+	// PN14QP438: 8192 slot e scala 2^34; con PN13QP218 (scala 2^30) le 13
+	// rotazioni di InnerSum lasciano un errore intorno a 5e-4. Servono solo 2
+	// livelli, uno per la media e uno per i pesi
+	params, err := ckks.NewParametersFromLiteral(ckks.PN14QP438)
+	if err != nil {
+		panic(err)
+	}
+	if len(data.Values) > params.Slots() {
+		fmt.Printf("%d valori, al massimo %d slot\n", len(data.Values), params.Slots())
+		os.Exit(1)
+	}
+	kgen := ckks.NewKeyGenerator(params)
+	sk, pk := kgen.GenKeyPair()
+	rots := params.RotationsForInnerSum(1, params.Slots())
+	rtks := kgen.GenRotationKeysForRotations(rots, false, sk)
+
+	encoder := ckks.NewEncoder(params)
+	encryptor := ckks.NewEncryptor(params, pk)
+	decryptor := ckks.NewDecryptor(params, sk)
+	evaluator := ckks.NewEvaluator(params, rlwe.EvaluationKey{Rtks: rtks})
+
+	// 2. CIFRATURA (tutti i valori in un ciphertext, gli slot liberi a zero)
+	slots := make([]float64, params.Slots())
+	copy(slots, data.Values)
+	pt := encoder.EncodeNew(slots, params.MaxLevel(), params.DefaultScale(), params.LogSlots())
+	ct := encryptor.EncryptNew(pt)
+	fmt.Printf("[Client] Cifrati %d valori KPI reali in un ciphertext CKKS\n", len(data.Values))
+
+	// 3. CALCOLO OMOMORFICO
+	// somma: InnerSum mette in ogni slot la somma di tutti gli slot
+	sum := ckks.NewCiphertext(params, 1, ct.Level())
+	evaluator.InnerSum(ct, 1, params.Slots(), sum)
+
+	// media: somma per 1/n, poi rescale per tornare alla scala di default
+	mean := evaluator.MultByConstNew(sum, 1/float64(len(data.Values)))
+	if err := evaluator.Rescale(mean, params.DefaultScale(), mean); err != nil {
+		panic(err)
+	}
+
+	// somma pesata: pesi in chiaro codificati alla scala dell'ultimo modulo, così
+	// dopo il rescale la scala resta quella di default
+	w := make([]float64, params.Slots())
+	copy(w, weights)
+	ptW := encoder.EncodeNew(w, ct.Level(), rlwe.NewScale(params.QiFloat64(ct.Level())), params.LogSlots())
+	weighted := evaluator.MulNew(ct, ptW)
+	if err := evaluator.Rescale(weighted, params.DefaultScale(), weighted); err != nil {
+		panic(err)
+	}
+	evaluator.InnerSum(weighted, 1, params.Slots(), weighted)
+	fmt.Printf("[Server] Somma, media e somma pesata calcolate (%d rotazioni)\n", len(rots))
+
+	// 4. DECIFRATURA E ERRORE MISURATO
+	var wantSum, wantWeighted float64
+	for i, v := range data.Values {
+		wantSum += v
+		wantWeighted += v * weights[i]
+	}
+	results := []struct {
+		name string
+		ct   *rlwe.Ciphertext
+		want float64
+	}{
+		{"Somma", sum, wantSum},
+		{"Media", mean, wantSum / float64(len(data.Values))},
+		{"Somma pesata", weighted, wantWeighted},
+	}
+	failed := false
+	fmt.Println()
+	for _, r := range results {
+		got, worst := decrypt(params, encoder, decryptor, r.ct, r.want)
+		fmt.Printf("[Risultato] %-12s %.6f (atteso %.6f) errore misurato slot 0 %.2e, peggior slot %.2e (%.1f bit di precisione, livello %d)\n",
+			r.name, got, r.want, math.Abs(got-r.want), worst, -math.Log2(worst), r.ct.Level())
+		if worst > *maxErr {
+			fmt.Printf("ERRORE %s: errore misurato %.2e oltre -maxerr %.2e\n", r.name, worst, *maxErr)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// decrypt restituisce lo slot 0 e l'errore misurato più grande su tutti gli
+// slot, che dopo InnerSum devono valere tutti want.
+func decrypt(params ckks.Parameters, encoder ckks.Encoder, decryptor rlwe.Decryptor, ct *rlwe.Ciphertext, want float64) (float64, float64) {
+	values := encoder.Decode(decryptor.DecryptNew(ct), params.LogSlots())
+	var worst float64
+	for _, v := range values {
+		worst = math.Max(worst, math.Abs(real(v)-want))
+	}
+	return real(values[0]), worst
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"zk-test/kpiinput"
+	"zk-test/latcommit"
+)
+
+// Impegno su reticoli dei KPI (latcommit) al posto degli hash Poseidon di
+// zsnark_Poseidon_linear_commitment: il fornitore impegna il vettore dei
+// valori ×1000 e prova che la somma è quella dichiarata e che i valori sono
+// corti, senza rivelarli. Impegno e prova passano in forma serializzata; il
+// main prova anche somme, impegni e prove sbagliati e, se uno viene
+// accettato, esce con codice 1.
+//
+//	go run ./lattigo_commitment -input kpi.json
+
+// exampleData sono i valori usati senza -input.
+const exampleData = `{
+	"values": [
+		1.3, 2.3, 4.234, 3.87, 5.12, 6.45, 7.01, 6.88,
+		5.76, 4.92, 3.58, 2.91, 3.14, 4.01, 5.33, 6.02,
+		6.77, 7.25, 8.1, 7.84, 6.59, 5.48, 4.66, 3.97,
+		3.21, 2.75, 2.18, 1.92, 1.56, 1.11
+	]
+}`
+
+func main() {
+	input := kpiinput.Flag()
+	seed := flag.String("seed", "kpi-latcommit", "seed pubblico delle matrici")
+	flag.Parse()
+
+	data, err := kpiinput.Load(*input, exampleData)
+	if err != nil {
+		panic(err)
+	}
+	values := make([]int64, len(data.Values))
+	var sum int64
+	for i, v := range data.Values {
+		values[i] = int64(math.Round(v * 1000))
+		sum += values[i]
+	}
+
+	// 1. SETUP: solo un seed pubblico, niente chiavi segrete
+	key, err := latcommit.NewKey([]byte(*seed))
+	if err != nil {
+		panic(err)
+	}
+
+	// 2. FORNITORE: impegna e prova la somma
+	start := time.Now()
+	com, opening, err := key.Commit(values)
+	if err != nil {
+		fmt.Printf("Errore: %v\n", err)
+		os.Exit(1)
+	}
+	proof, err := key.ProveSum(com, opening, sum)
+	if err != nil {
+		panic(err)
+	}
+	comBytes, proofBytes := key.MarshalCommitment(com), key.MarshalProof(proof)
+	fmt.Printf("impegno (%d KiB) e prova (%d KiB) in %v\n", len(comBytes)/1024, len(proofBytes)/1024, time.Since(start))
+
+	// 3. VERIFIER: legge i byte e controlla la somma
+	start = time.Now()
+	com2, err := key.UnmarshalCommitment(comBytes)
+	if err != nil {
+		panic(err)
+	}
+	proof2, err := key.UnmarshalProof(proofBytes)
+	if err != nil {
+		panic(err)
+	}
+	if err := key.VerifySum(com2, sum, proof2); err != nil {
+		fmt.Printf("Errore: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Somma verificata: %d su %d valori in %v\n", sum, len(values), time.Since(start))
+
+	// 4. casi che devono fallire
+	failed := false
+	expect := func(what string, err error) {
+		if err == nil {
+			fmt.Printf("ERRORE: %s accettato\n", what)
+			failed = true
+			return
+		}
+		fmt.Printf("%s rifiutato: %v\n", what, err)
+	}
+
+	expect("somma +1", key.VerifySum(com2, sum+1, proof2))
+
+	_, err = key.ProveSum(com, opening, sum+1)
+	expect("prova di una somma falsa", err)
+
+	// stessa somma, valori diversi: la prova è legata al suo impegno
+	other := append([]int64(nil), values...)
+	other[0]++
+	other[1]--
+	otherCom, _, err := key.Commit(other)
+	if err != nil {
+		panic(err)
+	}
+	expect("prova su un altro impegno", key.VerifySum(otherCom, sum, proof2))
+
+	flipped := append([]byte(nil), proofBytes...)
+	flipped[len(flipped)-3] ^= 1
+	p, err := key.UnmarshalProof(flipped)
+	if err == nil {
+		err = key.VerifySum(com2, sum, p)
+	}
+	expect("un bit della prova cambiato", err)
+
+	_, _, err = key.Commit([]int64{1 << latcommit.ValueBits})
+	expect(fmt.Sprintf("valore oltre 2^%d", latcommit.ValueBits), err)
+
+	if failed {
+		os.Exit(1)
+	}
+}
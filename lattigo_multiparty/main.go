@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bytes"
+	"encoding"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/dbfv"
+	"github.com/tuneinsight/lattigo/v4/drlwe"
+	"github.com/tuneinsight/lattigo/v4/rlwe"
+	"github.com/tuneinsight/lattigo/v4/utils"
+)
+
+// Somma BFV multiparty con lattigo dbfv/drlwe: nessuno ha la chiave segreta.
+// Ogni parte genera la propria s_i e ne distribuisce le share di Shamir; la
+// chiave pubblica e le chiavi di rotazione sono generate collettivamente;
+// ogni parte cifra i propri KPI, il cloud somma i ciphertext e basta una
+// soglia di t parti su N per decifrare, e solo il ciphertext aggregato.
+//
+// Le parti sono goroutine che si scambiano messaggi serializzati su un
+// trasporto in memoria, come farebbero in rete.
+//
+//	go run ./lattigo_multiparty -parties 5 -threshold 3
+
+// ValuesPerParty sono i valori KPI di ogni parte.
+const ValuesPerParty = 10
+
+// smudging è un segnaposto, non un rumore di flooding: le share di
+// decifratura non nascondono statisticamente la s_i di chi le produce.
+// lattigo v4 campiona l'errore su Q con deviazione smudging, lo estende a P e
+// divide per P: sulla share resta circa smudging/P, meno di 1 con P ≈ 2^30,
+// e già con 1 << 28 (6σ oltre P/2) la decifratura ogni tanto esce sbagliata.
+// Un flooding vero vorrebbe 2^λ volte il rumore del ciphertext aggregato: con
+// λ = 40 e il rumore misurato dopo somma e innerSum (circa 2^27, 34 bit di
+// budget su 61) sono 2^67, oltre il Q/2t ≈ 2^61 di PN12QP109.
+const smudging = 1 << 24
+
+// crsSeed è il seed pubblico da cui tutti ricavano i polinomi comuni.
+var crsSeed = []byte("kpi-multiparty-crs")
+
+// --- trasporto in memoria ---
+
+type message struct {
+	From    int
+	Kind    string
+	Payload []byte
+}
+
+type transport struct {
+	inbox []chan message
+	mu    sync.Mutex
+	bytes int
+}
+
+func newTransport(nodes int) *transport {
+	t := &transport{inbox: make([]chan message, nodes)}
+	for i := range t.inbox {
+		t.inbox[i] = make(chan message, 4*nodes)
+	}
+	return t
+}
+
+// send serializza v e lo consegna a to.
+func (t *transport) send(from, to int, kind string, v encoding.BinaryMarshaler) {
+	payload, err := v.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	t.mu.Lock()
+	t.bytes += len(payload)
+	t.mu.Unlock()
+	t.inbox[to] <- message{From: from, Kind: kind, Payload: payload}
+}
+
+// raw è un payload già serializzato.
+type raw []byte
+
+func (r raw) MarshalBinary() ([]byte, error) { return r, nil }
+
+// mailbox legge la inbox di un nodo tenendo da parte i messaggi che arrivano
+// prima della fase in cui servono.
+type mailbox struct {
+	in      chan message
+	pending []message
+}
+
+func (m *mailbox) recv(kinds ...string) message {
+	for i, msg := range m.pending {
+		if contains(kinds, msg.Kind) {
+			m.pending = append(m.pending[:i], m.pending[i+1:]...)
+			return msg
+		}
+	}
+	for msg := range m.in {
+		if contains(kinds, msg.Kind) {
+			return msg
+		}
+		m.pending = append(m.pending, msg)
+	}
+	panic("trasporto chiuso")
+}
+
+func contains(kinds []string, kind string) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func unmarshal(msg message, v encoding.BinaryUnmarshaler) {
+	if err := v.UnmarshalBinary(msg.Payload); err != nil {
+		panic(fmt.Sprintf("messaggio %s da %d: %v", msg.Kind, msg.From, err))
+	}
+}
+
+// --- parti ---
+
+// shamirPoint è il punto pubblico di Shamir della parte i (0 non è ammesso).
+func shamirPoint(i int) drlwe.ShamirPublicPoint {
+	return drlwe.ShamirPublicPoint(i + 1)
+}
+
+// partyValues sono i KPI della parte p: p·10+1, ..., p·10+10.
+func partyValues(p int) []uint64 {
+	values := make([]uint64, ValuesPerParty)
+	for j := range values {
+		values[j] = uint64(p*ValuesPerParty + j + 1)
+	}
+	return values
+}
+
+// party esegue il protocollo della parte id; cloud è l'indice del server.
+func party(params bfv.Parameters, net *transport, id, nbParties, threshold, cloud int) {
+	box := &mailbox{in: net.inbox[id]}
+	sk := bfv.NewKeyGenerator(params).GenSecretKey()
+
+	// 1. share di Shamir della propria s_i, una per ogni altra parte
+	thr := drlwe.NewThresholdizer(params.Parameters)
+	poly, err := thr.GenShamirPolynomial(threshold, sk)
+	if err != nil {
+		panic(err)
+	}
+	tsk := thr.AllocateThresholdSecretShare()
+	thr.GenShamirSecretShare(shamirPoint(id), poly, tsk)
+	for j := 0; j < nbParties; j++ {
+		if j != id {
+			share := thr.AllocateThresholdSecretShare()
+			thr.GenShamirSecretShare(shamirPoint(j), poly, share)
+			net.send(id, j, "shamir", share)
+		}
+	}
+	for j := 1; j < nbParties; j++ {
+		share := thr.AllocateThresholdSecretShare()
+		unmarshal(box.recv("shamir"), share)
+		thr.AggregateShares(tsk, share, tsk)
+	}
+
+	// 2. chiave pubblica e chiavi di rotazione collettive
+	crs, err := utils.NewKeyedPRNG(crsSeed)
+	if err != nil {
+		panic(err)
+	}
+	ckg := dbfv.NewCKGProtocol(params)
+	ckgShare := ckg.AllocateShare()
+	ckg.GenShare(sk, ckg.SampleCRP(crs), ckgShare)
+	net.send(id, cloud, "ckg", ckgShare)
+
+	rtg := dbfv.NewRTGProtocol(params)
+	for _, galEl := range innerSumGaloisElements(params) {
+		rtgShare := rtg.AllocateShare()
+		rtg.GenShare(sk, galEl, rtg.SampleCRP(crs), rtgShare)
+		net.send(id, cloud, "rtg", rtgShare)
+	}
+
+	pk := rlwe.NewPublicKey(params.Parameters)
+	unmarshal(box.recv("pk"), pk)
+	rtks := new(rlwe.RotationKeySet)
+	unmarshal(box.recv("rtks"), rtks)
+
+	// 3. cifratura dei propri KPI con la chiave collettiva
+	encoder := bfv.NewEncoder(params)
+	slots := make([]uint64, params.N())
+	copy(slots[id*ValuesPerParty:], partyValues(id))
+	pt := bfv.NewPlaintext(params, params.MaxLevel())
+	encoder.Encode(slots, pt)
+	net.send(id, cloud, "ct", bfv.NewEncryptor(params, pk).EncryptNew(pt))
+
+	// la bacheca: tutti i ciphertext pubblicati, per ricalcolare l'aggregato
+	cts := make([]*rlwe.Ciphertext, nbParties)
+	for range cts {
+		msg := box.recv("board")
+		ct := new(rlwe.Ciphertext)
+		unmarshal(msg, ct)
+		cts[msg.From] = ct
+	}
+	evaluator := bfv.NewEvaluator(params, rlwe.EvaluationKey{Rtks: rtks})
+	aggregated, err := aggregate(params, evaluator, cts).MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+
+	// 4. decifratura a soglia: solo del ciphertext aggregato
+	cks := dbfv.NewCKSProtocol(params, smudging)
+	zero := rlwe.NewSecretKey(params.Parameters)
+	for {
+		msg := box.recv("decrypt", "done")
+		if msg.Kind == "done" {
+			return
+		}
+		if !bytes.Equal(msg.Payload, aggregated) {
+			net.send(id, cloud, "refuse", raw(nil))
+			continue
+		}
+		ct := new(rlwe.Ciphertext)
+		unmarshal(msg, ct)
+
+		// le parti attive arrivano subito dopo, un byte per punto di Shamir
+		active := decodeActive(box.recv("active").Payload)
+		others := make([]drlwe.ShamirPublicPoint, nbParties)
+		for j := range others {
+			others[j] = shamirPoint(j)
+		}
+		combiner := drlwe.NewCombiner(params.Parameters, shamirPoint(id), others, threshold)
+		additive := rlwe.NewSecretKey(params.Parameters)
+		combiner.GenAdditiveShare(active, shamirPoint(id), tsk, additive)
+
+		share := cks.AllocateShare(ct.Level())
+		cks.GenShare(additive, zero, ct, share)
+		net.send(id, cloud, "cks", share)
+	}
+}
+
+func encodeActive(active []drlwe.ShamirPublicPoint) raw {
+	b := make(raw, len(active))
+	for i, p := range active {
+		b[i] = byte(p)
+	}
+	return b
+}
+
+func decodeActive(b []byte) []drlwe.ShamirPublicPoint {
+	active := make([]drlwe.ShamirPublicPoint, len(b))
+	for i := range b {
+		active[i] = drlwe.ShamirPublicPoint(b[i])
+	}
+	return active
+}
+
+// --- cloud ---
+
+// cloudNode raccoglie le share, genera le chiavi collettive, somma i
+// ciphertext e chiede la decifratura alle parti attive. Restituisce la somma
+// decifrata.
+func cloudNode(params bfv.Parameters, net *transport, nbParties, threshold int) (uint64, error) {
+	id := nbParties
+	box := &mailbox{in: net.inbox[id]}
+	broadcast := func(kind string, v encoding.BinaryMarshaler) {
+		for j := 0; j < nbParties; j++ {
+			net.send(id, j, kind, v)
+		}
+	}
+
+	crs, err := utils.NewKeyedPRNG(crsSeed)
+	if err != nil {
+		return 0, err
+	}
+
+	// chiave pubblica: somma delle share CKG
+	ckg := dbfv.NewCKGProtocol(params)
+	ckgCRP := ckg.SampleCRP(crs)
+	ckgAgg := ckg.AllocateShare()
+	for j := 0; j < nbParties; j++ {
+		share := ckg.AllocateShare()
+		unmarshal(box.recv("ckg"), share)
+		ckg.AggregateShares(ckgAgg, share, ckgAgg)
+	}
+	pk := rlwe.NewPublicKey(params.Parameters)
+	ckg.GenPublicKey(ckgAgg, ckgCRP, pk)
+
+	// chiavi di rotazione: le share arrivano nello stesso ordine dei galEl da ogni parte
+	galEls := innerSumGaloisElements(params)
+	rtg := dbfv.NewRTGProtocol(params)
+	rtgCRP := make([]drlwe.RTGCRP, len(galEls))
+	rtgAgg := make([]*drlwe.RTGShare, len(galEls))
+	for g := range galEls {
+		rtgCRP[g] = rtg.SampleCRP(crs)
+		rtgAgg[g] = rtg.AllocateShare()
+	}
+	next := make([]int, nbParties)
+	for n := 0; n < nbParties*len(galEls); n++ {
+		msg := box.recv("rtg")
+		share := rtg.AllocateShare()
+		unmarshal(msg, share)
+		g := next[msg.From]
+		next[msg.From]++
+		rtg.AggregateShares(rtgAgg[g], share, rtgAgg[g])
+	}
+	rtks := rlwe.NewRotationKeySet(params.Parameters, galEls)
+	for g, galEl := range galEls {
+		rtg.GenRotationKey(rtgAgg[g], rtgCRP[g], rtks.Keys[galEl])
+	}
+	broadcast("pk", pk)
+	broadcast("rtks", rtks)
+	fmt.Printf("[Cloud] Chiave pubblica e %d chiavi di rotazione collettive da %d parti\n", len(galEls), nbParties)
+
+	// ciphertext delle parti, ripubblicati sulla bacheca
+	cts := make([]*rlwe.Ciphertext, nbParties)
+	for range cts {
+		msg := box.recv("ct")
+		ct := new(rlwe.Ciphertext)
+		unmarshal(msg, ct)
+		cts[msg.From] = ct
+		// inoltrato con il mittente originale
+		for j := 0; j < nbParties; j++ {
+			net.send(msg.From, j, "board", ct)
+		}
+	}
+	evaluator := bfv.NewEvaluator(params, rlwe.EvaluationKey{Rtks: rtks})
+	result := aggregate(params, evaluator, cts)
+	fmt.Printf("[Cloud] Sommati %d ciphertext, somma interna completata\n", nbParties)
+
+	// le prime threshold parti decifrano; prima una richiesta su un
+	// ciphertext individuale, che deve essere rifiutata
+	active := make([]drlwe.ShamirPublicPoint, threshold)
+	for j := range active {
+		active[j] = shamirPoint(j)
+	}
+	for j := 0; j < threshold; j++ {
+		net.send(id, j, "decrypt", cts[0])
+	}
+	for j := 0; j < threshold; j++ {
+		if msg := box.recv("refuse", "cks"); msg.Kind != "refuse" {
+			return 0, fmt.Errorf("la parte %d ha decifrato un ciphertext individuale", msg.From)
+		}
+	}
+	fmt.Printf("[Cloud] ✓ Decifratura del ciphertext della parte 0 rifiutata da tutte le %d parti attive\n", threshold)
+
+	cks := dbfv.NewCKSProtocol(params, smudging)
+	cksAgg := cks.AllocateShare(result.Level())
+	for j := 0; j < threshold; j++ {
+		net.send(id, j, "decrypt", result)
+		net.send(id, j, "active", encodeActive(active))
+	}
+	for j := 0; j < threshold; j++ {
+		msg := box.recv("refuse", "cks")
+		if msg.Kind == "refuse" {
+			return 0, fmt.Errorf("la parte %d ha rifiutato il ciphertext aggregato", msg.From)
+		}
+		share := cks.AllocateShare(result.Level())
+		unmarshal(msg, share)
+		cks.AggregateShares(cksAgg, share, cksAgg)
+	}
+	broadcast("done", raw(nil))
+
+	// dopo il key switch verso la chiave nulla il ciphertext si decifra con s = 0
+	plain := bfv.NewCiphertext(params, 1, result.Level())
+	cks.KeySwitch(result, cksAgg, plain)
+	res := make([]uint64, params.N())
+	bfv.NewEncoder(params).Decode(bfv.NewDecryptor(params, rlwe.NewSecretKey(params.Parameters)).DecryptNew(plain), res)
+	fmt.Printf("[Cloud] Decifratura a soglia con %d parti su %d (punti di Shamir %v)\n", threshold, nbParties, active)
+	fmt.Println("[Cloud] Attenzione: smudging segnaposto, le share di decifratura non nascondono le s_i")
+	return res[0], nil
+}
+
+// aggregate somma i ciphertext delle parti e poi tutti gli slot; è
+// deterministica, così ogni parte può ricalcolarla e confrontarla.
+func aggregate(params bfv.Parameters, evaluator bfv.Evaluator, cts []*rlwe.Ciphertext) *rlwe.Ciphertext {
+	res := cts[0].CopyNew()
+	for _, ct := range cts[1:] {
+		evaluator.Add(res, ct, res)
+	}
+	innerSum(params, evaluator, res)
+	return res
+}
+
+// innerSumGaloisElements e innerSum come in lattigo/: rotazioni di colonna
+// per 1, 2, ..., N/4 e scambio delle righe.
+func innerSumGaloisElements(params bfv.Parameters) []uint64 {
+	var galEls []uint64
+	for k := 1; k < params.N()/2; k <<= 1 {
+		galEls = append(galEls, params.GaloisElementForColumnRotationBy(k))
+	}
+	return append(galEls, params.GaloisElementForRowRotation())
+}
+
+func innerSum(params bfv.Parameters, evaluator bfv.Evaluator, ct *rlwe.Ciphertext) {
+	for k := 1; k < params.N()/2; k <<= 1 {
+		rotated := evaluator.RotateColumnsNew(ct, k)
+		evaluator.Add(ct, rotated, ct)
+	}
+	rotated := evaluator.RotateRowsNew(ct)
+	evaluator.Add(ct, rotated, ct)
+}
+
+func main() {
+	nbParties := flag.Int("parties", 5, "numero di parti")
+	threshold := flag.Int("threshold", 3, "parti necessarie per decifrare")
+	flag.Parse()
+	if *threshold < 1 || *threshold > *nbParties || *nbParties > 200 {
+		fmt.Println("servono 1 ≤ threshold ≤ parties ≤ 200")
+		os.Exit(1)
+	}
+
+	params, err := bfv.NewParametersFromLiteral(bfv.PN12QP109)
+	if err != nil {
+		panic(err)
+	}
+	if *nbParties*ValuesPerParty > params.N() {
+		fmt.Printf("troppe parti: %d valori per %d slot\n", *nbParties*ValuesPerParty, params.N())
+		os.Exit(1)
+	}
+
+	// nodi 0..parties-1 le parti, parties il cloud
+	net := newTransport(*nbParties + 1)
+	var wg sync.WaitGroup
+	for p := 0; p < *nbParties; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			party(params, net, p, *nbParties, *threshold, *nbParties)
+		}(p)
+	}
+	sum, err := cloudNode(params, net, *nbParties, *threshold)
+	if err != nil {
+		fmt.Printf("Errore: %v\n", err)
+		os.Exit(1)
+	}
+	wg.Wait()
+
+	var expected uint64
+	for p := 0; p < *nbParties; p++ {
+		for _, v := range partyValues(p) {
+			expected += v
+		}
+	}
+	fmt.Printf("[Trasporto] %.1f MB scambiati\n", float64(net.bytes)/(1<<20))
+	fmt.Printf("\n[Risultato Finale] Sommatoria KPI Decifrata: %d (Attesa: %d)\n", sum, expected%params.T())
+	if sum != expected%params.T() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"zk-test/bfvplan"
+)
+
+// Sceglie i parametri BFV per una pipeline KPI e stampa il budget di rumore
+// misurato dopo ogni passo; esce con codice 1 se nessun preset va bene.
+//
+//	go run ./lattigo_plan -bits 16 -count 1000 -depth 1
+
+func main() {
+	var req bfvplan.Requirements
+	flag.IntVar(&req.ValueBits, "bits", 7, "bit di ciascun valore KPI")
+	flag.IntVar(&req.Count, "count", 100, "valori sommati")
+	flag.IntVar(&req.Depth, "depth", 0, "moltiplicazioni in sequenza prima della somma")
+	flag.BoolVar(&req.InnerSum, "innersum", true, "somma sugli slot con rotazioni (rumore ×N)")
+	flag.BoolVar(&req.Compare, "compare", false, "confronto cifrato del totale con una soglia (bfvcmp)")
+	flag.Parse()
+
+	plan, err := bfvplan.Choose(req)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println(plan)
+}
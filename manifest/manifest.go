@@ -0,0 +1,192 @@
+// Package manifest descrive i segnali pubblici di un circuito: per ogni indice
+// del public witness il nome del campo gnark, il tipo e la scala fixed-point.
+//
+// L'ordine dei segnali pubblici è quello di dichiarazione dei campi nella
+// struct del circuito (gli array vengono appiattiti), non l'ordine alfabetico:
+// il manifest viene ricavato dallo schema di gnark, così non va mantenuto a mano.
+//
+// Tipo e scala si dichiarano con il tag kpi sul campo, ad esempio
+//
+//	ExpectedSum frontend.Variable `gnark:",public" kpi:"sum,scale=1000"`
+//
+// Senza tag il tipo è "field" e la scala 1.
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/schema"
+)
+
+// File è il nome del manifest scritto accanto a public.json.
+const File = "public_manifest.json"
+
+// Signal è una voce del manifest.
+type Signal struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`  // nome completo gnark, es. Hashes_3
+	Field string `json:"field"` // campo della struct, es. Hashes
+	Type  string `json:"type"`  // field, sum, root, hash, ...
+	Scale int64  `json:"scale"` // fattore fixed-point, 1 se il valore è intero
+}
+
+// Manifest elenca i segnali pubblici nell'ordine del public witness.
+type Manifest struct {
+	Circuit string   `json:"circuit"` // tipo Go del circuito, es. kpimerkle.Circuit
+	Signals []Signal `json:"signals"`
+}
+
+var tVariable = reflect.TypeOf((*frontend.Variable)(nil)).Elem()
+
+// New ricava il manifest dallo schema del circuito.
+func New(circuit frontend.Circuit) (*Manifest, error) {
+	t := reflect.TypeOf(circuit)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("circuito %T non è una struct", circuit)
+	}
+
+	m := &Manifest{Circuit: t.String()}
+	_, err := schema.Walk(ecc.BN254.ScalarField(), circuit, tVariable, func(leaf schema.LeafInfo, _ reflect.Value) error {
+		if leaf.Visibility != schema.Public {
+			return nil
+		}
+		name := leaf.FullName()
+		field, _, _ := strings.Cut(name, "_")
+		s := Signal{Index: len(m.Signals), Name: name, Field: field, Type: "field", Scale: 1}
+		if sf, ok := t.FieldByName(field); ok {
+			if err := parseTag(sf.Tag.Get("kpi"), &s); err != nil {
+				return fmt.Errorf("campo %s: %w", field, err)
+			}
+		}
+		m.Signals = append(m.Signals, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseTag legge "tipo,scale=N".
+func parseTag(tag string, s *Signal) error {
+	if tag == "" {
+		return nil
+	}
+	for i, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if v, ok := strings.CutPrefix(part, "scale="); ok {
+			scale, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || scale <= 0 {
+				return fmt.Errorf("scala non valida %q", v)
+			}
+			s.Scale = scale
+			continue
+		}
+		if i == 0 && part != "" {
+			s.Type = part
+			continue
+		}
+		return fmt.Errorf("opzione kpi sconosciuta %q", part)
+	}
+	return nil
+}
+
+// Index restituisce la posizione del segnale con nome completo name.
+func (m *Manifest) Index(name string) (int, error) {
+	for _, s := range m.Signals {
+		if s.Name == name {
+			return s.Index, nil
+		}
+	}
+	return 0, fmt.Errorf("segnale pubblico %q non presente nel manifest", name)
+}
+
+// Summary descrive i valori pubblici per i log: i campi scalari con il loro
+// valore, gli array solo con la dimensione.
+func (m *Manifest) Summary(values []string) string {
+	var parts []string
+	for i := 0; i < len(m.Signals); {
+		s := m.Signals[i]
+		j := i + 1
+		for j < len(m.Signals) && m.Signals[j].Field == s.Field {
+			j++
+		}
+		switch {
+		case j-i > 1:
+			parts = append(parts, fmt.Sprintf("%s[%d]", s.Field, j-i))
+		case s.Index < len(values):
+			parts = append(parts, fmt.Sprintf("%s=%s", s.Name, values[s.Index]))
+		}
+		i = j
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Check confronta il manifest letto da file con quello del circuito e con il
+// numero di segnali di public.json.
+func (m *Manifest) Check(circuit frontend.Circuit, nbPublic int) error {
+	expected, err := New(circuit)
+	if err != nil {
+		return err
+	}
+	if m.Circuit != expected.Circuit {
+		return fmt.Errorf("manifest del circuito %s, atteso %s", m.Circuit, expected.Circuit)
+	}
+	if len(m.Signals) != len(expected.Signals) {
+		return fmt.Errorf("manifest con %d segnali, il circuito ne ha %d", len(m.Signals), len(expected.Signals))
+	}
+	if nbPublic != len(m.Signals) {
+		return fmt.Errorf("public.json ha %d segnali, il manifest %d", nbPublic, len(m.Signals))
+	}
+	for i, s := range m.Signals {
+		if s != expected.Signals[i] {
+			return fmt.Errorf("segnale %d: manifest %+v, circuito %+v", i, s, expected.Signals[i])
+		}
+	}
+	return nil
+}
+
+// Write scrive il manifest in dir/public_manifest.json.
+func (m *Manifest) Write(dir string) error {
+	f, err := os.Create(filepath.Join(dir, File))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read legge dir/public_manifest.json.
+func Read(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, File))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", File, err)
+	}
+	for i, s := range m.Signals {
+		if s.Index != i {
+			return nil, errors.New("manifest con indici non in ordine")
+		}
+	}
+	return &m, nil
+}
@@ -0,0 +1,88 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+type kpiCircuit struct {
+	Root        frontend.Variable    `gnark:",public" kpi:"root"`
+	ExpectedSum frontend.Variable    `gnark:",public" kpi:"sum,scale=1000"`
+	Hashes      [2]frontend.Variable `gnark:",public" kpi:"hash"`
+	Values      [2]frontend.Variable `gnark:",secret"`
+}
+
+func (c *kpiCircuit) Define(api frontend.API) error { return nil }
+
+type otherCircuit kpiCircuit
+
+func (c *otherCircuit) Define(api frontend.API) error { return nil }
+
+func TestNew(t *testing.T) {
+	m, err := New(&kpiCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Circuit != "manifest.kpiCircuit" {
+		t.Fatalf("circuito %q", m.Circuit)
+	}
+	want := []Signal{
+		{0, "Root", "Root", "root", 1},
+		{1, "ExpectedSum", "ExpectedSum", "sum", 1000},
+		{2, "Hashes_0", "Hashes", "hash", 1},
+		{3, "Hashes_1", "Hashes", "hash", 1},
+	}
+	if len(m.Signals) != len(want) {
+		t.Fatalf("%d segnali, attesi %d", len(m.Signals), len(want))
+	}
+	for i := range want {
+		if m.Signals[i] != want[i] {
+			t.Fatalf("segnale %d: %+v, atteso %+v", i, m.Signals[i], want[i])
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		change func(m *Manifest)
+		nb     int
+		err    string
+	}{
+		{"ok", func(m *Manifest) {}, 4, ""},
+		{"ordine scambiato", func(m *Manifest) {
+			m.Signals[0], m.Signals[1] = m.Signals[1], m.Signals[0]
+			m.Signals[0].Index, m.Signals[1].Index = 0, 1
+		}, 4, "segnale 0"},
+		{"nome", func(m *Manifest) { m.Signals[2].Name = "Hashes_9" }, 4, "segnale 2"},
+		{"scala", func(m *Manifest) { m.Signals[1].Scale = 100 }, 4, "segnale 1"},
+		{"circuito", func(m *Manifest) { m.Circuit = "manifest.otherCircuit" }, 4, "circuito"},
+		{"public.json", func(m *Manifest) {}, 3, "public.json"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := New(&kpiCircuit{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.change(m)
+			err = m.Check(&kpiCircuit{}, tc.nb)
+			switch {
+			case tc.err == "" && err != nil:
+				t.Fatal(err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Fatalf("errore %v, atteso %q", err, tc.err)
+			}
+		})
+	}
+
+	// stesso schema, tipo diverso
+	m, err := New(&otherCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Check(&kpiCircuit{}, 4) == nil {
+		t.Fatal("manifest di un altro circuito accettato")
+	}
+}
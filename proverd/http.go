@@ -0,0 +1,143 @@
+package proverd
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"zk-test/kpiinput"
+)
+
+// maxBody limita il JSON di un dataset: 128 valori stanno in pochi KiB.
+const maxBody = 1 << 20
+
+// Handler espone l'API:
+//
+//	POST   /jobs             {"values": [...]} → 202 con il job in coda, 503 se la coda è piena
+//	GET    /jobs             elenco dei job
+//	GET    /jobs/{id}        stato del job
+//	GET    /jobs/{id}/bundle zip con prova, public witness, public.json, manifest,
+//	                         verifying.key e job.json; 409 se la prova non è pronta
+//	DELETE /jobs/{id}        cancella un job in coda o in corso, 409 se è già terminato
+func (s *Service) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.submit)
+	mux.HandleFunc("GET /jobs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.List())
+	})
+	mux.HandleFunc("GET /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		job, err := s.Get(r.PathValue("id"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	})
+	mux.HandleFunc("DELETE /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		job, err := s.Cancel(r.PathValue("id"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	})
+	mux.HandleFunc("GET /jobs/{id}/bundle", s.bundle)
+	return mux
+}
+
+func (s *Service) submit(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+	if err != nil {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": err.Error()})
+		return
+	}
+	data, err := kpiinput.Parse(body)
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: %v", ErrInput, err))
+		return
+	}
+	job, err := s.Submit(data)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// bundle scrive lo zip del job; la verifying key è quella salvata con la
+// prova, non quella con cui il servizio gira ora.
+func (s *Service) bundle(w http.ResponseWriter, r *http.Request) {
+	job, err := s.Get(r.PathValue("id"))
+	if err == nil && job.Status != Done {
+		err = ErrNotDone
+	}
+	if err == nil {
+		// prima degli header: dopo, un file mancante darebbe uno zip troncato
+		for _, name := range bundle {
+			if _, err = os.Stat(filepath.Join(s.jobDir(job.ID), name)); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="kpi-%s.zip"`, job.ID))
+	z := zip.NewWriter(w)
+	for _, name := range append([]string{jobFile}, bundle...) {
+		if err := addFile(z, filepath.Join(s.jobDir(job.ID), name), name); err != nil {
+			// header già inviati: lo zip troncato non si apre
+			fmt.Printf("proverd: bundle %s: %v\n", job.ID, err)
+			return
+		}
+	}
+	if err := z.Close(); err != nil {
+		fmt.Printf("proverd: bundle %s: %v\n", job.ID, err)
+	}
+}
+
+func addFile(z *zip.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dst, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, f)
+	return err
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrInput):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrQueueFull):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, ErrFinished), errors.Is(err, ErrNotDone):
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,454 @@
+// Package proverd è il servizio di prova per il circuito kpimerkle: riceve i
+// dataset KPI via HTTP/JSON, li mette in coda e li prova con un numero fisso
+// di worker che condividono lo stesso zkbackend.System (ccs e chiavi caricati
+// una volta sola, da -keys).
+//
+// Ogni job ha una cartella in Dir con job.json (stato), input.json e, a prova
+// finita, i file del bundle con la verifying key usata. job.json registra
+// l'impronta delle chiavi (zkbackend.Fingerprint: curva, backend, hash e
+// constraint system): al riavvio i job in coda o interrotti durante la prova
+// tornano in coda se l'impronta è quella del servizio, altrimenti falliscono.
+//
+// La prova di gnark non si può interrompere: cancellare un job in corso lo
+// segna subito come cancelled e il worker ne scarta il risultato quando
+// Prove ritorna, prima di passare al successivo.
+package proverd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+
+	"zk-test/kpiinput"
+	"zk-test/kpimerkle"
+	"zk-test/manifest"
+	"zk-test/zkbackend"
+)
+
+// Status è lo stato di un job.
+type Status string
+
+const (
+	Queued    Status = "queued"
+	Running   Status = "running"
+	Done      Status = "done"
+	Failed    Status = "failed"
+	Cancelled Status = "cancelled"
+)
+
+// finished dice se il job non cambierà più stato.
+func (s Status) finished() bool {
+	return s == Done || s == Failed || s == Cancelled
+}
+
+// Job è lo stato di un job, salvato in job.json e restituito dall'API.
+type Job struct {
+	ID          string    `json:"id"`
+	Status      Status    `json:"status"`
+	Values      int       `json:"values"`        // valori nel dataset
+	Sum         *int64    `json:"sum,omitempty"` // somma ×1000, a prova finita
+	Root        string    `json:"root,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Fingerprint string    `json:"fingerprint"` // impronta delle chiavi con cui il job va provato
+	Created     time.Time `json:"created"`
+	Started     time.Time `json:"started,omitzero"`
+	Finished    time.Time `json:"finished,omitzero"`
+}
+
+const (
+	jobFile    = "job.json"
+	inputFile  = "input.json"
+	proofFile  = "proof.gnark"
+	publicFile = "public_witness.gnark"
+	vkFile     = "verifying.key"
+	signals    = "public.json"
+)
+
+// Circuit è il circuito provato dal servizio: Merkle in produzione, uno più
+// piccolo nei test.
+type Circuit interface {
+	// Schema è il circuito vuoto compilato per le chiavi.
+	Schema() frontend.Circuit
+	// MaxValues è il numero massimo di valori di un dataset.
+	MaxValues() int
+	// Assign restituisce l'assignment di values sul campo di curve, la somma
+	// ×1000 e i valori pubblici nell'ordine del public witness.
+	Assign(curve ecc.ID, values []float64) (frontend.Circuit, int64, []*big.Int, error)
+}
+
+// Merkle è kpimerkle.Circuit con l'hash hashKind.
+func Merkle(hashKind string) Circuit {
+	return merkle(hashKind)
+}
+
+type merkle string
+
+func (m merkle) Schema() frontend.Circuit { return &kpimerkle.Circuit{Hash: string(m)} }
+
+func (m merkle) MaxValues() int { return kpimerkle.MaxValues }
+
+func (m merkle) Assign(curve ecc.ID, values []float64) (frontend.Circuit, int64, []*big.Int, error) {
+	assignment, sum, err := kpimerkle.Assign(curve, string(m), values)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	// Root, ExpectedSum come nel manifest
+	expected := new(big.Int).Mod(big.NewInt(sum), curve.ScalarField())
+	return assignment, sum, []*big.Int{assignment.Root.(*big.Int), expected}, nil
+}
+
+var (
+	ErrInput     = errors.New("dataset non valido")
+	ErrNotFound  = errors.New("job inesistente")
+	ErrQueueFull = errors.New("coda piena")
+	ErrFinished  = errors.New("job già terminato")
+	ErrNotDone   = errors.New("prova non ancora pronta")
+)
+
+// Service è la coda dei job con i suoi worker.
+type Service struct {
+	sys         *zkbackend.System
+	circuit     Circuit
+	fingerprint string
+	dir         string
+	limit       int
+
+	mu      sync.Mutex
+	wake    *sync.Cond
+	jobs    map[string]*Job
+	pending []string // id in coda, in ordine di arrivo
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// New apre (o crea) dir e ricarica i job salvati. sys deve essere il sistema
+// di circuit.Schema(); limit è il numero massimo di job in coda, oltre il
+// quale Submit restituisce ErrQueueFull.
+func New(sys *zkbackend.System, circuit Circuit, dir string, limit int) (*Service, error) {
+	fp, err := zkbackend.Fingerprint(sys.Backend, circuit.Schema(), sys.CCS)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Service{sys: sys, circuit: circuit, fingerprint: fp, dir: dir, limit: limit, jobs: map[string]*Job{}}
+	s.wake = sync.NewCond(&s.mu)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name(), jobFile))
+		if err != nil {
+			continue // cartella rimasta a metà di una Submit
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil || job.ID != e.Name() {
+			return nil, fmt.Errorf("proverd: %s/%s non valido", e.Name(), jobFile)
+		}
+		s.jobs[job.ID] = &job
+	}
+
+	// i job interrotti tornano in coda, più vecchi prima; la coda ricaricata
+	// può superare limit. Un job inviato con altre chiavi (altra curva,
+	// backend o hash) non si prova con queste: fallisce.
+	var resume []*Job
+	for _, job := range s.jobs {
+		if !job.Status.finished() {
+			resume = append(resume, job)
+		}
+	}
+	sort.Slice(resume, func(i, j int) bool { return resume[i].Created.Before(resume[j].Created) })
+	for _, job := range resume {
+		if job.Fingerprint != fp {
+			job.Status, job.Finished = Failed, time.Now().UTC()
+			job.Error = "job inviato con chiavi diverse da quelle del servizio (curva, backend o hash)"
+		} else {
+			job.Status, job.Started = Queued, time.Time{}
+			s.pending = append(s.pending, job.ID)
+		}
+		if err := s.save(job); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Start avvia workers worker.
+func (s *Service) Start(workers int) {
+	for range workers {
+		s.wg.Add(1)
+		go s.worker()
+	}
+}
+
+// Close smette di prendere job dalla coda e aspetta le prove in corso. I job
+// ancora in coda restano salvati e ripartono al prossimo New.
+func (s *Service) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.wake.Broadcast()
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Submit salva il dataset e lo mette in coda.
+func (s *Service) Submit(data *kpiinput.Data) (Job, error) {
+	if n := s.circuit.MaxValues(); len(data.Values) > n {
+		return Job{}, fmt.Errorf("%w: %d valori, il circuito ne accetta al massimo %d", ErrInput, len(data.Values), n)
+	}
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return Job{}, errors.New("servizio in chiusura")
+	}
+	if len(s.pending) >= s.limit {
+		return Job{}, ErrQueueFull
+	}
+
+	job := &Job{ID: id, Status: Queued, Values: len(data.Values), Fingerprint: s.fingerprint, Created: time.Now().UTC()}
+	if err := os.Mkdir(s.jobDir(id), 0o755); err != nil {
+		return Job{}, err
+	}
+	input, err := json.Marshal(kpiinput.Data{Values: data.Values})
+	if err != nil {
+		return Job{}, err
+	}
+	if err := os.WriteFile(filepath.Join(s.jobDir(id), inputFile), input, 0o644); err != nil {
+		return Job{}, err
+	}
+	if err := s.save(job); err != nil {
+		return Job{}, err
+	}
+	s.jobs[id] = job
+	s.pending = append(s.pending, id)
+	s.wake.Signal()
+	return *job, nil
+}
+
+// Get restituisce lo stato del job id.
+func (s *Service) Get(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return *job, nil
+}
+
+// List restituisce tutti i job, più recenti prima.
+func (s *Service) List() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		res = append(res, *job)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Created.After(res[j].Created) })
+	return res
+}
+
+// Cancel cancella un job in coda o in corso.
+func (s *Service) Cancel(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	if job.Status.finished() {
+		return *job, ErrFinished
+	}
+	for i, p := range s.pending {
+		if p == id {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			break
+		}
+	}
+	job.Status, job.Finished = Cancelled, time.Now().UTC()
+	return *job, s.save(job)
+}
+
+func (s *Service) worker() {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		for len(s.pending) == 0 && !s.closed {
+			s.wake.Wait()
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		job := s.jobs[s.pending[0]]
+		s.pending = s.pending[1:]
+		job.Status, job.Started = Running, time.Now().UTC()
+		err := s.save(job)
+		s.mu.Unlock()
+
+		var sum int64
+		var root string
+		if err == nil {
+			sum, root, err = s.prove(job.ID)
+		}
+
+		s.mu.Lock()
+		switch {
+		case job.Status == Cancelled:
+			// cancellato durante la prova: il risultato non serve
+			s.removeBundle(job.ID)
+		case err != nil:
+			s.removeBundle(job.ID)
+			job.Status, job.Error = Failed, err.Error()
+		default:
+			job.Status, job.Sum, job.Root = Done, &sum, root
+		}
+		if job.Status != Cancelled {
+			job.Finished = time.Now().UTC()
+		}
+		if err := s.save(job); err != nil {
+			fmt.Printf("proverd: salvataggio %s: %v\n", job.ID, err)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// prove prova il dataset del job e scrive prova, public witness, verifying
+// key, public.json e manifest nella sua cartella. Restituisce la somma ×1000
+// e il segnale pubblico di tipo root, se il circuito ne ha uno.
+func (s *Service) prove(id string) (int64, string, error) {
+	dir := s.jobDir(id)
+	raw, err := os.ReadFile(filepath.Join(dir, inputFile))
+	if err != nil {
+		return 0, "", err
+	}
+	data, err := kpiinput.Parse(raw)
+	if err != nil {
+		return 0, "", err
+	}
+	assignment, sum, publicValues, err := s.circuit.Assign(s.sys.Curve, data.Values)
+	if err != nil {
+		return 0, "", err
+	}
+	fullWitness, err := frontend.NewWitness(assignment, s.sys.Curve.ScalarField())
+	if err != nil {
+		return 0, "", err
+	}
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		return 0, "", err
+	}
+	proof, err := s.sys.Prove(fullWitness)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := s.sys.Verify(proof, publicWitness); err != nil {
+		return 0, "", fmt.Errorf("la prova non verifica: %w", err)
+	}
+
+	if err := writeTo(filepath.Join(dir, proofFile), proof); err != nil {
+		return 0, "", err
+	}
+	if err := writeTo(filepath.Join(dir, publicFile), publicWitness); err != nil {
+		return 0, "", err
+	}
+	// la verifying key che verifica questa prova, anche se il servizio
+	// ripartirà con altre chiavi
+	if err := writeTo(filepath.Join(dir, vkFile), s.sys.VerifyingKey); err != nil {
+		return 0, "", err
+	}
+	m, err := manifest.New(assignment)
+	if err != nil {
+		return 0, "", err
+	}
+	if len(publicValues) != len(m.Signals) {
+		return 0, "", fmt.Errorf("%d valori pubblici, il manifest ne ha %d", len(publicValues), len(m.Signals))
+	}
+	// public.json come snarkjs, nell'ordine del manifest
+	public := make([]string, len(publicValues))
+	var root string
+	for i, v := range publicValues {
+		public[i] = v.String()
+		if m.Signals[i].Type == "root" {
+			root = public[i]
+		}
+	}
+	out, err := json.MarshalIndent(public, "", "  ")
+	if err != nil {
+		return 0, "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, signals), out, 0o644); err != nil {
+		return 0, "", err
+	}
+	return sum, root, m.Write(dir)
+}
+
+// bundle sono i file scaricati con la prova, più job.json.
+var bundle = []string{proofFile, publicFile, vkFile, signals, manifest.File}
+
+func (s *Service) removeBundle(id string) {
+	for _, name := range bundle {
+		os.Remove(filepath.Join(s.jobDir(id), name))
+	}
+}
+
+func (s *Service) jobDir(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// save scrive job.json passando da un file temporaneo, così un crash non
+// lascia uno stato a metà.
+func (s *Service) save(job *Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.jobDir(job.ID), jobFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeTo(path string, v io.WriterTo) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := v.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
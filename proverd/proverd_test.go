@@ -0,0 +1,378 @@
+package proverd
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+
+	"zk-test/kpiinput"
+	"zk-test/zkbackend"
+)
+
+const testValues = 4
+
+// sumCircuit è un kpimerkle senza albero: solo la somma dei valori ×1000.
+type sumCircuit struct {
+	ExpectedSum frontend.Variable             `gnark:",public" kpi:"sum,scale=1000"`
+	Values      [testValues]frontend.Variable `gnark:",secret"`
+}
+
+func (c *sumCircuit) Define(api frontend.API) error {
+	var sum frontend.Variable = 0
+	for _, v := range c.Values {
+		sum = api.Add(sum, v)
+	}
+	api.AssertIsEqual(sum, c.ExpectedSum)
+	return nil
+}
+
+// testCircuit è il Circuit di sumCircuit. Con gate non nil Assign segnala
+// su started e aspetta gate: il job resta running finché il test non lo
+// lascia andare.
+type testCircuit struct {
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func (c *testCircuit) Schema() frontend.Circuit { return &sumCircuit{} }
+
+func (c *testCircuit) MaxValues() int { return testValues }
+
+func (c *testCircuit) Assign(curve ecc.ID, values []float64) (frontend.Circuit, int64, []*big.Int, error) {
+	if c.gate != nil {
+		c.started <- struct{}{}
+		<-c.gate
+	}
+	assignment := &sumCircuit{}
+	var sum int64
+	for i := range assignment.Values {
+		var v int64
+		if i < len(values) {
+			v = int64(math.Round(values[i] * 1000))
+		}
+		assignment.Values[i] = v
+		sum += v
+	}
+	assignment.ExpectedSum = sum
+	return assignment, sum, []*big.Int{big.NewInt(sum)}, nil
+}
+
+func setup(t *testing.T, curve ecc.ID) *zkbackend.System {
+	t.Helper()
+	ccs, err := zkbackend.Compile(curve, zkbackend.Groth16, &sumCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sys, err := zkbackend.Setup(zkbackend.Groth16, ccs, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sys
+}
+
+func request(t *testing.T, method, url, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, data
+}
+
+func submit(t *testing.T, srv *httptest.Server, body string) Job {
+	t.Helper()
+	resp, data := request(t, http.MethodPost, srv.URL+"/jobs", body)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST /jobs: %d %s", resp.StatusCode, data)
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+// wait interroga GET /jobs/{id} finché il job non è terminato.
+func wait(t *testing.T, srv *httptest.Server, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(time.Minute)
+	for time.Now().Before(deadline) {
+		resp, data := request(t, http.MethodGet, srv.URL+"/jobs/"+id, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /jobs/%s: %d %s", id, resp.StatusCode, data)
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			t.Fatal(err)
+		}
+		if job.Status.finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s non terminato", id)
+	return Job{}
+}
+
+// verifyBundle scarica lo zip del job e verifica la prova con la verifying
+// key e il public witness che contiene.
+func verifyBundle(t *testing.T, srv *httptest.Server, id string, curve ecc.ID) {
+	t.Helper()
+	resp, data := request(t, http.MethodGet, srv.URL+"/jobs/"+id+"/bundle", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("bundle: %d %s", resp.StatusCode, data)
+	}
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], err = io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range append([]string{jobFile}, bundle...) {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("bundle senza %s", name)
+		}
+	}
+
+	proof, err := zkbackend.NewProof(curve, zkbackend.Groth16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vk, err := zkbackend.NewVerifyingKey(curve, zkbackend.Groth16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicWitness, err := witness.New(curve.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, v := range map[string]io.ReaderFrom{proofFile: proof, vkFile: vk, publicFile: publicWitness} {
+		if _, err := v.ReadFrom(bytes.NewReader(files[name])); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	if err := zkbackend.Verify(zkbackend.Groth16, proof, vk, publicWitness); err != nil {
+		t.Fatalf("la prova del bundle non verifica: %v", err)
+	}
+}
+
+// Invio, attesa del risultato e bundle che verifica, anche dopo un riavvio
+// con chiavi nuove: la verifying key è quella salvata con la prova.
+func TestSubmitAndBundle(t *testing.T) {
+	dir := t.TempDir()
+	svc, err := New(setup(t, ecc.BN254), &testCircuit{}, dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.Start(1)
+	srv := httptest.NewServer(svc.Handler())
+
+	job := submit(t, srv, `{"values": [1.5, 2, 3.25]}`)
+	job = wait(t, srv, job.ID)
+	if job.Status != Done || job.Sum == nil || *job.Sum != 6750 {
+		t.Fatalf("job %+v, attesa somma 6750", job)
+	}
+	verifyBundle(t, srv, job.ID, ecc.BN254)
+
+	if resp, _ := request(t, http.MethodPost, srv.URL+"/jobs", `{"values": [1, 2, 3, 4, 5]}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("troppi valori: %d invece di 400", resp.StatusCode)
+	}
+	srv.Close()
+	svc.Close()
+
+	// stesso circuito, nuovo setup: la vk del servizio non verifica la prova vecchia
+	svc, err = New(setup(t, ecc.BN254), &testCircuit{}, dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv = httptest.NewServer(svc.Handler())
+	defer srv.Close()
+	verifyBundle(t, srv, job.ID, ecc.BN254)
+}
+
+func TestQueueFull(t *testing.T) {
+	svc, err := New(setup(t, ecc.BN254), &testCircuit{}, t.TempDir(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(svc.Handler())
+	defer srv.Close()
+
+	// senza worker i job restano in coda
+	submit(t, srv, kpiinput.Example)
+	submit(t, srv, kpiinput.Example)
+	if resp, data := request(t, http.MethodPost, srv.URL+"/jobs", kpiinput.Example); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("coda piena: %d %s invece di 503", resp.StatusCode, data)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	dir := t.TempDir()
+	circuit := &testCircuit{started: make(chan struct{}), gate: make(chan struct{})}
+	svc, err := New(setup(t, ecc.BN254), circuit, dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(svc.Handler())
+	defer srv.Close()
+
+	// il primo job arriva al worker e resta in Assign, il secondo in coda
+	running := submit(t, srv, kpiinput.Example)
+	queued := submit(t, srv, kpiinput.Example)
+	svc.Start(1)
+	<-circuit.started
+	if job, _ := svc.Get(running.ID); job.Status != Running {
+		t.Fatalf("primo job %s invece di running", job.Status)
+	}
+
+	for _, id := range []string{queued.ID, running.ID} {
+		resp, data := request(t, http.MethodDelete, srv.URL+"/jobs/"+id, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("DELETE %s: %d %s", id, resp.StatusCode, data)
+		}
+		if resp, _ := request(t, http.MethodDelete, srv.URL+"/jobs/"+id, ""); resp.StatusCode != http.StatusConflict {
+			t.Fatalf("secondo DELETE %s: %d invece di 409", id, resp.StatusCode)
+		}
+	}
+
+	// il worker finisce la prova e ne scarta il risultato
+	close(circuit.gate)
+	svc.Close()
+	for _, id := range []string{queued.ID, running.ID} {
+		job, err := svc.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != Cancelled {
+			t.Fatalf("job %s: %s invece di cancelled", id, job.Status)
+		}
+		if _, err := os.Stat(filepath.Join(dir, id, proofFile)); !os.IsNotExist(err) {
+			t.Fatalf("job %s cancellato con %s: %v", id, proofFile, err)
+		}
+		if resp, _ := request(t, http.MethodGet, srv.URL+"/jobs/"+id+"/bundle", ""); resp.StatusCode != http.StatusConflict {
+			t.Fatalf("bundle del job %s cancellato: %d invece di 409", id, resp.StatusCode)
+		}
+	}
+}
+
+// New rimette in coda i job queued e running della stessa impronta, nell'ordine
+// di arrivo, e fa fallire quelli inviati con un'altra curva.
+func TestResume(t *testing.T) {
+	dir := t.TempDir()
+	bn254 := setup(t, ecc.BN254)
+	svc, err := New(bn254, &testCircuit{}, dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued, err := svc.Submit(&kpiinput.Data{Values: []float64{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	running, err := svc.Submit(&kpiinput.Data{Values: []float64{2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.Close()
+
+	// running come se il servizio fosse morto durante la prova
+	svc.jobs[running.ID].Status = Running
+	if err := svc.save(svc.jobs[running.ID]); err != nil {
+		t.Fatal(err)
+	}
+
+	// riavvio su un'altra curva: nessun job si prova con queste chiavi
+	other, err := New(setup(t, ecc.BLS12_381), &testCircuit{}, t.TempDir(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.fingerprint == svc.fingerprint {
+		t.Fatal("stessa impronta per BN254 e BLS12-381")
+	}
+	copyDir(t, dir, other.dir)
+	other, err = New(other.sys, &testCircuit{}, other.dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{queued.ID, running.ID} {
+		if job, _ := other.Get(id); job.Status != Failed {
+			t.Fatalf("job %s su un'altra curva: %s invece di failed", id, job.Status)
+		}
+	}
+	if len(other.pending) != 0 {
+		t.Fatalf("%d job in coda su un'altra curva", len(other.pending))
+	}
+
+	// riavvio con le stesse chiavi: entrambi in coda, il più vecchio prima
+	svc, err = New(bn254, &testCircuit{}, dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svc.pending) != 2 || svc.pending[0] != queued.ID || svc.pending[1] != running.ID {
+		t.Fatalf("coda %v, attesa [%s %s]", svc.pending, queued.ID, running.ID)
+	}
+	svc.Start(1)
+	srv := httptest.NewServer(svc.Handler())
+	defer srv.Close()
+	for _, id := range []string{queued.ID, running.ID} {
+		if job := wait(t, srv, id); job.Status != Done {
+			t.Fatalf("job %s ripreso: %s %s", id, job.Status, job.Error)
+		}
+		verifyBundle(t, srv, id, ecc.BN254)
+	}
+	svc.Close()
+}
+
+// copyDir copia le cartelle dei job da src a dst.
+func copyDir(t *testing.T, src, dst string) {
+	t.Helper()
+	err := filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0o755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, rel), data, 0o644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,380 @@
+// Package secreport valuta la sicurezza classica e post-quantum di una
+// configurazione KPI (curva, backend di prova, hash) e dei parametri a
+// reticolo usati con lattigo, per la documentazione di compliance.
+//
+// Le cifre non vengono calcolate con stimatori: sono le stime pubblicate,
+// arrotondate per difetto, con le correzioni generiche degli attacchi
+// quantistici:
+//   - DLOG su curve e campi (pairing): Shor in tempo polinomiale, 0 bit
+//   - hash a n bit: preimmagine n/2 (Grover), collisione n/3 (BHT)
+//   - soundness Fiat-Shamir di FRI: metà dei bit (ricerca di Grover sul
+//     transcript)
+//   - RLWE/MLWE: tabelle classica e quantistica dell'HomomorphicEncryption.org
+//     standard per segreto ternario
+package secreport
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/tuneinsight/lattigo/v4/bfv"
+	"github.com/tuneinsight/lattigo/v4/ckks"
+
+	"zk-test/bfvplan"
+	"zk-test/kpihash"
+	"zk-test/latcommit"
+	"zk-test/zkbackend"
+	"zk-test/zkstark"
+)
+
+// Stark è il backend di zkstark, accanto a quelli di zkbackend.
+const Stark = "stark"
+
+// Backends elenca i backend valutabili.
+var Backends = []string{string(zkbackend.Groth16), string(zkbackend.Plonk), Stark}
+
+// Config è la configurazione da valutare.
+type Config struct {
+	Curve     string `json:"curve"`      // nome gnark-crypto, es. bn254
+	Backend   string `json:"backend"`    // groth16, plonk o stark
+	Hash      string `json:"hash"`       // kpihash.Kinds
+	ValueBits int    `json:"value_bits"` // bit dei KPI scalati, per la forza bruta sugli hash pubblici
+	Lattice   bool   `json:"lattice"`    // aggiunge i parametri lattigo del repo
+}
+
+// Level sono i bit di sicurezza contro un attaccante classico e quantistico.
+type Level struct {
+	Classical int `json:"classical_bits"`
+	Quantum   int `json:"quantum_bits"`
+}
+
+func (l Level) min(o Level) Level {
+	return Level{min(l.Classical, o.Classical), min(l.Quantum, o.Quantum)}
+}
+
+// Component è una primitiva della configurazione. Scope dice cosa protegge:
+// soundness (nessuno prova il falso) o privacy (i KPI restano nascosti).
+type Component struct {
+	Name          string         `json:"name"`
+	Kind          string         `json:"kind"`
+	Scope         string         `json:"scope"`
+	Assumption    string         `json:"assumption"`
+	Security      Level          `json:"security"`
+	Collision     *Level         `json:"collision,omitempty"`
+	Preimage      *Level         `json:"preimage,omitempty"`
+	QuantumAttack string         `json:"quantum_attack"`
+	Parameters    map[string]any `json:"parameters,omitempty"`
+	Notes         []string       `json:"notes,omitempty"`
+}
+
+const (
+	Soundness = "soundness"
+	Privacy   = "privacy"
+)
+
+// Report è il risultato, serializzabile in JSON così com'è. PostQuantum
+// vuol dire che nessuna componente della soundness cade in tempo polinomiale
+// con Shor, non che i bit quantistici bastino.
+type Report struct {
+	Configuration Config      `json:"configuration"`
+	Components    []Component `json:"components"`
+	Lattice       []Component `json:"lattice,omitempty"`
+	Soundness     Level       `json:"soundness"`
+	Privacy       Level       `json:"privacy"`
+	PostQuantum   bool        `json:"post_quantum"`
+	Summary       string      `json:"summary"`
+	References    []string    `json:"references"`
+}
+
+// Inspect valuta cfg.
+func Inspect(cfg Config) (*Report, error) {
+	curve, err := ecc.IDFromString(cfg.Curve)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ValueBits < 1 || cfg.ValueBits > 253 {
+		return nil, fmt.Errorf("secreport: %d bit per valore", cfg.ValueBits)
+	}
+	if !contains(kpihash.Kinds, cfg.Hash) {
+		return nil, fmt.Errorf("secreport: hash sconosciuto %q (%s)", cfg.Hash, strings.Join(kpihash.Kinds, ", "))
+	}
+	r := &Report{Configuration: cfg}
+
+	switch cfg.Backend {
+	case string(zkbackend.Groth16), string(zkbackend.Plonk):
+		p, err := pairing(curve)
+		if err != nil {
+			return nil, err
+		}
+		r.Components = append(r.Components, p, snark(cfg.Backend, p))
+	case Stark:
+		// zkstark lavora sul campo di BN254 con Poseidon2, senza pairing
+		if curve != ecc.BN254 || cfg.Hash != kpihash.Poseidon2 {
+			return nil, fmt.Errorf("secreport: zkstark prova solo bn254 con poseidon2")
+		}
+		r.Components = append(r.Components, stark(), hash("sha256", 256, 128,
+			"Merkle e Fiat-Shamir di zkstark"))
+	default:
+		return nil, fmt.Errorf("secreport: backend sconosciuto %q (%s)", cfg.Backend, strings.Join(Backends, ", "))
+	}
+
+	h := fieldHash(cfg.Hash, curve)
+	r.Components = append(r.Components, h, values(cfg.ValueBits, *h.Preimage))
+
+	// la privacy è perfetta finché non ci sono componenti che la limitano
+	r.Soundness = Level{math.MaxInt, math.MaxInt}
+	r.Privacy = r.Soundness
+	for _, c := range r.Components {
+		if c.Scope == Soundness {
+			r.Soundness = r.Soundness.min(c.Security)
+		} else {
+			r.Privacy = r.Privacy.min(c.Security)
+		}
+	}
+	r.PostQuantum = r.Soundness.Quantum > 0
+	r.Summary = fmt.Sprintf("%s su %s con %s: soundness %d bit classici / %d quantistici, privacy dei KPI %d / %d",
+		cfg.Backend, curve, cfg.Hash, r.Soundness.Classical, r.Soundness.Quantum, r.Privacy.Classical, r.Privacy.Quantum)
+
+	if cfg.Lattice {
+		r.Lattice, err = lattice()
+		if err != nil {
+			return nil, err
+		}
+	}
+	r.References = references
+	return r, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// dlp sono le stime di sicurezza del DLP nel campo F_(p^k) del pairing dopo
+// (ex)TNFS, per difetto.
+var dlp = map[ecc.ID]struct {
+	bits, k int
+	source  string
+}{
+	ecc.BN254:     {100, 12, "Barbulescu-Duquesne 2019: 100-103 bit dopo exTNFS"},
+	ecc.BLS12_377: {120, 12, "Guillevic 2020, stima prudente"},
+	ecc.BLS12_381: {117, 12, "Barbulescu-Duquesne 2019: 117-120 bit"},
+	ecc.BW6_761:   {126, 6, "El Housni-Guillevic 2020"},
+}
+
+// pairing valuta la curva: ECDLP con rho di Pollard in G1/G2, DLP in G_T.
+func pairing(curve ecc.ID) (Component, error) {
+	d, ok := dlp[curve]
+	if !ok {
+		return Component{}, fmt.Errorf("secreport: nessuna stima per %s (bn254, bls12_377, bls12_381, bw6_761)", curve)
+	}
+	rBits := curve.ScalarField().BitLen()
+	pBits := curve.BaseField().BitLen()
+	return Component{
+		Name:       "pairing " + curve.String(),
+		Kind:       "pairing",
+		Scope:      Soundness,
+		Assumption: "DLOG in G1, G2 (rho di Pollard) e in G_T ⊂ F_(p^k) (TNFS)",
+		Security:   Level{Classical: min(rBits/2, d.bits), Quantum: 0},
+		// Roetteler et al. 2017: 9n + 2⌈log n⌉ + 10 qubit logici per ECDLP
+		QuantumAttack: fmt.Sprintf("Shor: tempo polinomiale, circa %d qubit logici per l'ECDLP a %d bit",
+			9*pBits+2*bits.Len(uint(pBits))+10, pBits),
+		Parameters: map[string]any{
+			"scalar_field_bits": rBits,
+			"base_field_bits":   pBits,
+			"embedding_degree":  d.k,
+		},
+		Notes: []string{d.source},
+	}, nil
+}
+
+// snark è il sistema di prova sopra la curva: la soundness dipende da
+// assunzioni nei gruppi del pairing, la zero-knowledge no.
+func snark(backend string, p Component) Component {
+	c := Component{
+		Name:          backend,
+		Kind:          "proof-system",
+		Scope:         Soundness,
+		Security:      p.Security,
+		QuantumAttack: "Shor ricava il trapdoor τ dall'SRS e permette prove false",
+	}
+	if backend == string(zkbackend.Groth16) {
+		c.Assumption = "knowledge-of-exponent e q-type nei gruppi del pairing, trusted setup per circuito"
+	} else {
+		c.Assumption = "q-DLOG / AGM per gli impegni KZG, SRS universale"
+	}
+	c.Notes = []string{
+		"zero-knowledge perfetta: le prove già pubblicate non rivelano i KPI neanche a un attaccante quantistico",
+		"la soundness non sopravvive: chi conosce τ (o lo calcola con Shor) prova qualsiasi somma",
+	}
+	return c
+}
+
+// stark è zkstark: FRI con SHA-256, soundness congetturata da zkstark.
+func stark() Component {
+	b := zkstark.SecurityBits()
+	return Component{
+		Name:          "zkstark (FRI)",
+		Kind:          "proof-system",
+		Scope:         Soundness,
+		Assumption:    "proximity gap di FRI (congettura) e SHA-256 come random oracle",
+		Security:      Level{Classical: b, Quantum: b / 2},
+		QuantumAttack: "Grover sul transcript Fiat-Shamir (grinding): metà dei bit",
+		Parameters: map[string]any{
+			"rows":    zkstark.Rows,
+			"blowup":  zkstark.Blowup,
+			"queries": zkstark.Queries,
+		},
+		Notes: []string{
+			"nessun trusted setup",
+			fmt.Sprintf("per 128 bit quantistici servono circa %d aperture invece di %d", 128*zkstark.Queries/(b/2), zkstark.Queries),
+			"non zero-knowledge: la privacy dipende solo dagli hash pubblici dei valori",
+		},
+	}
+}
+
+// hash valuta un hash a n bit di output con sicurezza di progetto design.
+func hash(name string, n, design int, use string) Component {
+	col := Level{Classical: min(n/2, design), Quantum: min(n/3, design)}
+	pre := Level{Classical: min(n, design), Quantum: min(n/2, design)}
+	return Component{
+		Name:          name,
+		Kind:          "hash",
+		Scope:         Soundness,
+		Assumption:    "resistenza alle collisioni (" + use + ")",
+		Security:      col,
+		Collision:     &col,
+		Preimage:      &pre,
+		QuantumAttack: "Grover per la preimmagine (2^(n/2)), BHT per le collisioni (2^(n/3), con memoria quantistica 2^(n/3))",
+		Parameters:    map[string]any{"output_bits": n, "design_bits": design},
+	}
+}
+
+// fieldHash è l'hash dei KPI nel circuito, con output un elemento del campo.
+func fieldHash(kind string, curve ecc.ID) Component {
+	n := curve.ScalarField().BitLen() - 1 // un elemento di F_r, circa log2(r) bit
+	c := hash(kind, n, 128, "binding di hash e root pubblici")
+	switch kind {
+	case kpihash.MiMC:
+		c.Notes = append(c.Notes, "MiMC x^5 ha margini algebrici ridotti rispetto a Poseidon (attacchi GCD e di interpolazione)")
+	default:
+		c.Notes = append(c.Notes, "sicurezza di progetto 128 bit contro attacchi algebrici (Gröbner, interpolazione), senza accelerazioni quantistiche note")
+	}
+	return c
+}
+
+// values è la privacy dei KPI dati gli hash pubblici: chi ha Hashes[i] prova
+// tutti i 2^bits valori possibili.
+func values(bits int, pre Level) Component {
+	return Component{
+		Name:          "KPI dietro hash pubblici",
+		Kind:          "input",
+		Scope:         Privacy,
+		Assumption:    fmt.Sprintf("i valori hanno %d bit: la preimmagine si trova per forza bruta", bits),
+		Security:      Level{Classical: min(bits, pre.Classical), Quantum: min(bits/2, pre.Quantum)},
+		QuantumAttack: "Grover sui 2^bits valori possibili",
+		Parameters:    map[string]any{"value_bits": bits},
+		Notes:         []string{"l'hash non è un impegno nascosto: servirebbe un salt segreto nella foglia"},
+	}
+}
+
+// heStandard è log2(QP) massimo per 128, 192 e 256 bit con segreto
+// ternario, per dimensione dell'anello (HomomorphicEncryption.org, 2018).
+var heStandard = map[string]map[int][3]int{
+	"classical": {
+		1024: {27, 19, 14}, 2048: {54, 37, 29}, 4096: {109, 75, 58},
+		8192: {218, 152, 118}, 16384: {438, 305, 237}, 32768: {881, 611, 476},
+	},
+	"quantum": {
+		1024: {25, 17, 13}, 2048: {51, 35, 27}, 4096: {101, 70, 54},
+		8192: {202, 141, 109}, 16384: {411, 284, 220}, 32768: {827, 571, 443},
+	},
+}
+
+// lweBits legge la tabella: il livello più alto che ammette logQ; sotto i
+// 128 bit stima per proporzione, la sicurezza cresce circa come n/log q.
+// Dimensioni non in tabella usano la più vicina per difetto.
+func lweBits(table map[int][3]int, n, logQ int) int {
+	dim := 0
+	for d := range table {
+		if d <= n && d > dim {
+			dim = d
+		}
+	}
+	if dim == 0 {
+		return 0
+	}
+	limits := table[dim]
+	for i, lvl := range []int{256, 192, 128} {
+		if logQ <= limits[2-i] {
+			return lvl
+		}
+	}
+	return 128 * limits[0] / logQ
+}
+
+func lweLevel(n, logQ int) Level {
+	return Level{
+		Classical: lweBits(heStandard["classical"], n, logQ),
+		Quantum:   lweBits(heStandard["quantum"], n, logQ),
+	}
+}
+
+// lattice valuta i parametri lattigo usati nel repo.
+func lattice() ([]Component, error) {
+	var res []Component
+	add := func(name, use string, n, logQ int, params map[string]any) {
+		params["ring_degree"] = n
+		params["log_qp"] = logQ
+		res = append(res, Component{
+			Name:          name,
+			Kind:          "lattice",
+			Scope:         Privacy,
+			Assumption:    "RLWE/MLWE con segreto ternario (" + use + ")",
+			Security:      lweLevel(n, logQ),
+			QuantumAttack: "nessun attacco quantistico oltre all'accelerazione del sieving nel BKZ",
+			Parameters:    params,
+			Notes:         []string{"livelli dalla tabella dell'HE standard: 128/192/256, sotto 128 per proporzione"},
+		})
+	}
+	for _, p := range bfvplan.Presets {
+		params, err := bfv.NewParametersFromLiteral(p.Literal)
+		if err != nil {
+			return nil, err
+		}
+		add("bfv "+p.Name, "cifratura dei KPI, lattigo e bfvplan", params.N(), params.LogQP(),
+			map[string]any{"plaintext_modulus": params.T()})
+	}
+	params, err := ckks.NewParametersFromLiteral(ckks.PN14QP438)
+	if err != nil {
+		return nil, err
+	}
+	add("ckks PN14QP438", "lattigo_ckks", params.N(), params.LogQP(),
+		map[string]any{"log_scale": math.Log2(params.DefaultScale().Float64())})
+
+	// latcommit: il segreto MLWE sono RandLen-Rows polinomi
+	dim := latcommit.N * (latcommit.RandLen - latcommit.Rows)
+	add("latcommit", "hiding dell'impegno ai KPI", dim, latcommit.LogQ,
+		map[string]any{"module_rank": latcommit.RandLen - latcommit.Rows, "rows": latcommit.Rows})
+	last := &res[len(res)-1]
+	last.Notes = append(last.Notes, "il binding (MSIS) non è stimato: parametri d'esempio",
+		fmt.Sprintf("prova della somma con %d sfide in Z_q: errore circa 2^-%d", latcommit.SumChallenges, latcommit.SumChallenges*(latcommit.LogQ-1)))
+	return res, nil
+}
+
+var references = []string{
+	"Shor 1994; Roetteler, Naehrig, Svore, Lauter 2017 (qubit per l'ECDLP)",
+	"Grover 1996; Brassard, Høyer, Tapp 1998",
+	"Barbulescu, Duquesne 2019, Updating key size estimations for pairings",
+	"Guillevic 2020, A short-list of pairing-friendly curves resistant to STNFS",
+	"Albrecht et al. 2018, Homomorphic Encryption Security Standard",
+	"Grassi et al. 2019, Poseidon (eprint 2019/458); Albrecht et al. 2016, MiMC (eprint 2016/492)",
+	"Chiesa, Manohar, Spooner 2019, Succinct arguments in the quantum random oracle model",
+}
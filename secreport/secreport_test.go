@@ -0,0 +1,128 @@
+package secreport
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// curves sono le curve con una stima del DLP in dlp.
+var curves = []string{"bn254", "bls12_377", "bls12_381", "bw6_761"}
+
+// Il report arriva ai compliance come JSON: le chiavi sono quelle dei tag e
+// lattice compare solo se richiesto.
+func TestInspectJSON(t *testing.T) {
+	for _, curve := range curves {
+		for _, backend := range Backends {
+			cfg := Config{Curve: curve, Backend: backend, Hash: "poseidon2", ValueBits: 20}
+			r, err := Inspect(cfg)
+			if backend == Stark && curve != "bn254" {
+				if err == nil {
+					t.Errorf("%s su %s: accettato, zkstark prova solo bn254", backend, curve)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s su %s: %v", backend, curve, err)
+			}
+
+			data, err := json.Marshal(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var m map[string]any
+			if err := json.Unmarshal(data, &m); err != nil {
+				t.Fatal(err)
+			}
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			want := []string{"components", "configuration", "post_quantum", "privacy", "references", "soundness", "summary"}
+			if !slices.Equal(keys, want) {
+				t.Fatalf("%s su %s: chiavi %v, attese %v", backend, curve, keys, want)
+			}
+			conf := m["configuration"].(map[string]any)
+			if conf["curve"] != curve || conf["backend"] != backend {
+				t.Fatalf("%s su %s: configurazione %v", backend, curve, conf)
+			}
+			for _, level := range []string{"soundness", "privacy"} {
+				l := m[level].(map[string]any)
+				if _, ok := l["classical_bits"]; !ok {
+					t.Fatalf("%s su %s: %s senza classical_bits: %v", backend, curve, level, l)
+				}
+				if _, ok := l["quantum_bits"]; !ok {
+					t.Fatalf("%s su %s: %s senza quantum_bits: %v", backend, curve, level, l)
+				}
+			}
+
+			// con un pairing Shor rompe la soundness, FRI con SHA-256 no
+			if pq := m["post_quantum"].(bool); pq != (backend == Stark) {
+				t.Fatalf("%s su %s: post_quantum %v", backend, curve, pq)
+			}
+			if backend != Stark && (r.Soundness.Quantum != 0 || r.Soundness.Classical == 0) {
+				t.Fatalf("%s su %s: soundness %+v", backend, curve, r.Soundness)
+			}
+			names := make([]string, len(r.Components))
+			for i, c := range r.Components {
+				names[i] = c.Name
+			}
+			if !slices.Contains(names, backend) && !(backend == Stark && slices.Contains(names, "zkstark (FRI)")) {
+				t.Fatalf("%s su %s: componenti %v", backend, curve, names)
+			}
+		}
+	}
+}
+
+func TestInspectErrors(t *testing.T) {
+	for _, tc := range []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{Curve: "curva25519", Backend: "groth16", Hash: "poseidon2", ValueBits: 20}, "unknown curve"},
+		{Config{Curve: "secp256k1", Backend: "groth16", Hash: "poseidon2", ValueBits: 20}, "nessuna stima"}, // senza pairing
+		{Config{Curve: "bn254", Backend: "halo2", Hash: "poseidon2", ValueBits: 20}, "backend sconosciuto"},
+		{Config{Curve: "bn254", Backend: "groth16", Hash: "sha3", ValueBits: 20}, "hash sconosciuto"},
+		{Config{Curve: "bn254", Backend: "groth16", Hash: "poseidon2", ValueBits: 0}, "bit per valore"},
+		{Config{Curve: "bn254", Backend: Stark, Hash: "mimc", ValueBits: 20}, "poseidon2"},
+	} {
+		r, err := Inspect(tc.cfg)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: report %v, errore %v, atteso %q", tc.cfg, r, err, tc.want)
+		}
+	}
+}
+
+// tabella dell'HE standard: con il segreto MLWE di 2048 coefficienti un q da
+// 60 bit ne dava circa 115.
+func TestLatcommitHiding(t *testing.T) {
+	r, err := Inspect(Config{Curve: "bn254", Backend: Stark, Hash: "poseidon2", ValueBits: 20, Lattice: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range r.Lattice {
+		if c.Name != "latcommit" {
+			continue
+		}
+		if c.Security.Classical < 128 || c.Security.Quantum < 128 {
+			t.Fatalf("hiding di latcommit a %d/%d bit (log q %v)", c.Security.Classical, c.Security.Quantum, c.Parameters["log_qp"])
+		}
+		return
+	}
+	t.Fatal("latcommit manca nel report")
+}
+
+func TestLweBits(t *testing.T) {
+	for _, tc := range []struct{ n, logQ, classical int }{
+		{2048, 54, 128},
+		{2048, 60, 115},
+		{2048, 29, 256},
+		{4096, 109, 128},
+	} {
+		if got := lweLevel(tc.n, tc.logQ).Classical; got != tc.classical {
+			t.Errorf("n %d, log q %d: %d bit, attesi %d", tc.n, tc.logQ, got, tc.classical)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Package snarkjsgroth16 legge i file Groth16 di snarkjs/circom
+// (verification_key.json, proof.json, public.json) e verifica la prova con i
+// pairing di gnark-crypto, senza passare da Node.
+//
+// È il verso opposto di gnarktosnarkjs: i file sono gli stessi delle cartelle
+// testSnarkJS, quindi si possono verificare sia le prove esportate dai main sia
+// quelle prodotte da circuiti circom dei partner.
+package snarkjsgroth16
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// VerifyingKey è la verification key Groth16 in forma affine.
+type VerifyingKey struct {
+	Alpha bn254.G1Affine
+	Beta  bn254.G2Affine
+	Gamma bn254.G2Affine
+	Delta bn254.G2Affine
+	IC    []bn254.G1Affine // IC[0] costante, IC[i+1] per il segnale pubblico i
+}
+
+// Proof è la prova Groth16 (A, B, C).
+type Proof struct {
+	A bn254.G1Affine
+	B bn254.G2Affine
+	C bn254.G1Affine
+}
+
+// NPublic è il numero di segnali pubblici attesi dalla vk.
+func (vk *VerifyingKey) NPublic() int {
+	return len(vk.IC) - 1
+}
+
+// formato JSON di snarkjs: punti proiettivi in decimale, G2 come [c0, c1]
+type vkJSON struct {
+	Protocol string     `json:"protocol"`
+	Curve    string     `json:"curve"`
+	NPublic  int        `json:"nPublic"`
+	Alpha    []string   `json:"vk_alpha_1"`
+	Beta     [][]string `json:"vk_beta_2"`
+	Gamma    [][]string `json:"vk_gamma_2"`
+	Delta    [][]string `json:"vk_delta_2"`
+	IC       [][]string `json:"IC"`
+}
+
+type proofJSON struct {
+	Protocol string     `json:"protocol"`
+	Curve    string     `json:"curve"`
+	A        []string   `json:"pi_a"`
+	B        [][]string `json:"pi_b"`
+	C        []string   `json:"pi_c"`
+}
+
+// checkHeader accetta solo Groth16 su BN254: snarkjs la chiama bn128,
+// gnarktosnarkjs bn254.
+func checkHeader(protocol, curve string) error {
+	if protocol != "groth16" {
+		return fmt.Errorf("protocollo %q, atteso groth16", protocol)
+	}
+	if curve != "bn128" && curve != "bn254" {
+		return fmt.Errorf("curva %q non supportata, solo bn128/bn254", curve)
+	}
+	return nil
+}
+
+// ReadVerifyingKey legge verification_key.json.
+func ReadVerifyingKey(path string) (*VerifyingKey, error) {
+	var raw vkJSON
+	if err := readJSON(path, &raw); err != nil {
+		return nil, err
+	}
+	if err := checkHeader(raw.Protocol, raw.Curve); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(raw.IC) != raw.NPublic+1 {
+		return nil, fmt.Errorf("%s: IC ha %d punti, nPublic = %d", path, len(raw.IC), raw.NPublic)
+	}
+
+	vk := &VerifyingKey{IC: make([]bn254.G1Affine, len(raw.IC))}
+	var err error
+	if vk.Alpha, err = parseG1(raw.Alpha); err != nil {
+		return nil, fmt.Errorf("vk_alpha_1: %w", err)
+	}
+	if vk.Beta, err = parseG2(raw.Beta); err != nil {
+		return nil, fmt.Errorf("vk_beta_2: %w", err)
+	}
+	if vk.Gamma, err = parseG2(raw.Gamma); err != nil {
+		return nil, fmt.Errorf("vk_gamma_2: %w", err)
+	}
+	if vk.Delta, err = parseG2(raw.Delta); err != nil {
+		return nil, fmt.Errorf("vk_delta_2: %w", err)
+	}
+	for i := range raw.IC {
+		if vk.IC[i], err = parseG1(raw.IC[i]); err != nil {
+			return nil, fmt.Errorf("IC[%d]: %w", i, err)
+		}
+	}
+	return vk, nil
+}
+
+// ReadProof legge proof.json.
+func ReadProof(path string) (*Proof, error) {
+	var raw proofJSON
+	if err := readJSON(path, &raw); err != nil {
+		return nil, err
+	}
+	if err := checkHeader(raw.Protocol, raw.Curve); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var p Proof
+	var err error
+	if p.A, err = parseG1(raw.A); err != nil {
+		return nil, fmt.Errorf("pi_a: %w", err)
+	}
+	if p.B, err = parseG2(raw.B); err != nil {
+		return nil, fmt.Errorf("pi_b: %w", err)
+	}
+	if p.C, err = parseG1(raw.C); err != nil {
+		return nil, fmt.Errorf("pi_c: %w", err)
+	}
+	return &p, nil
+}
+
+// ReadPublic legge public.json. I valori devono essere già ridotti modulo r,
+// come li scrive snarkjs.
+func ReadPublic(path string) ([]fr.Element, error) {
+	var raw []string
+	if err := readJSON(path, &raw); err != nil {
+		return nil, err
+	}
+	res := make([]fr.Element, len(raw))
+	for i, s := range raw {
+		v, ok := new(big.Int).SetString(s, 10)
+		if !ok || v.Sign() < 0 || v.Cmp(fr.Modulus()) >= 0 {
+			return nil, fmt.Errorf("%s: segnale %d non è un elemento di Fr: %q", path, i, s)
+		}
+		res[i].SetBigInt(v)
+	}
+	return res, nil
+}
+
+// Read legge i tre file da dir.
+func Read(dir string) (*VerifyingKey, *Proof, []fr.Element, error) {
+	vk, err := ReadVerifyingKey(filepath.Join(dir, "verification_key.json"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	proof, err := ReadProof(filepath.Join(dir, "proof.json"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	public, err := ReadPublic(filepath.Join(dir, "public.json"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return vk, proof, public, nil
+}
+
+// Verify controlla e(A, B) = e(α, β)·e(L, γ)·e(C, δ) con
+// L = IC₀ + Σ publicᵢ·ICᵢ₊₁, come groth16.verify di snarkjs.
+func Verify(vk *VerifyingKey, proof *Proof, public []fr.Element) error {
+	if len(public) != vk.NPublic() {
+		return fmt.Errorf("%d segnali pubblici, la vk ne attende %d", len(public), vk.NPublic())
+	}
+	// i punti G2 letti da JSON possono stare sulla curva ma fuori dal sottogruppo
+	for _, p := range []*bn254.G2Affine{&proof.B, &vk.Beta, &vk.Gamma, &vk.Delta} {
+		if !p.IsInSubGroup() {
+			return errors.New("punto G2 fuori dal sottogruppo")
+		}
+	}
+
+	var l bn254.G1Affine
+	if len(public) > 0 {
+		if _, err := l.MultiExp(vk.IC[1:], public, ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+	}
+	l.Add(&l, &vk.IC[0])
+
+	// e(-A, B)·e(α, β)·e(L, γ)·e(C, δ) = 1
+	var negA bn254.G1Affine
+	negA.Neg(&proof.A)
+	ok, err := bn254.PairingCheck(
+		[]bn254.G1Affine{negA, vk.Alpha, l, proof.C},
+		[]bn254.G2Affine{proof.B, vk.Beta, vk.Gamma, vk.Delta},
+	)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("pairing check fallito: prova non valida")
+	}
+	return nil
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// parseG1 legge [x, y, z]; snarkjs scrive z = 1, oppure z = 0 per il punto
+// all'infinito.
+func parseG1(c []string) (bn254.G1Affine, error) {
+	var p bn254.G1Affine
+	if len(c) != 3 {
+		return p, fmt.Errorf("punto G1 con %d coordinate", len(c))
+	}
+	z, err := parseFp(c[2])
+	if err != nil {
+		return p, err
+	}
+	if z.IsZero() {
+		return p, nil
+	}
+	if !z.IsOne() {
+		return p, errors.New("punto G1 non normalizzato (z ≠ 1)")
+	}
+	if p.X, err = parseFp(c[0]); err != nil {
+		return p, err
+	}
+	if p.Y, err = parseFp(c[1]); err != nil {
+		return p, err
+	}
+	if !p.IsOnCurve() {
+		return p, errors.New("punto G1 non sulla curva")
+	}
+	return p, nil
+}
+
+// parseG2 legge [[x.c0, x.c1], [y.c0, y.c1], [z.c0, z.c1]].
+func parseG2(c [][]string) (bn254.G2Affine, error) {
+	var p bn254.G2Affine
+	if len(c) != 3 || len(c[0]) != 2 || len(c[1]) != 2 || len(c[2]) != 2 {
+		return p, errors.New("punto G2 malformato")
+	}
+	var coords [6]fp.Element
+	for i := range coords {
+		var err error
+		if coords[i], err = parseFp(c[i/2][i%2]); err != nil {
+			return p, err
+		}
+	}
+	if coords[4].IsZero() && coords[5].IsZero() {
+		return p, nil
+	}
+	if !coords[4].IsOne() || !coords[5].IsZero() {
+		return p, errors.New("punto G2 non normalizzato (z ≠ 1)")
+	}
+	p.X.A0, p.X.A1 = coords[0], coords[1]
+	p.Y.A0, p.Y.A1 = coords[2], coords[3]
+	if !p.IsOnCurve() {
+		return p, errors.New("punto G2 non sulla curva")
+	}
+	return p, nil
+}
+
+func parseFp(s string) (fp.Element, error) {
+	var e fp.Element
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 || v.Cmp(fp.Modulus()) >= 0 {
+		return e, fmt.Errorf("coordinata non valida %q", s)
+	}
+	e.SetBigInt(v)
+	return e, nil
+}
@@ -0,0 +1,50 @@
+package snarkjsgroth16
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// fixtures sono le cartelle testSnarkJS committate dai main, negli stessi
+// file JSON di snarkjs.
+var fixtures = []string{
+	"zsnark_MiMC",
+	"zsnark_Poseidon_merkle_tree",
+	"zsnark_Poseidon_linear_commitment",
+}
+
+func TestVerifyFixtures(t *testing.T) {
+	for _, name := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			vk, proof, public, err := Read(filepath.Join("..", name, "testSnarkJS"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Verify(vk, proof, public); err != nil {
+				t.Fatalf("prova valida rifiutata: %v", err)
+			}
+
+			// l'ultimo segnale pubblico è ExpectedSum in tutti i circuiti KPI
+			last := len(public) - 1
+			orig := public[last]
+			public[last].SetUint64(orig.Uint64() + 1)
+			if Verify(vk, proof, public) == nil {
+				t.Fatal("accettata con un segnale pubblico alterato")
+			}
+			public[last] = orig
+
+			if Verify(vk, proof, public[:last]) == nil {
+				t.Fatal("accettata con un segnale pubblico in meno")
+			}
+		})
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	if err := checkHeader("plonk", "bn128"); err == nil {
+		t.Fatal("accettato il protocollo plonk")
+	}
+	if err := checkHeader("groth16", "bls12381"); err == nil {
+		t.Fatal("accettata la curva bls12381")
+	}
+}
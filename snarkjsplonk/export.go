@@ -0,0 +1,117 @@
+package snarkjsplonk
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// snarkjs chiama la curva bn128 e serializza i punti in coordinate proiettive
+// con z = 1, tutti i numeri in decimale. Il punto all'infinito, che gnark
+// tiene come (0, 0), per snarkjs è (0, 1, 0).
+
+func g1JSON(p *bn254.G1Affine) []string {
+	if p.IsInfinity() {
+		return []string{"0", "1", "0"}
+	}
+	return []string{p.X.String(), p.Y.String(), "1"}
+}
+
+func g2JSON(p *bn254.G2Affine) [][]string {
+	if p.IsInfinity() {
+		return [][]string{{"0", "0"}, {"1", "0"}, {"0", "0"}}
+	}
+	return [][]string{
+		{p.X.A0.String(), p.X.A1.String()},
+		{p.Y.A0.String(), p.Y.A1.String()},
+		{"1", "0"},
+	}
+}
+
+func (vk *VerifyingKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"protocol": "plonk",
+		"curve":    "bn128",
+		"nPublic":  vk.NPublic,
+		"power":    vk.Power,
+		"k1":       vk.K1.String(),
+		"k2":       vk.K2.String(),
+		"Qm":       g1JSON(&vk.Qm),
+		"Ql":       g1JSON(&vk.Ql),
+		"Qr":       g1JSON(&vk.Qr),
+		"Qo":       g1JSON(&vk.Qo),
+		"Qc":       g1JSON(&vk.Qc),
+		"S1":       g1JSON(&vk.S1),
+		"S2":       g1JSON(&vk.S2),
+		"S3":       g1JSON(&vk.S3),
+		"X_2":      g2JSON(&vk.X2),
+		"w":        vk.W.String(),
+	})
+}
+
+func (p *Proof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"A":        g1JSON(&p.A),
+		"B":        g1JSON(&p.B),
+		"C":        g1JSON(&p.C),
+		"Z":        g1JSON(&p.Z),
+		"T1":       g1JSON(&p.T1),
+		"T2":       g1JSON(&p.T2),
+		"T3":       g1JSON(&p.T3),
+		"Wxi":      g1JSON(&p.Wxi),
+		"Wxiw":     g1JSON(&p.Wxiw),
+		"eval_a":   p.EvalA.String(),
+		"eval_b":   p.EvalB.String(),
+		"eval_c":   p.EvalC.String(),
+		"eval_s1":  p.EvalS1.String(),
+		"eval_s2":  p.EvalS2.String(),
+		"eval_zw":  p.EvalZw.String(),
+		"protocol": "plonk",
+		"curve":    "bn128",
+	})
+}
+
+// PublicSignals converte i segnali pubblici nel formato di public.json.
+func PublicSignals(publicSignals []fr.Element) []string {
+	res := make([]string, len(publicSignals))
+	for i := range publicSignals {
+		res[i] = publicSignals[i].String()
+	}
+	return res
+}
+
+// Export scrive proof.json, verification_key.json e public.json in dir, gli
+// stessi file prodotti per Groth16, così testSnarkJS funziona con entrambi.
+func Export(dir string, proof *Proof, vk *VerifyingKey, publicSignals []fr.Element) error {
+	files := []struct {
+		name string
+		v    any
+	}{
+		{"proof.json", proof},
+		{"verification_key.json", vk},
+		{"public.json", PublicSignals(publicSignals)},
+	}
+	for _, f := range files {
+		if err := writeJSON(filepath.Join(dir, f.name), f.v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(path string, v any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
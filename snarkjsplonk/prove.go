@@ -0,0 +1,439 @@
+package snarkjsplonk
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark/backend/witness"
+	cs_bn254 "github.com/consensys/gnark/constraint/bn254"
+)
+
+// Proof rispecchia il proof.json plonk di snarkjs.
+type Proof struct {
+	A, B, C, Z, T1, T2, T3, Wxi, Wxiw bn254.G1Affine
+
+	EvalA, EvalB, EvalC, EvalS1, EvalS2, EvalZw fr.Element
+}
+
+// Prove risolve il circuito con il witness completo e genera una prova nel
+// protocollo PLONK di snarkjs. Restituisce anche i segnali pubblici in ordine.
+func Prove(pk *ProvingKey, fullWitness witness.Witness) (*Proof, []fr.Element, error) {
+	solution, err := pk.ccs.Solve(fullWitness)
+	if err != nil {
+		return nil, nil, err
+	}
+	sol := solution.(*cs_bn254.SparseR1CSSolution)
+
+	n := int(pk.domain.Cardinality)
+	vk := &pk.Vk
+
+	// 0. valori delle colonne a, b, c nel layout di Setup
+	aL := make([]fr.Element, n)
+	bL := make([]fr.Element, n)
+	cL := make([]fr.Element, n)
+	copy(aL, sol.L[:pk.nbPublic])
+	used := pk.nbPublic + pk.nbConstraints
+	copy(aL[pk.nbPublic:used], sol.L[pk.nbPublic:used])
+	copy(bL[pk.nbPublic:used], sol.R[pk.nbPublic:used])
+	copy(cL[pk.nbPublic:used], sol.O[pk.nbPublic:used])
+	aL[used].SetOne()
+	bL[used].SetOne()
+	cL[used].SetInt64(-4)
+
+	publicSignals := make([]fr.Element, pk.nbPublic)
+	copy(publicSignals, aL[:pk.nbPublic])
+
+	blinding := make([]fr.Element, 12)
+	for i := 1; i < len(blinding); i++ {
+		if _, err := blinding[i].SetRandom(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	proof := &Proof{}
+
+	// 1. round 1: a, b, c con blinding (b₁X + b₂)·Z_H
+	a := blind(pk.toCoefficients(aL), blinding[2], blinding[1])
+	b := blind(pk.toCoefficients(bL), blinding[4], blinding[3])
+	c := blind(pk.toCoefficients(cL), blinding[6], blinding[5])
+	if proof.A, err = pk.commit(a); err != nil {
+		return nil, nil, err
+	}
+	if proof.B, err = pk.commit(b); err != nil {
+		return nil, nil, err
+	}
+	if proof.C, err = pk.commit(c); err != nil {
+		return nil, nil, err
+	}
+
+	t := newTranscript()
+	t.addPoint(&vk.Qm, &vk.Ql, &vk.Qr, &vk.Qo, &vk.Qc, &vk.S1, &vk.S2, &vk.S3)
+	for i := range publicSignals {
+		t.addScalar(&publicSignals[i])
+	}
+	t.addPoint(&proof.A, &proof.B, &proof.C)
+	beta := t.challenge()
+	t.addScalar(&beta)
+	gamma := t.challenge()
+
+	// 2. round 2: polinomio di permutazione z
+	omegas := make([]fr.Element, n)
+	fft.BuildExpTable(pk.domain.Generator, omegas)
+
+	num := make([]fr.Element, n)
+	den := make([]fr.Element, n)
+	for i := 0; i < n; i++ {
+		var betaW, t1, t2 fr.Element
+		betaW.Mul(&beta, &omegas[i])
+
+		num[i] = linearTerm(&aL[i], &betaW, &gamma)
+		t1.Mul(&betaW, &vk.K1)
+		t2 = linearTerm(&bL[i], &t1, &gamma)
+		num[i].Mul(&num[i], &t2)
+		t1.Mul(&betaW, &vk.K2)
+		t2 = linearTerm(&cL[i], &t1, &gamma)
+		num[i].Mul(&num[i], &t2)
+
+		for col, v := range [3]*fr.Element{&aL[i], &bL[i], &cL[i]} {
+			var bs fr.Element
+			bs.Mul(&beta, &pk.sigma[col][i])
+			t2 = linearTerm(v, &bs, &gamma)
+			if col == 0 {
+				den[i] = t2
+			} else {
+				den[i].Mul(&den[i], &t2)
+			}
+		}
+	}
+	denInv := fr.BatchInvert(den)
+
+	zL := make([]fr.Element, n)
+	zL[0].SetOne()
+	for i := 0; i < n-1; i++ {
+		zL[i+1].Mul(&zL[i], &num[i]).Mul(&zL[i+1], &denInv[i])
+	}
+	var closing fr.Element
+	closing.Mul(&zL[n-1], &num[n-1]).Mul(&closing, &denInv[n-1])
+	if !closing.IsOne() {
+		return nil, nil, fmt.Errorf("copy constraint non soddisfatti")
+	}
+
+	z := pk.toCoefficients(zL)
+	z = append(z, fr.Element{}, fr.Element{}, fr.Element{})
+	z[0].Sub(&z[0], &blinding[9])
+	z[1].Sub(&z[1], &blinding[8])
+	z[2].Sub(&z[2], &blinding[7])
+	z[n].Add(&z[n], &blinding[9])
+	z[n+1].Add(&z[n+1], &blinding[8])
+	z[n+2].Add(&z[n+2], &blinding[7])
+	if proof.Z, err = pk.commit(z); err != nil {
+		return nil, nil, err
+	}
+
+	t.reset()
+	t.addScalar(&beta, &gamma)
+	t.addPoint(&proof.Z)
+	alpha := t.challenge()
+
+	// 3. round 3: quoziente t(X)
+	piL := make([]fr.Element, n)
+	for i := range publicSignals {
+		piL[i].Neg(&publicSignals[i])
+	}
+	pi := pk.toCoefficients(piL)
+
+	tCoeffs, err := pk.quotient(a, b, c, z, pi, alpha, beta, gamma)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t1 := make([]fr.Element, n+1)
+	t2 := make([]fr.Element, n+1)
+	t3 := make([]fr.Element, n+6)
+	copy(t1, tCoeffs[:n])
+	copy(t2, tCoeffs[n:2*n])
+	copy(t3, tCoeffs[2*n:3*n+6])
+	t1[n].Add(&t1[n], &blinding[10])
+	t2[0].Sub(&t2[0], &blinding[10])
+	t2[n].Add(&t2[n], &blinding[11])
+	t3[0].Sub(&t3[0], &blinding[11])
+	if proof.T1, err = pk.commit(t1); err != nil {
+		return nil, nil, err
+	}
+	if proof.T2, err = pk.commit(t2); err != nil {
+		return nil, nil, err
+	}
+	if proof.T3, err = pk.commit(t3); err != nil {
+		return nil, nil, err
+	}
+
+	t.reset()
+	t.addScalar(&alpha)
+	t.addPoint(&proof.T1, &proof.T2, &proof.T3)
+	xi := t.challenge()
+
+	// 4. round 4: valutazioni in ξ e ξω
+	var xiw fr.Element
+	xiw.Mul(&xi, &pk.domain.Generator)
+	proof.EvalA = evaluate(a, &xi)
+	proof.EvalB = evaluate(b, &xi)
+	proof.EvalC = evaluate(c, &xi)
+	proof.EvalS1 = evaluate(pk.s1, &xi)
+	proof.EvalS2 = evaluate(pk.s2, &xi)
+	proof.EvalZw = evaluate(z, &xiw)
+
+	t.reset()
+	t.addScalar(&xi, &proof.EvalA, &proof.EvalB, &proof.EvalC, &proof.EvalS1, &proof.EvalS2, &proof.EvalZw)
+	var v [6]fr.Element
+	v[1] = t.challenge()
+	for i := 2; i < 6; i++ {
+		v[i].Mul(&v[i-1], &v[1])
+	}
+
+	// 5. round 5: polinomio di linearizzazione e aperture
+	ch := challenges{beta: beta, gamma: gamma, alpha: alpha, xi: xi}
+	ch.lagrange(vk)
+	piXi := ch.pi(publicSignals)
+	r0 := ch.r0(proof, piXi)
+
+	var coefZ, coefS3, tmp, betaXi fr.Element
+	betaXi.Mul(&beta, &xi)
+	coefZ = linearTerm(&proof.EvalA, &betaXi, &gamma)
+	tmp.Mul(&betaXi, &vk.K1)
+	tmp = linearTerm(&proof.EvalB, &tmp, &gamma)
+	coefZ.Mul(&coefZ, &tmp)
+	tmp.Mul(&betaXi, &vk.K2)
+	tmp = linearTerm(&proof.EvalC, &tmp, &gamma)
+	coefZ.Mul(&coefZ, &tmp).Mul(&coefZ, &alpha)
+	tmp.Square(&alpha).Mul(&tmp, &ch.l1)
+	coefZ.Add(&coefZ, &tmp)
+
+	tmp.Mul(&beta, &proof.EvalS1)
+	coefS3 = linearTerm(&proof.EvalA, &tmp, &gamma)
+	tmp.Mul(&beta, &proof.EvalS2)
+	tmp = linearTerm(&proof.EvalB, &tmp, &gamma)
+	coefS3.Mul(&coefS3, &tmp).Mul(&coefS3, &alpha).Mul(&coefS3, &beta).Mul(&coefS3, &proof.EvalZw)
+
+	var ab, xin2 fr.Element
+	ab.Mul(&proof.EvalA, &proof.EvalB)
+	xin2.Square(&ch.xin)
+
+	r := make([]fr.Element, n+6)
+	addScaled(r, pk.qm, &ab)
+	addScaled(r, pk.ql, &proof.EvalA)
+	addScaled(r, pk.qr, &proof.EvalB)
+	addScaled(r, pk.qo, &proof.EvalC)
+	addScaled(r, pk.qc, nil)
+	addScaled(r, z, &coefZ)
+	coefS3.Neg(&coefS3)
+	addScaled(r, pk.s3, &coefS3)
+	var negZh, negZhXin, negZhXin2 fr.Element
+	negZh.Neg(&ch.zh)
+	negZhXin.Mul(&negZh, &ch.xin)
+	negZhXin2.Mul(&negZh, &xin2)
+	addScaled(r, t1, &negZh)
+	addScaled(r, t2, &negZhXin)
+	addScaled(r, t3, &negZhXin2)
+
+	rXi := evaluate(r, &xi)
+	rXi.Add(&rXi, &r0)
+	if !rXi.IsZero() {
+		return nil, nil, fmt.Errorf("linearizzazione non valida: witness non soddisfa il circuito")
+	}
+
+	// W_ξ = (r + r0 + Σ vⁱ·(pᵢ - p̄ᵢ)) / (X - ξ)
+	w := r
+	w[0].Add(&w[0], &r0)
+	for i, p := range []struct {
+		poly []fr.Element
+		eval *fr.Element
+	}{
+		{a, &proof.EvalA}, {b, &proof.EvalB}, {c, &proof.EvalC}, {pk.s1, &proof.EvalS1}, {pk.s2, &proof.EvalS2},
+	} {
+		addScaled(w, p.poly, &v[i+1])
+		tmp.Mul(&v[i+1], p.eval)
+		w[0].Sub(&w[0], &tmp)
+	}
+	if proof.Wxi, err = pk.commit(divideByLinear(w, &xi)); err != nil {
+		return nil, nil, err
+	}
+
+	wz := make([]fr.Element, len(z))
+	copy(wz, z)
+	wz[0].Sub(&wz[0], &proof.EvalZw)
+	if proof.Wxiw, err = pk.commit(divideByLinear(wz, &xiw)); err != nil {
+		return nil, nil, err
+	}
+
+	return proof, publicSignals, nil
+}
+
+// quotient calcola t(X) = [gate + PI + α·perm + α²·(z-1)·L₁] / Z_H.
+//
+// Il numeratore ha grado 4n+5 per via del blinding, quindi serve un coset di 8n
+// punti. Per non tenere in memoria 15 vettori di 8n elementi il coset viene
+// visitato come 8 coset di H di dimensione n: su s·H vale Z_H(x) = sⁿ - 1.
+func (pk *ProvingKey) quotient(a, b, c, z, pi []fr.Element, alpha, beta, gamma fr.Element) ([]fr.Element, error) {
+	n := int(pk.domain.Cardinality)
+	const ratio = 8
+	coset := fft.NewDomain(uint64(ratio * n))
+	vk := &pk.Vk
+
+	// L₁(X) ha tutti i coefficienti pari a 1/n
+	l1 := make([]fr.Element, n)
+	for i := range l1 {
+		l1[i] = pk.domain.CardinalityInv
+	}
+
+	var alpha2 fr.Element
+	alpha2.Square(&alpha)
+
+	res := make([]fr.Element, ratio*n)
+	shift := coset.FrMultiplicativeGen
+	for j := 0; j < ratio; j++ {
+		// i punti s·ωₙᵏ sono gli indici j + ratio·k del coset grande
+		ea, eb, ec, ez := pk.evalOnCoset(a, &shift), pk.evalOnCoset(b, &shift), pk.evalOnCoset(c, &shift), pk.evalOnCoset(z, &shift)
+		eqm, eql, eqr, eqo, eqc := pk.evalOnCoset(pk.qm, &shift), pk.evalOnCoset(pk.ql, &shift), pk.evalOnCoset(pk.qr, &shift), pk.evalOnCoset(pk.qo, &shift), pk.evalOnCoset(pk.qc, &shift)
+		es1, es2, es3 := pk.evalOnCoset(pk.s1, &shift), pk.evalOnCoset(pk.s2, &shift), pk.evalOnCoset(pk.s3, &shift)
+		epi, el1 := pk.evalOnCoset(pi, &shift), pk.evalOnCoset(l1, &shift)
+
+		var zhInv fr.Element
+		zhInv.Exp(shift, bigInt(n))
+		zhInv.Sub(&zhInv, new(fr.Element).SetOne()).Inverse(&zhInv)
+
+		x := shift
+		for k := 0; k < n; k++ {
+			var gate, tmp, num, den, bx fr.Element
+
+			gate.Mul(&ea[k], &eb[k]).Mul(&gate, &eqm[k])
+			tmp.Mul(&ea[k], &eql[k])
+			gate.Add(&gate, &tmp)
+			tmp.Mul(&eb[k], &eqr[k])
+			gate.Add(&gate, &tmp)
+			tmp.Mul(&ec[k], &eqo[k])
+			gate.Add(&gate, &tmp)
+			gate.Add(&gate, &eqc[k]).Add(&gate, &epi[k])
+
+			bx.Mul(&beta, &x)
+			num = linearTerm(&ea[k], &bx, &gamma)
+			tmp.Mul(&bx, &vk.K1)
+			tmp = linearTerm(&eb[k], &tmp, &gamma)
+			num.Mul(&num, &tmp)
+			tmp.Mul(&bx, &vk.K2)
+			tmp = linearTerm(&ec[k], &tmp, &gamma)
+			num.Mul(&num, &tmp).Mul(&num, &ez[k])
+
+			// z(ωx) è il punto successivo dello stesso coset
+			tmp.Mul(&beta, &es1[k])
+			den = linearTerm(&ea[k], &tmp, &gamma)
+			tmp.Mul(&beta, &es2[k])
+			tmp = linearTerm(&eb[k], &tmp, &gamma)
+			den.Mul(&den, &tmp)
+			tmp.Mul(&beta, &es3[k])
+			tmp = linearTerm(&ec[k], &tmp, &gamma)
+			den.Mul(&den, &tmp).Mul(&den, &ez[(k+1)%n])
+
+			num.Sub(&num, &den).Mul(&num, &alpha)
+
+			tmp.SetOne()
+			tmp.Sub(&ez[k], &tmp).Mul(&tmp, &el1[k]).Mul(&tmp, &alpha2)
+
+			r := &res[j+ratio*k]
+			r.Add(&gate, &num).Add(r, &tmp).Mul(r, &zhInv)
+			x.Mul(&x, &pk.domain.Generator)
+		}
+		shift.Mul(&shift, &coset.Generator)
+	}
+
+	coset.FFTInverse(res, fft.DIF, fft.OnCoset())
+	fft.BitReverse(res)
+
+	for i := 3*n + 6; i < len(res); i++ {
+		if !res[i].IsZero() {
+			return nil, fmt.Errorf("quoziente di grado troppo alto: witness non soddisfa il circuito")
+		}
+	}
+	return res, nil
+}
+
+// evalOnCoset valuta p sui punti s·ωₙᵏ. Su s·H vale xⁿ = sⁿ, quindi i
+// coefficienti oltre n si ripiegano sui primi n prima della FFT.
+func (pk *ProvingKey) evalOnCoset(p []fr.Element, shift *fr.Element) []fr.Element {
+	n := int(pk.domain.Cardinality)
+	q := make([]fr.Element, n)
+	var sn, acc, tmp fr.Element
+	sn.Exp(*shift, bigInt(n))
+	acc.SetOne()
+	for i := 0; i < len(p); i += n {
+		for k := i; k < min(i+n, len(p)); k++ {
+			tmp.Mul(&p[k], &acc)
+			q[k-i].Add(&q[k-i], &tmp)
+		}
+		acc.Mul(&acc, &sn)
+	}
+	acc.SetOne()
+	for i := range q {
+		q[i].Mul(&q[i], &acc)
+		acc.Mul(&acc, shift)
+	}
+	pk.domain.FFT(q, fft.DIF)
+	fft.BitReverse(q)
+	return q
+}
+
+// blind aggiunge (hi·X + lo)·Z_H a p.
+func blind(p []fr.Element, lo, hi fr.Element) []fr.Element {
+	n := len(p)
+	p = append(p, fr.Element{}, fr.Element{})
+	p[0].Sub(&p[0], &lo)
+	p[1].Sub(&p[1], &hi)
+	p[n].Add(&p[n], &lo)
+	p[n+1].Add(&p[n+1], &hi)
+	return p
+}
+
+// linearTerm restituisce v + s + γ.
+func linearTerm(v, s, gamma *fr.Element) fr.Element {
+	var res fr.Element
+	res.Add(v, s).Add(&res, gamma)
+	return res
+}
+
+// addScaled somma s·p in dst (s nil vale 1).
+func addScaled(dst, p []fr.Element, s *fr.Element) {
+	for i := range p {
+		if s == nil {
+			dst[i].Add(&dst[i], &p[i])
+			continue
+		}
+		var tmp fr.Element
+		tmp.Mul(&p[i], s)
+		dst[i].Add(&dst[i], &tmp)
+	}
+}
+
+func evaluate(p []fr.Element, x *fr.Element) fr.Element {
+	var res fr.Element
+	for i := len(p) - 1; i >= 0; i-- {
+		res.Mul(&res, x).Add(&res, &p[i])
+	}
+	return res
+}
+
+// divideByLinear restituisce p(X) / (X - z) trascurando il resto.
+func divideByLinear(p []fr.Element, z *fr.Element) []fr.Element {
+	q := make([]fr.Element, len(p)-1)
+	var carry fr.Element
+	for i := len(p) - 1; i > 0; i-- {
+		carry.Mul(&carry, z).Add(&carry, &p[i])
+		q[i-1] = carry
+	}
+	return q
+}
+
+func bigInt(v int) *big.Int {
+	return big.NewInt(int64(v))
+}
@@ -0,0 +1,265 @@
+package snarkjsplonk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// Lettura dei file plonk di snarkjs, il verso opposto di Export: serve a
+// verificare in Go le prove prodotte da snarkjs stesso.
+
+type vkJSON struct {
+	Protocol string     `json:"protocol"`
+	Curve    string     `json:"curve"`
+	NPublic  int        `json:"nPublic"`
+	Power    int        `json:"power"`
+	K1       string     `json:"k1"`
+	K2       string     `json:"k2"`
+	Qm       []string   `json:"Qm"`
+	Ql       []string   `json:"Ql"`
+	Qr       []string   `json:"Qr"`
+	Qo       []string   `json:"Qo"`
+	Qc       []string   `json:"Qc"`
+	S1       []string   `json:"S1"`
+	S2       []string   `json:"S2"`
+	S3       []string   `json:"S3"`
+	X2       [][]string `json:"X_2"`
+	W        string     `json:"w"`
+}
+
+type proofJSON struct {
+	Protocol string   `json:"protocol"`
+	Curve    string   `json:"curve"`
+	A        []string `json:"A"`
+	B        []string `json:"B"`
+	C        []string `json:"C"`
+	Z        []string `json:"Z"`
+	T1       []string `json:"T1"`
+	T2       []string `json:"T2"`
+	T3       []string `json:"T3"`
+	Wxi      []string `json:"Wxi"`
+	Wxiw     []string `json:"Wxiw"`
+	EvalA    string   `json:"eval_a"`
+	EvalB    string   `json:"eval_b"`
+	EvalC    string   `json:"eval_c"`
+	EvalS1   string   `json:"eval_s1"`
+	EvalS2   string   `json:"eval_s2"`
+	EvalZw   string   `json:"eval_zw"`
+}
+
+func checkHeader(protocol, curve string) error {
+	if protocol != "plonk" {
+		return fmt.Errorf("protocollo %q, atteso plonk", protocol)
+	}
+	if curve != "bn128" {
+		return fmt.Errorf("curva %q, il plonk di snarkjs è solo bn128", curve)
+	}
+	return nil
+}
+
+// ReadVerifyingKey legge verification_key.json.
+func ReadVerifyingKey(path string) (*VerifyingKey, error) {
+	var raw vkJSON
+	if err := readJSON(path, &raw); err != nil {
+		return nil, err
+	}
+	if err := checkHeader(raw.Protocol, raw.Curve); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if raw.Power < 1 || raw.Power > 28 {
+		return nil, fmt.Errorf("%s: power %d fuori range", path, raw.Power)
+	}
+
+	vk := &VerifyingKey{NPublic: raw.NPublic, Power: raw.Power}
+	var err error
+	for _, s := range []struct {
+		name string
+		raw  string
+		dst  *fr.Element
+	}{{"k1", raw.K1, &vk.K1}, {"k2", raw.K2, &vk.K2}, {"w", raw.W, &vk.W}} {
+		if *s.dst, err = parseFr(s.raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.name, err)
+		}
+	}
+	for _, p := range []struct {
+		name string
+		raw  []string
+		dst  *bn254.G1Affine
+	}{
+		{"Qm", raw.Qm, &vk.Qm}, {"Ql", raw.Ql, &vk.Ql}, {"Qr", raw.Qr, &vk.Qr}, {"Qo", raw.Qo, &vk.Qo}, {"Qc", raw.Qc, &vk.Qc},
+		{"S1", raw.S1, &vk.S1}, {"S2", raw.S2, &vk.S2}, {"S3", raw.S3, &vk.S3},
+	} {
+		if *p.dst, err = parseG1(p.raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", p.name, err)
+		}
+	}
+	if vk.X2, err = parseG2(raw.X2); err != nil {
+		return nil, fmt.Errorf("X_2: %w", err)
+	}
+	return vk, nil
+}
+
+// ReadProof legge proof.json.
+func ReadProof(path string) (*Proof, error) {
+	var raw proofJSON
+	if err := readJSON(path, &raw); err != nil {
+		return nil, err
+	}
+	if err := checkHeader(raw.Protocol, raw.Curve); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	p := &Proof{}
+	var err error
+	for _, c := range []struct {
+		name string
+		raw  []string
+		dst  *bn254.G1Affine
+	}{
+		{"A", raw.A, &p.A}, {"B", raw.B, &p.B}, {"C", raw.C, &p.C}, {"Z", raw.Z, &p.Z},
+		{"T1", raw.T1, &p.T1}, {"T2", raw.T2, &p.T2}, {"T3", raw.T3, &p.T3},
+		{"Wxi", raw.Wxi, &p.Wxi}, {"Wxiw", raw.Wxiw, &p.Wxiw},
+	} {
+		if *c.dst, err = parseG1(c.raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.name, err)
+		}
+	}
+	for _, e := range []struct {
+		name string
+		raw  string
+		dst  *fr.Element
+	}{
+		{"eval_a", raw.EvalA, &p.EvalA}, {"eval_b", raw.EvalB, &p.EvalB}, {"eval_c", raw.EvalC, &p.EvalC},
+		{"eval_s1", raw.EvalS1, &p.EvalS1}, {"eval_s2", raw.EvalS2, &p.EvalS2}, {"eval_zw", raw.EvalZw, &p.EvalZw},
+	} {
+		if *e.dst, err = parseFr(e.raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", e.name, err)
+		}
+	}
+	return p, nil
+}
+
+// ReadPublic legge public.json.
+func ReadPublic(path string) ([]fr.Element, error) {
+	var raw []string
+	if err := readJSON(path, &raw); err != nil {
+		return nil, err
+	}
+	res := make([]fr.Element, len(raw))
+	for i, s := range raw {
+		var err error
+		if res[i], err = parseFr(s); err != nil {
+			return nil, fmt.Errorf("%s: segnale %d: %w", path, i, err)
+		}
+	}
+	return res, nil
+}
+
+// Read legge i tre file scritti da Export (o da snarkjs) in dir.
+func Read(dir string) (*VerifyingKey, *Proof, []fr.Element, error) {
+	vk, err := ReadVerifyingKey(filepath.Join(dir, "verification_key.json"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	proof, err := ReadProof(filepath.Join(dir, "proof.json"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	public, err := ReadPublic(filepath.Join(dir, "public.json"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return vk, proof, public, nil
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// parseG1 legge [x, y, 1]; snarkjs scrive [0, 1, 0] per il punto all'infinito.
+func parseG1(c []string) (bn254.G1Affine, error) {
+	var p bn254.G1Affine
+	if len(c) != 3 {
+		return p, fmt.Errorf("punto G1 con %d coordinate", len(c))
+	}
+	var coords [3]fp.Element
+	for i := range coords {
+		var err error
+		if coords[i], err = parseFp(c[i]); err != nil {
+			return p, err
+		}
+	}
+	if coords[2].IsZero() {
+		return p, nil
+	}
+	if !coords[2].IsOne() {
+		return p, errors.New("punto G1 non normalizzato (z ≠ 1)")
+	}
+	p.X, p.Y = coords[0], coords[1]
+	if !p.IsOnCurve() {
+		return p, errors.New("punto G1 non sulla curva")
+	}
+	return p, nil
+}
+
+// parseG2 legge [[x.c0, x.c1], [y.c0, y.c1], [1, 0]], con z = 0 all'infinito.
+func parseG2(c [][]string) (bn254.G2Affine, error) {
+	var p bn254.G2Affine
+	if len(c) != 3 || len(c[0]) != 2 || len(c[1]) != 2 || len(c[2]) != 2 {
+		return p, errors.New("punto G2 malformato")
+	}
+	var coords [6]fp.Element
+	for i := range coords {
+		var err error
+		if coords[i], err = parseFp(c[i/2][i%2]); err != nil {
+			return p, err
+		}
+	}
+	if coords[4].IsZero() && coords[5].IsZero() {
+		return p, nil
+	}
+	if !coords[4].IsOne() || !coords[5].IsZero() {
+		return p, errors.New("punto G2 non normalizzato (z ≠ 1)")
+	}
+	p.X.A0, p.X.A1 = coords[0], coords[1]
+	p.Y.A0, p.Y.A1 = coords[2], coords[3]
+	if !p.IsOnCurve() || !p.IsInSubGroup() {
+		return p, errors.New("punto G2 non nel sottogruppo")
+	}
+	return p, nil
+}
+
+func parseFp(s string) (fp.Element, error) {
+	var e fp.Element
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 || v.Cmp(fp.Modulus()) >= 0 {
+		return e, fmt.Errorf("coordinata non valida %q", s)
+	}
+	e.SetBigInt(v)
+	return e, nil
+}
+
+func parseFr(s string) (fr.Element, error) {
+	var e fr.Element
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 || v.Cmp(fr.Modulus()) >= 0 {
+		return e, fmt.Errorf("non è un elemento di Fr: %q", s)
+	}
+	e.SetBigInt(v)
+	return e, nil
+}
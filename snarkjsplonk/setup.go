@@ -0,0 +1,213 @@
+// Package snarkjsplonk produce prove PLONK verificabili da snarkjs (plonk.verify).
+//
+// Il PLONK di gnark e quello di snarkjs non sono compatibili a livello di prova:
+// cambiano transcript (sha256 vs keccak256), coset della permutazione (u, u² vs
+// k1=2, k2=3), linearizzazione e aperture KZG. Per questo non basta serializzare
+// una plonk.Proof di gnark: il package riusa il constraint system sparse
+// compilato da gnark e l'SRS KZG universale, ma esegue setup e prover secondo il
+// protocollo di snarkjs, così che proof.json e verification_key.json siano
+// accettati da snarkjs.
+package snarkjsplonk
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bn254/kzg"
+	"github.com/consensys/gnark/constraint"
+	cs_bn254 "github.com/consensys/gnark/constraint/bn254"
+)
+
+// VerifyingKey rispecchia il verification_key.json plonk di snarkjs.
+type VerifyingKey struct {
+	NPublic int
+	Power   int
+	K1, K2  fr.Element
+
+	Qm, Ql, Qr, Qo, Qc bn254.G1Affine
+	S1, S2, S3         bn254.G1Affine
+
+	X2 bn254.G2Affine // [τ]₂
+	W  fr.Element     // radice dell'unità di ordine 2^Power
+}
+
+// ProvingKey contiene il trace del circuito nel layout di snarkjs: prima una riga
+// per ogni input pubblico, poi i vincoli di gnark, una riga di riempimento e il
+// padding fino a una potenza di 2.
+type ProvingKey struct {
+	Vk  VerifyingKey
+	Kzg kzg.ProvingKey
+
+	ccs    *cs_bn254.SparseR1CS
+	domain *fft.Domain
+
+	nbPublic, nbConstraints int
+
+	// selettori e permutazione in forma di coefficienti
+	qm, ql, qr, qo, qc []fr.Element
+	s1, s2, s3         []fr.Element
+
+	// permutazione in forma di Lagrange, serve per z
+	sigma [3][]fr.Element
+}
+
+// Size restituisce il numero di punti G1 dell'SRS richiesti dal circuito.
+func Size(ccs constraint.ConstraintSystem) int {
+	// t_hi ha grado n+5 a causa del blinding
+	return int(domainSize(ccs)) + 6
+}
+
+// domainSize conta righe pubbliche, vincoli e la riga di riempimento; almeno 8
+// righe così i termini di blinding non si sovrappongono.
+func domainSize(ccs constraint.ConstraintSystem) uint64 {
+	return max(8, ecc.NextPowerOfTwo(uint64(ccs.GetNbPublicVariables()+ccs.GetNbConstraints()+1)))
+}
+
+// Setup calcola selettori e permutazione del circuito e li committa con l'SRS.
+func Setup(ccs constraint.ConstraintSystem, srs *kzg.SRS) (*ProvingKey, error) {
+	spr, ok := ccs.(*cs_bn254.SparseR1CS)
+	if !ok {
+		return nil, fmt.Errorf("snarkjs plonk supporta solo sparse R1CS BN254, non %T", ccs)
+	}
+	if len(spr.GetCommitments().CommitmentIndexes()) > 0 {
+		return nil, errors.New("snarkjs plonk non supporta circuiti con commitment BSB22")
+	}
+	if len(srs.Pk.G1) < Size(ccs) {
+		return nil, fmt.Errorf("SRS troppo piccolo: %d punti, servono %d", len(srs.Pk.G1), Size(ccs))
+	}
+
+	constraints := spr.GetSparseR1Cs()
+	pk := &ProvingKey{
+		ccs:           spr,
+		nbPublic:      len(spr.Public),
+		nbConstraints: len(constraints),
+	}
+	n := domainSize(ccs)
+	pk.domain = fft.NewDomain(n)
+	pk.Kzg.G1 = srs.Pk.G1[:Size(ccs)]
+
+	vk := &pk.Vk
+	vk.NPublic = pk.nbPublic
+	vk.Power = bits.TrailingZeros64(n)
+	vk.K1.SetUint64(2)
+	vk.K2.SetUint64(3)
+	vk.X2 = srs.Vk.G2[1]
+	vk.W = pk.domain.Generator
+
+	// 1. selettori in forma di Lagrange
+	ql := make([]fr.Element, n)
+	qr := make([]fr.Element, n)
+	qo := make([]fr.Element, n)
+	qm := make([]fr.Element, n)
+	qc := make([]fr.Element, n)
+
+	// wires[col][row] = id della variabile gnark, -1 se la cella non è collegata
+	var wires [3][]int64
+	for col := range wires {
+		wires[col] = make([]int64, n)
+		for row := range wires[col] {
+			wires[col][row] = -1
+		}
+	}
+
+	// righe pubbliche: a - w = 0, il termine w arriva da PI(X)
+	for i := 0; i < pk.nbPublic; i++ {
+		ql[i].SetOne()
+		wires[0][i] = int64(i)
+	}
+	for j, c := range constraints {
+		row := pk.nbPublic + j
+		ql[row] = spr.Coefficients[c.QL]
+		qr[row] = spr.Coefficients[c.QR]
+		qo[row] = spr.Coefficients[c.QO]
+		qm[row] = spr.Coefficients[c.QM]
+		qc[row] = spr.Coefficients[c.QC]
+		wires[0][row] = int64(c.XA)
+		wires[1][row] = int64(c.XB)
+		wires[2][row] = int64(c.XC)
+	}
+	// riga di riempimento con tutti i selettori a 1 (a=1, b=1, c=-4), così nessun
+	// commitment dei selettori è il punto all'infinito
+	filler := pk.nbPublic + pk.nbConstraints
+	ql[filler].SetOne()
+	qr[filler].SetOne()
+	qo[filler].SetOne()
+	qm[filler].SetOne()
+	qc[filler].SetOne()
+
+	// 2. permutazione: le celle con la stessa variabile formano un ciclo
+	cycles := make(map[int64][]int)
+	for col := range wires {
+		for row, w := range wires[col] {
+			if w >= 0 {
+				cycles[w] = append(cycles[w], col*int(n)+row)
+			}
+		}
+	}
+	next := make([]int, 3*n)
+	for i := range next {
+		next[i] = i
+	}
+	for _, cells := range cycles {
+		for i, cell := range cells {
+			next[cell] = cells[(i+1)%len(cells)]
+		}
+	}
+
+	ks := [3]fr.Element{}
+	ks[0].SetOne()
+	ks[1] = vk.K1
+	ks[2] = vk.K2
+	omegas := make([]fr.Element, n)
+	fft.BuildExpTable(pk.domain.Generator, omegas)
+
+	for col := range pk.sigma {
+		pk.sigma[col] = make([]fr.Element, n)
+		for row := 0; row < int(n); row++ {
+			target := next[col*int(n)+row]
+			tcol, trow := target/int(n), target%int(n)
+			pk.sigma[col][row].Mul(&ks[tcol], &omegas[trow])
+		}
+	}
+
+	// 3. coefficienti e commitment
+	pk.qm, pk.ql, pk.qr, pk.qo, pk.qc = pk.toCoefficients(qm), pk.toCoefficients(ql), pk.toCoefficients(qr), pk.toCoefficients(qo), pk.toCoefficients(qc)
+	pk.s1 = pk.toCoefficients(pk.sigma[0])
+	pk.s2 = pk.toCoefficients(pk.sigma[1])
+	pk.s3 = pk.toCoefficients(pk.sigma[2])
+
+	var err error
+	commitments := []struct {
+		dst *bn254.G1Affine
+		p   []fr.Element
+	}{
+		{&vk.Qm, pk.qm}, {&vk.Ql, pk.ql}, {&vk.Qr, pk.qr}, {&vk.Qo, pk.qo}, {&vk.Qc, pk.qc},
+		{&vk.S1, pk.s1}, {&vk.S2, pk.s2}, {&vk.S3, pk.s3},
+	}
+	for _, c := range commitments {
+		if *c.dst, err = pk.commit(c.p); err != nil {
+			return nil, err
+		}
+	}
+
+	return pk, nil
+}
+
+// toCoefficients converte valori sul dominio (ordine naturale) in coefficienti.
+func (pk *ProvingKey) toCoefficients(lagrange []fr.Element) []fr.Element {
+	p := make([]fr.Element, len(lagrange))
+	copy(p, lagrange)
+	pk.domain.FFTInverse(p, fft.DIF)
+	fft.BitReverse(p)
+	return p
+}
+
+func (pk *ProvingKey) commit(p []fr.Element) (bn254.G1Affine, error) {
+	d, err := kzg.Commit(p, pk.Kzg)
+	return bn254.G1Affine(d), err
+}
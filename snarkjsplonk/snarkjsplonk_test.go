@@ -0,0 +1,122 @@
+package snarkjsplonk
+
+import (
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/kzg"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+)
+
+// go test ./snarkjsplonk -update riscrive testdata/gnark con una prova nuova;
+// poi va rieseguito testdata/snarkjs.sh per registrare il verify di snarkjs.
+var update = flag.Bool("update", false, "rigenera testdata/gnark")
+
+// cubicCircuit è il circuito degli esempi di gnark e snarkjs: x³ + x + 5 = y.
+type cubicCircuit struct {
+	X frontend.Variable `gnark:",secret"`
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *cubicCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, 5))
+	return nil
+}
+
+// prove genera con il package una prova di x = 3, y = 35 su un SRS di sviluppo.
+func prove(t *testing.T) (*ProvingKey, *Proof, []fr.Element) {
+	t.Helper()
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srs, err := kzg.NewSRS(uint64(Size(ccs)), big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := Setup(ccs, srs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, public, err := Prove(pk, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pk, proof, public
+}
+
+// checkFixture verifica la prova in dir e controlla che venga rifiutata con un
+// segnale pubblico alterato.
+func checkFixture(t *testing.T, dir string) {
+	t.Helper()
+	vk, proof, public, err := Read(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(vk, proof, public); err != nil {
+		t.Fatalf("%s: %v", dir, err)
+	}
+	bad := make([]fr.Element, len(public))
+	copy(bad, public)
+	bad[0].SetUint64(9999)
+	if Verify(vk, proof, bad) == nil {
+		t.Fatalf("%s: accettata con un segnale pubblico alterato", dir)
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	pk, proof, public := prove(t)
+	if err := Verify(&pk.Vk, proof, public); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if *update {
+		// l'esito di snarkjs registrato era per la prova vecchia
+		dir = filepath.Join("testdata", "gnark")
+		os.Remove(filepath.Join(dir, "snarkjs_verify.txt"))
+	}
+	if err := Export(dir, proof, &pk.Vk, public); err != nil {
+		t.Fatal(err)
+	}
+	checkFixture(t, dir)
+}
+
+// La prova esportata dal package, con l'esito di snarkjs plonk verify
+// registrato da testdata/snarkjs.sh in snarkjs_verify.txt.
+func TestGnarkFixture(t *testing.T) {
+	dir := filepath.Join("testdata", "gnark")
+	checkFixture(t, dir)
+
+	out, err := os.ReadFile(filepath.Join(dir, "snarkjs_verify.txt"))
+	if os.IsNotExist(err) {
+		t.Fatal("snarkjs_verify.txt mancante: senza l'esito di snarkjs la compatibilità non è dimostrata, eseguire testdata/snarkjs.sh (serve node)")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "OK!") {
+		t.Fatalf("snarkjs plonk verify non ha accettato la prova:\n%s", out)
+	}
+}
+
+// Verification key, prova e public.json prodotti da snarkjs stesso (plonk
+// setup/prove sul circuito cubic.circom), letti e verificati in Go.
+func TestSnarkJSFixture(t *testing.T) {
+	dir := filepath.Join("testdata", "snarkjs")
+	if _, err := os.Stat(filepath.Join(dir, "proof.json")); os.IsNotExist(err) {
+		t.Fatal("testdata/snarkjs mancante: eseguire testdata/snarkjs.sh (serve node)")
+	}
+	checkFixture(t, dir)
+}
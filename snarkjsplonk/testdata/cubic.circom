@@ -0,0 +1,15 @@
+pragma circom 2.0.0;
+
+// stesso circuito di cubicCircuit in snarkjsplonk_test.go: x³ + x + 5 = y
+template Cubic() {
+    signal input x;
+    signal output y;
+    signal x2;
+    signal x3;
+
+    x2 <== x * x;
+    x3 <== x2 * x;
+    y <== x3 + x + 5;
+}
+
+component main = Cubic();
@@ -0,0 +1,34 @@
+#!/bin/sh
+# Fixture di compatibilità con snarkjs per snarkjsplonk_test.go:
+#   - snarkjs/: verification key, prova e public.json prodotti da snarkjs stesso
+#     (plonk setup e prove su cubic.circom), che il verificatore Go deve accettare
+#   - gnark/snarkjs_verify.txt: l'esito di snarkjs plonk verify sulla prova
+#     esportata dal package in gnark/ (go test ./snarkjsplonk -update)
+# Serve node con npm e accesso al registry.
+set -e
+cd "$(dirname "$0")"
+work=$(mktemp -d)
+trap 'rm -rf "$work"' EXIT
+
+npm install --silent --prefix "$work" snarkjs@^0.7.6 circom2
+bin="$work/node_modules/.bin"
+
+"$bin/circom2" cubic.circom --r1cs --wasm -o "$work"
+"$bin/snarkjs" powersoftau new bn128 8 "$work/pot_0.ptau"
+"$bin/snarkjs" powersoftau contribute "$work/pot_0.ptau" "$work/pot_1.ptau" -e="snarkjsplonk testdata"
+"$bin/snarkjs" powersoftau prepare phase2 "$work/pot_1.ptau" "$work/pot.ptau"
+"$bin/snarkjs" plonk setup "$work/cubic.r1cs" "$work/pot.ptau" "$work/cubic.zkey"
+echo '{"x": "3"}' > "$work/input.json"
+"$bin/snarkjs" wtns calculate "$work/cubic_js/cubic.wasm" "$work/input.json" "$work/witness.wtns"
+
+mkdir -p snarkjs
+"$bin/snarkjs" plonk prove "$work/cubic.zkey" "$work/witness.wtns" snarkjs/proof.json snarkjs/public.json
+"$bin/snarkjs" zkey export verificationkey "$work/cubic.zkey" snarkjs/verification_key.json
+"$bin/snarkjs" plonk verify snarkjs/verification_key.json snarkjs/public.json snarkjs/proof.json
+
+version=$(node -p "require('$work/node_modules/snarkjs/package.json').version")
+{
+    echo "snarkjs $version plonk verify gnark/verification_key.json gnark/public.json gnark/proof.json"
+    "$bin/snarkjs" plonk verify gnark/verification_key.json gnark/public.json gnark/proof.json 2>&1
+} > gnark/snarkjs_verify.txt || { cat gnark/snarkjs_verify.txt; exit 1; }
+cat gnark/snarkjs_verify.txt
@@ -0,0 +1,235 @@
+package snarkjsplonk
+
+import (
+	"errors"
+	"hash"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"golang.org/x/crypto/sha3"
+)
+
+// transcript replica Keccak256Transcript di snarkjs: punti G1 non compressi
+// big-endian (x‖y) e scalari big-endian su 32 byte, challenge = keccak256 mod r.
+type transcript struct {
+	h hash.Hash
+}
+
+func newTranscript() *transcript {
+	return &transcript{h: sha3.NewLegacyKeccak256()}
+}
+
+func (t *transcript) reset() {
+	t.h.Reset()
+}
+
+func (t *transcript) addPoint(points ...*bn254.G1Affine) {
+	for _, p := range points {
+		x, y := p.X.Bytes(), p.Y.Bytes()
+		t.h.Write(x[:])
+		t.h.Write(y[:])
+	}
+}
+
+func (t *transcript) addScalar(scalars ...*fr.Element) {
+	for _, s := range scalars {
+		b := s.Bytes()
+		t.h.Write(b[:])
+	}
+}
+
+// challenge restituisce la challenge e azzera il transcript, come fa snarkjs
+// prima di ogni round.
+func (t *transcript) challenge() fr.Element {
+	var res fr.Element
+	res.SetBigInt(new(big.Int).SetBytes(t.h.Sum(nil)))
+	t.h.Reset()
+	return res
+}
+
+type challenges struct {
+	beta, gamma, alpha, xi, u fr.Element
+	v                         [6]fr.Element
+
+	xin, zh fr.Element   // ξⁿ e Z_H(ξ)
+	l       []fr.Element // l[i] = Lᵢ(ξ), i ≥ 1
+	l1      fr.Element
+}
+
+func deriveChallenges(vk *VerifyingKey, proof *Proof, publicSignals []fr.Element) challenges {
+	var ch challenges
+	t := newTranscript()
+	t.addPoint(&vk.Qm, &vk.Ql, &vk.Qr, &vk.Qo, &vk.Qc, &vk.S1, &vk.S2, &vk.S3)
+	for i := range publicSignals {
+		t.addScalar(&publicSignals[i])
+	}
+	t.addPoint(&proof.A, &proof.B, &proof.C)
+	ch.beta = t.challenge()
+
+	t.addScalar(&ch.beta)
+	ch.gamma = t.challenge()
+
+	t.addScalar(&ch.beta, &ch.gamma)
+	t.addPoint(&proof.Z)
+	ch.alpha = t.challenge()
+
+	t.addScalar(&ch.alpha)
+	t.addPoint(&proof.T1, &proof.T2, &proof.T3)
+	ch.xi = t.challenge()
+
+	t.addScalar(&ch.xi, &proof.EvalA, &proof.EvalB, &proof.EvalC, &proof.EvalS1, &proof.EvalS2, &proof.EvalZw)
+	ch.v[1] = t.challenge()
+	for i := 2; i < 6; i++ {
+		ch.v[i].Mul(&ch.v[i-1], &ch.v[1])
+	}
+
+	t.addPoint(&proof.Wxi, &proof.Wxiw)
+	ch.u = t.challenge()
+	return ch
+}
+
+// lagrange calcola ξⁿ, Z_H(ξ) e Lᵢ(ξ) = ωⁱ⁻¹·Z_H(ξ) / (n·(ξ - ωⁱ⁻¹)).
+func (ch *challenges) lagrange(vk *VerifyingKey) {
+	ch.xin = ch.xi
+	for i := 0; i < vk.Power; i++ {
+		ch.xin.Square(&ch.xin)
+	}
+	var one fr.Element
+	one.SetOne()
+	ch.zh.Sub(&ch.xin, &one)
+
+	var n fr.Element
+	n.SetUint64(1 << vk.Power)
+
+	nbL := max(1, vk.NPublic)
+	ch.l = make([]fr.Element, nbL+1)
+	w := one
+	for i := 1; i <= nbL; i++ {
+		var num, den fr.Element
+		num.Mul(&w, &ch.zh)
+		den.Sub(&ch.xi, &w).Mul(&den, &n)
+		ch.l[i].Div(&num, &den)
+		w.Mul(&w, &vk.W)
+	}
+	ch.l1 = ch.l[1]
+}
+
+// pi restituisce PI(ξ) = -Σ wᵢ·Lᵢ(ξ).
+func (ch *challenges) pi(publicSignals []fr.Element) fr.Element {
+	var res, tmp fr.Element
+	for i := range publicSignals {
+		tmp.Mul(&publicSignals[i], &ch.l[i+1])
+		res.Sub(&res, &tmp)
+	}
+	return res
+}
+
+// r0 è il termine costante della linearizzazione.
+func (ch *challenges) r0(proof *Proof, pi fr.Element) fr.Element {
+	var e2, e3, tmp fr.Element
+	e2.Square(&ch.alpha).Mul(&e2, &ch.l1)
+
+	tmp.Mul(&ch.beta, &proof.EvalS1)
+	e3 = linearTerm(&proof.EvalA, &tmp, &ch.gamma)
+	tmp.Mul(&ch.beta, &proof.EvalS2)
+	tmp = linearTerm(&proof.EvalB, &tmp, &ch.gamma)
+	e3.Mul(&e3, &tmp)
+	tmp.Add(&proof.EvalC, &ch.gamma)
+	e3.Mul(&e3, &tmp).Mul(&e3, &proof.EvalZw).Mul(&e3, &ch.alpha)
+
+	var res fr.Element
+	res.Sub(&pi, &e2).Sub(&res, &e3)
+	return res
+}
+
+// Verify replica plonk.verify di snarkjs.
+func Verify(vk *VerifyingKey, proof *Proof, publicSignals []fr.Element) error {
+	if len(publicSignals) != vk.NPublic {
+		return errors.New("numero di segnali pubblici errato")
+	}
+	for _, p := range []*bn254.G1Affine{&proof.A, &proof.B, &proof.C, &proof.Z, &proof.T1, &proof.T2, &proof.T3, &proof.Wxi, &proof.Wxiw} {
+		if !p.IsOnCurve() {
+			return errors.New("punto della prova non sulla curva")
+		}
+	}
+
+	ch := deriveChallenges(vk, proof, publicSignals)
+	ch.lagrange(vk)
+	r0 := ch.r0(proof, ch.pi(publicSignals))
+
+	// D = [r(τ)]₁ + u·[z(τ)]₁
+	var ab, tmp, betaXi, coefZ, coefS3 fr.Element
+	ab.Mul(&proof.EvalA, &proof.EvalB)
+	betaXi.Mul(&ch.beta, &ch.xi)
+	coefZ = linearTerm(&proof.EvalA, &betaXi, &ch.gamma)
+	tmp.Mul(&betaXi, &vk.K1)
+	tmp = linearTerm(&proof.EvalB, &tmp, &ch.gamma)
+	coefZ.Mul(&coefZ, &tmp)
+	tmp.Mul(&betaXi, &vk.K2)
+	tmp = linearTerm(&proof.EvalC, &tmp, &ch.gamma)
+	coefZ.Mul(&coefZ, &tmp).Mul(&coefZ, &ch.alpha)
+	tmp.Square(&ch.alpha).Mul(&tmp, &ch.l1)
+	coefZ.Add(&coefZ, &tmp).Add(&coefZ, &ch.u)
+
+	tmp.Mul(&ch.beta, &proof.EvalS1)
+	coefS3 = linearTerm(&proof.EvalA, &tmp, &ch.gamma)
+	tmp.Mul(&ch.beta, &proof.EvalS2)
+	tmp = linearTerm(&proof.EvalB, &tmp, &ch.gamma)
+	coefS3.Mul(&coefS3, &tmp).Mul(&coefS3, &ch.alpha).Mul(&coefS3, &ch.beta).Mul(&coefS3, &proof.EvalZw)
+	coefS3.Neg(&coefS3)
+
+	var xin2, negZh, negZhXin, negZhXin2 fr.Element
+	xin2.Square(&ch.xin)
+	negZh.Neg(&ch.zh)
+	negZhXin.Mul(&negZh, &ch.xin)
+	negZhXin2.Mul(&negZh, &xin2)
+
+	var one fr.Element
+	one.SetOne()
+
+	// F = D + v₁A + v₂B + v₃C + v₄S1 + v₅S2
+	points := []bn254.G1Affine{vk.Qm, vk.Ql, vk.Qr, vk.Qo, vk.Qc, proof.Z, vk.S3, proof.T1, proof.T2, proof.T3, proof.A, proof.B, proof.C, vk.S1, vk.S2}
+	scalars := []fr.Element{ab, proof.EvalA, proof.EvalB, proof.EvalC, one, coefZ, coefS3, negZh, negZhXin, negZhXin2, ch.v[1], ch.v[2], ch.v[3], ch.v[4], ch.v[5]}
+
+	// E = (-r0 + Σ vᵢ·p̄ᵢ + u·z̄ω)·G₁, sottratto direttamente da F
+	var e fr.Element
+	e.Neg(&r0)
+	for i, eval := range []*fr.Element{&proof.EvalA, &proof.EvalB, &proof.EvalC, &proof.EvalS1, &proof.EvalS2} {
+		tmp.Mul(&ch.v[i+1], eval)
+		e.Add(&e, &tmp)
+	}
+	tmp.Mul(&ch.u, &proof.EvalZw)
+	e.Add(&e, &tmp)
+	e.Neg(&e)
+
+	_, _, g1, g2 := bn254.Generators()
+
+	// B₁ = ξ·W_ξ + u·ξ·ω·W_ξω + F - E
+	var uXiW fr.Element
+	uXiW.Mul(&ch.u, &ch.xi).Mul(&uXiW, &vk.W)
+	points = append(points, proof.Wxi, proof.Wxiw, g1)
+	scalars = append(scalars, ch.xi, uXiW, e)
+
+	var b1 bn254.G1Affine
+	if _, err := b1.MultiExp(points, scalars, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+
+	// A₁ = W_ξ + u·W_ξω
+	var a1 bn254.G1Affine
+	if _, err := a1.MultiExp([]bn254.G1Affine{proof.Wxi, proof.Wxiw}, []fr.Element{one, ch.u}, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	a1.Neg(&a1)
+
+	ok, err := bn254.PairingCheck([]bn254.G1Affine{a1, b1}, []bn254.G2Affine{vk.X2, g2})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("pairing non valido")
+	}
+	return nil
+}
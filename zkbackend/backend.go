@@ -0,0 +1,494 @@
+// Package zkbackend permette di usare lo stesso circuito KPI con Groth16 o PLONK
+// su BN254, BLS12-381 o BLS12-377: compilazione, setup, prova, verifica e
+// persistenza delle chiavi funzionano allo stesso modo per ogni combinazione.
+package zkbackend
+
+import (
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+)
+
+// Backend è il sistema di prova usato per un circuito.
+type Backend string
+
+const (
+	Groth16 Backend = "groth16" // setup specifico per circuito
+	Plonk   Backend = "plonk"   // setup universale KZG
+)
+
+// DefaultCurve è la curva dei circuiti KPI se non si sceglie -curve: l'unica
+// letta da snarkjs (groth16), arkworks e dai precompile EVM.
+const DefaultCurve = ecc.BN254
+
+// Curves elenca le curve supportate. BN254 dà circa 100 bit classici, le due
+// BLS12 circa 120: BLS12-381 è quella da usare per puntare ai 128 bit.
+var Curves = []ecc.ID{ecc.BN254, ecc.BLS12_381, ecc.BLS12_377}
+
+// ParseCurve accetta i nomi di ecc.ID (bn254, bls12_381, bls12_377), anche
+// con il trattino.
+func ParseCurve(s string) (ecc.ID, error) {
+	name := strings.ReplaceAll(strings.ToLower(s), "-", "_")
+	for _, c := range Curves {
+		if c.String() == name {
+			return c, nil
+		}
+	}
+	return ecc.UNKNOWN, fmt.Errorf("curva sconosciuta %q (%v)", s, Curves)
+}
+
+// CurveOf restituisce la curva con campo scalare field, ecc.UNKNOWN se non è
+// tra Curves.
+func CurveOf(field *big.Int) ecc.ID {
+	for _, c := range Curves {
+		if c.ScalarField().Cmp(field) == 0 {
+			return c
+		}
+	}
+	return ecc.UNKNOWN
+}
+
+const (
+	curveFile       = "curve"
+	fingerprintFile = "fingerprint"
+	ccsFile         = "circuit.ccs"
+	pkFile          = "proving.key"
+	vkFile          = "verifying.key"
+	proofFile       = "proof.gnark"
+	publicFile      = "public_witness.gnark"
+)
+
+// Serializable è implementato da chiavi e prove di entrambi i backend.
+type Serializable interface {
+	io.WriterTo
+	io.ReaderFrom
+}
+
+// Proof è una groth16.Proof o una plonk.Proof.
+type Proof = Serializable
+
+// System raccoglie il constraint system e le chiavi di un circuito.
+// ProvingKey e VerifyingKey sono groth16.ProvingKey/VerifyingKey oppure
+// plonk.ProvingKey/VerifyingKey a seconda di Backend.
+type System struct {
+	Backend      Backend
+	Curve        ecc.ID
+	CCS          constraint.ConstraintSystem
+	ProvingKey   Serializable
+	VerifyingKey Serializable
+}
+
+func ParseBackend(s string) (Backend, error) {
+	switch Backend(s) {
+	case Groth16, Plonk:
+		return Backend(s), nil
+	}
+	return "", fmt.Errorf("backend sconosciuto %q (groth16 o plonk)", s)
+}
+
+// Compile usa r1cs per Groth16 e scs (sparse R1CS) per PLONK, sul campo
+// scalare di curve.
+func Compile(curve ecc.ID, b Backend, circuit frontend.Circuit) (constraint.ConstraintSystem, error) {
+	switch b {
+	case Groth16:
+		return frontend.Compile(curve.ScalarField(), r1cs.NewBuilder, circuit)
+	case Plonk:
+		return frontend.Compile(curve.ScalarField(), scs.NewBuilder, circuit)
+	}
+	return nil, fmt.Errorf("backend sconosciuto %q", b)
+}
+
+// Setup genera le chiavi sulla curva di ccs. Per PLONK srsPath è il file con
+// l'SRS KZG universale, che deve essere della stessa curva; devSRS lo
+// sostituisce con un SRS di sviluppo NON sicuro (vedi LoadSRS).
+func Setup(b Backend, ccs constraint.ConstraintSystem, srsPath string, devSRS bool) (*System, error) {
+	s := &System{Backend: b, Curve: CurveOf(ccs.Field()), CCS: ccs}
+	switch b {
+	case Groth16:
+		pk, vk, err := groth16.Setup(ccs)
+		if err != nil {
+			return nil, err
+		}
+		s.ProvingKey, s.VerifyingKey = pk, vk
+	case Plonk:
+		srs, srsLagrange, err := LoadSRS(srsPath, devSRS, ccs)
+		if err != nil {
+			return nil, err
+		}
+		pk, vk, err := plonk.Setup(ccs, srs, srsLagrange)
+		if err != nil {
+			return nil, err
+		}
+		s.ProvingKey, s.VerifyingKey = pk, vk
+	default:
+		return nil, fmt.Errorf("backend sconosciuto %q", b)
+	}
+	return s, nil
+}
+
+// LoadOrSetup riusa le chiavi salvate in keysDir se sono di questo circuito,
+// altrimenti esegue il setup e salva tutto in keysDir. Con keysDir vuoto non
+// viene salvato nulla.
+//
+// Il circuito viene sempre compilato: l'impronta salvata accanto alle chiavi
+// (vedi Fingerprint) dice se sono state generate per lo stesso constraint
+// system. Chiavi di un altro circuito, di un altro -hash o senza impronta
+// vengono rigenerate. Le chiavi PLONK fatte con devSRS hanno un'impronta
+// propria, così una run senza -dev-srs non le riusa come se venissero dalla
+// cerimonia.
+func LoadOrSetup(curve ecc.ID, b Backend, circuit frontend.Circuit, keysDir, srsPath string, devSRS bool) (*System, error) {
+	ccs, err := Compile(curve, b, circuit)
+	if err != nil {
+		return nil, err
+	}
+	fp, err := Fingerprint(b, circuit, ccs)
+	if err != nil {
+		return nil, err
+	}
+	if b == Plonk && devSRS {
+		fp += "srs dev NON sicuro\n"
+	}
+
+	if keysDir != "" {
+		s, err := loadKeys(curve, b, keysDir, fp)
+		switch {
+		case err == nil:
+			return s, nil
+		case errors.Is(err, errStale):
+			fmt.Printf("%v: rifaccio il setup\n", err)
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	s, err := Setup(b, ccs, srsPath, devSRS)
+	if err != nil {
+		return nil, err
+	}
+	if keysDir != "" {
+		if err := s.Save(keysDir); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(keysDir, fingerprintFile), []byte(fp), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+var errStale = errors.New("chiavi di un altro circuito")
+
+// loadKeys legge il System salvato in dir se l'impronta salvata è fp.
+func loadKeys(curve ecc.ID, b Backend, dir, fp string) (*System, error) {
+	saved, err := os.ReadFile(filepath.Join(dir, fingerprintFile))
+	if errors.Is(err, os.ErrNotExist) {
+		if _, err := os.Stat(filepath.Join(dir, vkFile)); err == nil {
+			return nil, fmt.Errorf("%w in %s: manca %s", errStale, dir, fingerprintFile)
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if string(saved) != fp {
+		return nil, fmt.Errorf("%w in %s: %s diverso", errStale, dir, fingerprintFile)
+	}
+	return Load(curve, b, dir)
+}
+
+// Fingerprint identifica le chiavi di un circuito: backend, curva, tipo del
+// circuito con i suoi campi gnark:"-" (es. Hash=mimc) e sha256 del constraint
+// system compilato, che cambia con MaxValues, l'hash e ogni vincolo.
+func Fingerprint(b Backend, circuit frontend.Circuit, ccs constraint.ConstraintSystem) (string, error) {
+	h := sha256.New()
+	if _, err := ccs.WriteTo(h); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "backend %s\n", b)
+	fmt.Fprintf(&sb, "curve %s\n", CurveOf(ccs.Field()))
+	fmt.Fprintf(&sb, "circuit %s\n", describe(circuit))
+	fmt.Fprintf(&sb, "ccs sha256:%x\n", h.Sum(nil))
+	return sb.String(), nil
+}
+
+// describe scrive il tipo del circuito e i campi esclusi dallo schema di tipo
+// stringa, intero o bool: sono le opzioni come Hash che decidono i vincoli.
+func describe(circuit frontend.Circuit) string {
+	v := reflect.ValueOf(circuit)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	res := fmt.Sprintf("%T", circuit)
+	if v.Kind() != reflect.Struct {
+		return res
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.Tag.Get("gnark") != "-" {
+			continue
+		}
+		switch f.Type.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Uint64:
+			res += fmt.Sprintf(" %s=%v", f.Name, v.Field(i))
+		}
+	}
+	return res
+}
+
+// Save scrive curva, constraint system, proving key e verifying key in dir.
+func (s *System) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, curveFile), []byte(s.Curve.String()+"\n"), 0o644); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, ccsFile), s.CCS); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, pkFile), s.ProvingKey); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, vkFile), s.VerifyingKey)
+}
+
+// Load legge un System salvato con Save per curve.
+func Load(curve ecc.ID, b Backend, dir string) (*System, error) {
+	if err := checkCurve(curve, dir); err != nil {
+		return nil, err
+	}
+	s := &System{Backend: b, Curve: curve}
+	switch b {
+	case Groth16:
+		s.CCS = groth16.NewCS(curve)
+		s.ProvingKey = groth16.NewProvingKey(curve)
+		s.VerifyingKey = groth16.NewVerifyingKey(curve)
+	case Plonk:
+		s.CCS = plonk.NewCS(curve)
+		s.ProvingKey = plonk.NewProvingKey(curve)
+		s.VerifyingKey = plonk.NewVerifyingKey(curve)
+	default:
+		return nil, fmt.Errorf("backend sconosciuto %q", b)
+	}
+
+	if err := readFile(filepath.Join(dir, ccsFile), s.CCS); err != nil {
+		return nil, err
+	}
+	if err := readFile(filepath.Join(dir, pkFile), s.ProvingKey); err != nil {
+		return nil, err
+	}
+	if err := readFile(filepath.Join(dir, vkFile), s.VerifyingKey); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// checkCurve controlla che le chiavi in dir siano di curve; le cartelle
+// salvate prima del file curve sono BN254.
+func checkCurve(curve ecc.ID, dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, curveFile))
+	if errors.Is(err, os.ErrNotExist) {
+		if _, err := os.Stat(filepath.Join(dir, vkFile)); err != nil {
+			return err
+		}
+		data = []byte(DefaultCurve.String())
+	} else if err != nil {
+		return err
+	}
+	saved, err := ParseCurve(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("%s: %w", dir, err)
+	}
+	if saved != curve {
+		return fmt.Errorf("le chiavi in %s sono per %s, non per %s", dir, saved, curve)
+	}
+	return nil
+}
+
+// LoadVerifyingKey legge solo la verifying key salvata con Save.
+func LoadVerifyingKey(curve ecc.ID, b Backend, dir string) (Serializable, error) {
+	if err := checkCurve(curve, dir); err != nil {
+		return nil, err
+	}
+	vk, err := NewVerifyingKey(curve, b)
+	if err != nil {
+		return nil, err
+	}
+	if err := readFile(filepath.Join(dir, vkFile), vk); err != nil {
+		return nil, err
+	}
+	return vk, nil
+}
+
+// SaveProof salva l'ultima prova e il suo public witness accanto alle chiavi,
+// così i comandi che lavorano sulle chiavi salvate (es. zsnark_solidity) non
+// devono rigenerarla.
+func SaveProof(dir string, proof Proof, publicWitness witness.Witness) error {
+	if err := writeFile(filepath.Join(dir, proofFile), proof); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, publicFile), publicWitness)
+}
+
+// LoadProof legge prova e public witness salvati con SaveProof.
+func LoadProof(curve ecc.ID, b Backend, dir string) (Proof, witness.Witness, error) {
+	if err := checkCurve(curve, dir); err != nil {
+		return nil, nil, err
+	}
+	proof, err := NewProof(curve, b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := readFile(filepath.Join(dir, proofFile), proof); err != nil {
+		return nil, nil, err
+	}
+	publicWitness, err := witness.New(curve.ScalarField())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := readFile(filepath.Join(dir, publicFile), publicWitness); err != nil {
+		return nil, nil, err
+	}
+	return proof, publicWitness, nil
+}
+
+// NewProof restituisce una prova vuota da usare con ReadFrom.
+func NewProof(curve ecc.ID, b Backend) (Proof, error) {
+	switch b {
+	case Groth16:
+		return groth16.NewProof(curve), nil
+	case Plonk:
+		return plonk.NewProof(curve), nil
+	}
+	return nil, fmt.Errorf("backend sconosciuto %q", b)
+}
+
+// NewVerifyingKey restituisce una verifying key vuota da usare con ReadFrom.
+func NewVerifyingKey(curve ecc.ID, b Backend) (Serializable, error) {
+	switch b {
+	case Groth16:
+		return groth16.NewVerifyingKey(curve), nil
+	case Plonk:
+		return plonk.NewVerifyingKey(curve), nil
+	}
+	return nil, fmt.Errorf("backend sconosciuto %q", b)
+}
+
+func (s *System) Prove(fullWitness witness.Witness, opts ...backend.ProverOption) (Proof, error) {
+	switch s.Backend {
+	case Groth16:
+		return groth16.Prove(s.CCS, s.ProvingKey.(groth16.ProvingKey), fullWitness, opts...)
+	case Plonk:
+		return plonk.Prove(s.CCS, s.ProvingKey.(plonk.ProvingKey), fullWitness, opts...)
+	}
+	return nil, fmt.Errorf("backend sconosciuto %q", s.Backend)
+}
+
+func (s *System) Verify(proof Proof, publicWitness witness.Witness, opts ...backend.VerifierOption) error {
+	return Verify(s.Backend, proof, s.VerifyingKey, publicWitness, opts...)
+}
+
+// Verify verifica una prova avendo solo la verifying key (lato auditor).
+func Verify(b Backend, proof Proof, vk Serializable, publicWitness witness.Witness, opts ...backend.VerifierOption) error {
+	switch b {
+	case Groth16:
+		p, ok := proof.(groth16.Proof)
+		if !ok {
+			return fmt.Errorf("prova %T non è groth16", proof)
+		}
+		return groth16.Verify(p, vk.(groth16.VerifyingKey), publicWitness, opts...)
+	case Plonk:
+		p, ok := proof.(plonk.Proof)
+		if !ok {
+			return fmt.Errorf("prova %T non è plonk", proof)
+		}
+		return plonk.Verify(p, vk.(plonk.VerifyingKey), publicWitness, opts...)
+	}
+	return fmt.Errorf("backend sconosciuto %q", b)
+}
+
+func writeFile(path string, v io.WriterTo) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := v.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readFile(path string, v io.ReaderFrom) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = v.ReadFrom(f)
+	return err
+}
+
+// Config raccoglie le opzioni da riga di comando comuni a tutti i main.
+type Config struct {
+	Backend string
+	Curve   string
+	SRSPath string
+	DevSRS  bool
+	KeysDir string
+}
+
+// Flags registra -backend, -curve, -srs, -dev-srs e -keys; va chiamata prima di
+// flag.Parse.
+func Flags() *Config {
+	cfg := &Config{}
+	flag.StringVar(&cfg.Backend, "backend", string(Groth16), "sistema di prova: groth16 o plonk")
+	flag.StringVar(&cfg.Curve, "curve", DefaultCurve.String(), "curva: bn254, bls12_381 o bls12_377 (export snarkjs/arkworks/EVM solo bn254)")
+	flag.StringVar(&cfg.SRSPath, "srs", "", "SRS KZG universale in forma canonica (solo plonk), di default kzg_<curva>.srs")
+	flag.BoolVar(&cfg.DevSRS, "dev-srs", false, "plonk con un SRS KZG di sviluppo NON sicuro generato in memoria, al posto di -srs")
+	flag.StringVar(&cfg.KeysDir, "keys", "", "cartella dove salvare/riusare constraint system e chiavi")
+	return cfg
+}
+
+// Parse legge curva e backend scelti e completa il percorso dell'SRS.
+func (cfg *Config) Parse() (ecc.ID, Backend, error) {
+	curve, err := ParseCurve(cfg.Curve)
+	if err != nil {
+		return ecc.UNKNOWN, "", err
+	}
+	b, err := ParseBackend(cfg.Backend)
+	if err != nil {
+		return ecc.UNKNOWN, "", err
+	}
+	if cfg.SRSPath == "" {
+		cfg.SRSPath = "kzg_" + curve.String() + ".srs"
+	}
+	return curve, b, nil
+}
+
+// LoadOrSetup applica la configurazione al circuito.
+func (cfg *Config) LoadOrSetup(circuit frontend.Circuit) (*System, error) {
+	curve, b, err := cfg.Parse()
+	if err != nil {
+		return nil, err
+	}
+	return LoadOrSetup(curve, b, circuit, cfg.KeysDir, cfg.SRSPath, cfg.DevSRS)
+}
@@ -0,0 +1,113 @@
+package zkbackend
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+)
+
+// powCircuit prova Y = X^Exp: Exp non è nello schema, come Hash nei circuiti KPI.
+type powCircuit struct {
+	X   frontend.Variable `gnark:",secret"`
+	Y   frontend.Variable `gnark:",public"`
+	Exp int               `gnark:"-"`
+}
+
+func (c *powCircuit) Define(api frontend.API) error {
+	res := c.X
+	for i := 1; i < c.Exp; i++ {
+		res = api.Mul(res, c.X)
+	}
+	api.AssertIsEqual(res, c.Y)
+	return nil
+}
+
+func vkBytes(t *testing.T, s *System) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := s.VerifyingKey.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Il setup Groth16 è casuale: stessa vk vuol dire chiavi ricaricate.
+func TestLoadOrSetupFingerprint(t *testing.T) {
+	keys := filepath.Join(t.TempDir(), "keys")
+	setup := func(exp int) []byte {
+		s, err := LoadOrSetup(ecc.BN254, Groth16, &powCircuit{Exp: exp}, keys, "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return vkBytes(t, s)
+	}
+
+	first := setup(3)
+	if !bytes.Equal(setup(3), first) {
+		t.Fatal("stesso circuito: le chiavi salvate non sono state riusate")
+	}
+	other := setup(5)
+	if bytes.Equal(other, first) {
+		t.Fatal("Exp diverso: riusate le chiavi dell'altro circuito")
+	}
+	if !bytes.Equal(setup(5), other) {
+		t.Fatal("le chiavi rigenerate non sono state salvate")
+	}
+
+	// cartelle salvate prima dell'impronta: setup rifatto
+	if err := os.Remove(filepath.Join(keys, fingerprintFile)); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(setup(5), other) {
+		t.Fatal("chiavi senza impronta riusate")
+	}
+}
+
+func TestFingerprintBackend(t *testing.T) {
+	circuit := &powCircuit{Exp: 3}
+	fps := map[string]Backend{}
+	for _, b := range []Backend{Groth16, Plonk} {
+		ccs, err := Compile(ecc.BN254, b, circuit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fp, err := Fingerprint(b, circuit, ccs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other, ok := fps[fp]; ok {
+			t.Fatalf("stessa impronta per %s e %s", other, b)
+		}
+		fps[fp] = b
+	}
+}
+
+// Senza il file SRS plonk si ferma e non crea nulla; -dev-srs genera un SRS in
+// memoria, e le chiavi fatte così non vengono riusate senza -dev-srs.
+func TestPlonkSRS(t *testing.T) {
+	dir := t.TempDir()
+	srs := filepath.Join(dir, "kzg_bn254.srs")
+	keys := filepath.Join(dir, "keys")
+	circuit := &powCircuit{Exp: 3}
+
+	if _, err := LoadOrSetup(ecc.BN254, Plonk, circuit, keys, srs, false); err == nil {
+		t.Fatal("setup plonk senza file SRS")
+	}
+	if _, err := os.Stat(srs); !os.IsNotExist(err) {
+		t.Fatalf("creato %s: %v", srs, err)
+	}
+
+	if _, err := LoadOrSetup(ecc.BN254, Plonk, circuit, keys, srs, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(srs); !os.IsNotExist(err) {
+		t.Fatalf("l'SRS di sviluppo è finito in %s", srs)
+	}
+	if _, err := LoadOrSetup(ecc.BN254, Plonk, circuit, keys, srs, false); err == nil {
+		t.Fatal("riusate senza -dev-srs le chiavi fatte con l'SRS di sviluppo")
+	}
+}
@@ -0,0 +1,165 @@
+package zkbackend
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	kzg_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/kzg"
+	kzg_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/kzg"
+	"github.com/consensys/gnark-crypto/ecc/bn254/kzg"
+	gnark_kzg "github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/constraint"
+
+	"zk-test/snarkjsplonk"
+)
+
+// LoadSRS legge l'SRS KZG universale (forma canonica, formato kzg.SRS.WriteTo) da
+// path e ne ricava la forma di Lagrange della dimensione richiesta da ccs.
+// Lo stesso file vale per qualunque circuito non più grande dell'SRS, quindi
+// aggiungere slot non richiede una nuova cerimonia. L'SRS deve essere della
+// curva di ccs: ogni curva ha il suo file (kzg_bn254.srs, kzg_bls12_381.srs...).
+//
+// Se path non esiste è un errore: in produzione va usato l'output di una
+// cerimonia MPC. Solo con dev (-dev-srs) path viene ignorato e si genera in
+// memoria un SRS di sviluppo NON sicuro (toxic waste noto), mai salvato.
+func LoadSRS(path string, dev bool, ccs constraint.ConstraintSystem) (gnark_kzg.SRS, gnark_kzg.SRS, error) {
+	sizeCanonical, sizeLagrange := plonk.SRSSize(ccs)
+
+	switch CurveOf(ccs.Field()) {
+	case ecc.BN254:
+		srs, err := ReadSRS(path, dev, sizeCanonical, ccs)
+		if err != nil {
+			return nil, nil, err
+		}
+		canonical := &kzg.SRS{Vk: srs.Vk}
+		canonical.Pk.G1 = srs.Pk.G1[:sizeCanonical]
+
+		lagrange := &kzg.SRS{Vk: srs.Vk}
+		lagrange.Pk.G1, err = kzg.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+		if err != nil {
+			return nil, nil, err
+		}
+		return canonical, lagrange, nil
+
+	case ecc.BLS12_381:
+		var srs kzg_bls12381.SRS
+		err := openSRS(path, dev, ecc.BLS12_381, &srs, func(tau *big.Int) error {
+			s, err := kzg_bls12381.NewSRS(uint64(sizeCanonical), tau)
+			if err == nil {
+				srs = *s
+			}
+			return err
+		})
+		if err == nil {
+			err = checkSize(path, len(srs.Pk.G1), sizeCanonical)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		canonical := &kzg_bls12381.SRS{Vk: srs.Vk}
+		canonical.Pk.G1 = srs.Pk.G1[:sizeCanonical]
+
+		lagrange := &kzg_bls12381.SRS{Vk: srs.Vk}
+		lagrange.Pk.G1, err = kzg_bls12381.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+		if err != nil {
+			return nil, nil, err
+		}
+		return canonical, lagrange, nil
+
+	case ecc.BLS12_377:
+		var srs kzg_bls12377.SRS
+		err := openSRS(path, dev, ecc.BLS12_377, &srs, func(tau *big.Int) error {
+			s, err := kzg_bls12377.NewSRS(uint64(sizeCanonical), tau)
+			if err == nil {
+				srs = *s
+			}
+			return err
+		})
+		if err == nil {
+			err = checkSize(path, len(srs.Pk.G1), sizeCanonical)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		canonical := &kzg_bls12377.SRS{Vk: srs.Vk}
+		canonical.Pk.G1 = srs.Pk.G1[:sizeCanonical]
+
+		lagrange := &kzg_bls12377.SRS{Vk: srs.Vk}
+		lagrange.Pk.G1, err = kzg_bls12377.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+		if err != nil {
+			return nil, nil, err
+		}
+		return canonical, lagrange, nil
+	}
+	return nil, nil, fmt.Errorf("plonk non supportato su %s", CurveOf(ccs.Field()))
+}
+
+// ReadSRS legge da path un SRS BN254 canonico con almeno size punti G1. Con
+// dev genera invece un SRS di sviluppo abbastanza grande sia per il PLONK di
+// gnark sia per quello di snarkjs su ccs.
+func ReadSRS(path string, dev bool, size int, ccs constraint.ConstraintSystem) (*kzg.SRS, error) {
+	var srs kzg.SRS
+	err := openSRS(path, dev, ecc.BN254, &srs, func(tau *big.Int) error {
+		s, err := kzg.NewSRS(uint64(max(size, devSRSSize(ccs))), tau)
+		if err == nil {
+			srs = *s
+		}
+		return err
+	})
+	if err == nil {
+		err = checkSize(path, len(srs.Pk.G1), size)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &srs, nil
+}
+
+func devSRSSize(ccs constraint.ConstraintSystem) int {
+	sizeCanonical, _ := plonk.SRSSize(ccs)
+	return max(sizeCanonical, snarkjsplonk.Size(ccs))
+}
+
+// openSRS legge path in srs. Con dev non tocca path e chiama create con un
+// tau casuale, che resta solo in memoria: l'SRS di sviluppo non va mai su un
+// file che una run successiva potrebbe prendere per quello della cerimonia.
+func openSRS(path string, dev bool, curve ecc.ID, srs io.ReaderFrom, create func(tau *big.Int) error) error {
+	if dev {
+		fmt.Printf("ATTENZIONE: -dev-srs, SRS KZG %s di sviluppo NON sicuro generato in memoria e non salvato\n", curve)
+		tau, err := rand.Int(rand.Reader, curve.ScalarField())
+		if err != nil {
+			return err
+		}
+		return create(tau)
+	}
+	if path == "" {
+		return errors.New("plonk richiede un file SRS KZG (-srs), oppure -dev-srs per uno di sviluppo NON sicuro")
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("SRS %s non trovato: serve l'output di una cerimonia MPC (-srs), oppure -dev-srs per uno di sviluppo NON sicuro", path)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := srs.ReadFrom(f); err != nil {
+		return fmt.Errorf("lettura SRS %s (%s): %w", path, curve, err)
+	}
+	return nil
+}
+
+func checkSize(path string, got, size int) error {
+	if got < size {
+		return fmt.Errorf("SRS %s troppo piccolo: %d punti, servono %d", path, got, size)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package zkstark
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+
+	"zk-test/kpihash"
+)
+
+// AIR: una riga per valore KPI, con tutti i round di Poseidon2 srotolati
+// nelle colonne. La riga i ha
+//
+//	v       il valore
+//	acc     la somma dei valori fino alla riga i
+//	a_r b_r lo stato di Poseidon2 dopo il round r
+//
+// Il hash è quello di kpihash per le foglie: compressione di (IV, v) con
+// feed-forward, perm(IV, v)[1] + v, dove IV è kpihash.LeafIV, con gli
+// stessi round di kpihash su BN254 (width 2, 8 round completi e 56
+// parziali, S-box x^5).
+const (
+	colV      = 0
+	colAcc    = 1
+	colRounds = 2
+)
+
+var (
+	p2        = poseidon2.NewParameters(2, 8, 56)
+	nbRounds  = p2.NbFullRounds + p2.NbPartialRounds
+	halfFull  = p2.NbFullRounds / 2
+	nbColumns = colRounds + 2*nbRounds
+
+	// 2 vincoli per round, il hash, la transizione di acc, prima e ultima riga
+	nbConstraints = 2*nbRounds + 4
+
+	// IV delle foglie, lo stesso di kpihash
+	leafIV = func() fr.Element {
+		var iv fr.Element
+		iv.SetBigInt(kpihash.LeafIV(fr.Modulus()))
+		return iv
+	}()
+)
+
+// sboxDegree è il grado dei vincoli dei round, e fissa il grado della
+// composizione: (sboxDegree-1)·Rows.
+const sboxDegree = 5
+
+func colA(r int) int { return colRounds + 2*r }
+func colB(r int) int { return colRounds + 2*r + 1 }
+
+func sbox(x fr.Element) fr.Element {
+	var y fr.Element
+	y.Square(&x).Square(&y).Mul(&y, &x)
+	return y
+}
+
+// round applica il round r di Poseidon2 allo stato (in0, in1), come
+// poseidon2.Permutation: round key, S-box (solo sul primo elemento nei round
+// parziali) e matrice esterna [[2,1],[1,2]] o interna [[2,1],[1,3]].
+func round(r int, in0, in1 fr.Element) (fr.Element, fr.Element) {
+	rk := p2.RoundKeys[r]
+	var s0, s1, out0, out1 fr.Element
+	s0.Add(&in0, &rk[0])
+	s0 = sbox(s0)
+	if r < halfFull || r >= halfFull+p2.NbPartialRounds {
+		s1.Add(&in1, &rk[1])
+		s1 = sbox(s1)
+		out0.Double(&s0).Add(&out0, &s1)
+		out1.Double(&s1).Add(&out1, &s0)
+		return out0, out1
+	}
+	out0.Double(&s0).Add(&out0, &in1)
+	out1.Double(&in1).Add(&out1, &in1).Add(&out1, &s0)
+	return out0, out1
+}
+
+// initialState è la matrice esterna applicata a (IV, v) prima dei round.
+func initialState(v fr.Element) (fr.Element, fr.Element) {
+	var a, b fr.Element
+	a.Double(&leafIV).Add(&a, &v)
+	b.Double(&v).Add(&b, &leafIV)
+	return a, b
+}
+
+// leaf calcola il hash di una foglia come fa la traccia.
+func leaf(v fr.Element) fr.Element {
+	a, b := initialState(v)
+	for r := 0; r < nbRounds; r++ {
+		a, b = round(r, a, b)
+	}
+	b.Add(&b, &v)
+	return b
+}
+
+// buildTrace restituisce le colonne della traccia, columns[j][i] per la riga i.
+func buildTrace(values []fr.Element) [][]fr.Element {
+	columns := make([][]fr.Element, nbColumns)
+	for j := range columns {
+		columns[j] = make([]fr.Element, len(values))
+	}
+	var acc fr.Element
+	for i, v := range values {
+		acc.Add(&acc, &v)
+		columns[colV][i] = v
+		columns[colAcc][i] = acc
+		a, b := initialState(v)
+		for r := 0; r < nbRounds; r++ {
+			a, b = round(r, a, b)
+			columns[colA(r)][i] = a
+			columns[colB(r)][i] = b
+		}
+	}
+	return columns
+}
+
+// checkTrace controlla che la traccia soddisfi lo statement prima di provarlo.
+func checkTrace(columns [][]fr.Element, st *Statement) error {
+	last := len(columns[colV]) - 1
+	for i := range columns[colV] {
+		var h fr.Element
+		h.Add(&columns[colB(nbRounds-1)][i], &columns[colV][i])
+		if !h.Equal(&st.Hashes[i]) {
+			return fmt.Errorf("zkstark: il hash del valore %d non è quello dello statement", i)
+		}
+	}
+	if !columns[colAcc][last].Equal(&st.Sum) {
+		return errors.New("zkstark: la somma dei valori non è quella dello statement")
+	}
+	return nil
+}
+
+// point sono i valori che servono a valutare la composizione in x: la riga
+// in x, v e acc nella riga successiva (x·ω), il hash pubblico interpolato.
+type point struct {
+	x       fr.Element
+	row     []fr.Element
+	vNext   fr.Element
+	accNext fr.Element
+	hash    fr.Element
+}
+
+// compose valuta la composizione C(x) = Σ α_k·N_k(x)/Z_k(x):
+//   - round e hash valgono su tutte le righe: diviso per Z_H = x^n - 1
+//   - acc(ωx) = acc(x) + v(ωx) tranne che sull'ultima riga: moltiplicato per
+//     (x - ω^(n-1)) e diviso per Z_H
+//   - acc = v sulla prima riga, diviso per x - 1
+//   - acc = Sum sull'ultima riga, diviso per x - ω^(n-1)
+//
+// Con una traccia onesta C è un polinomio di grado < (sboxDegree-1)·n.
+func compose(alpha []fr.Element, p *point, sum, lastRow fr.Element, n uint64) fr.Element {
+	var onH, t fr.Element
+	k := 0
+	add := func(num *fr.Element) {
+		t.Mul(&alpha[k], num)
+		onH.Add(&onH, &t)
+		k++
+	}
+
+	in0, in1 := initialState(p.row[colV])
+	for r := 0; r < nbRounds; r++ {
+		out0, out1 := round(r, in0, in1)
+		var d fr.Element
+		d.Sub(&p.row[colA(r)], &out0)
+		add(&d)
+		d.Sub(&p.row[colB(r)], &out1)
+		add(&d)
+		in0, in1 = p.row[colA(r)], p.row[colB(r)]
+	}
+	var d fr.Element
+	d.Add(&in1, &p.row[colV]).Sub(&d, &p.hash)
+	add(&d)
+
+	var xLast fr.Element
+	xLast.Sub(&p.x, &lastRow)
+	d.Sub(&p.accNext, &p.row[colAcc]).Sub(&d, &p.vNext).Mul(&d, &xLast)
+	add(&d)
+
+	var zh, res fr.Element
+	zh = exp(p.x, n)
+	zh.Sub(&zh, &one).Inverse(&zh)
+	res.Mul(&onH, &zh)
+
+	var first, den fr.Element
+	first.Sub(&p.row[colAcc], &p.row[colV]).Mul(&first, &alpha[k])
+	den.Sub(&p.x, &one).Inverse(&den)
+	first.Mul(&first, &den)
+	res.Add(&res, &first)
+	k++
+
+	var last fr.Element
+	last.Sub(&p.row[colAcc], &sum).Mul(&last, &alpha[k])
+	den.Inverse(&xLast)
+	last.Mul(&last, &den)
+	return *res.Add(&res, &last)
+}
+
+var one = fr.One()
+
+// exp è x^e con quadrati e prodotti.
+func exp(x fr.Element, e uint64) fr.Element {
+	res := fr.One()
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			res.Mul(&res, &x)
+		}
+		x.Square(&x)
+	}
+	return res
+}
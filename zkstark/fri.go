@@ -0,0 +1,118 @@
+package zkstark
+
+import (
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// FRI sul dominio D_0 = g·<ω> di taglia L: lo strato i vive su
+// D_i = g^(2^i)·<ω^(2^i)>, e x e -x = x·ω^(L_i/2) stanno agli indici p e
+// p + L_i/2. Ogni piega dimezza dominio e grado:
+//
+//	f_{i+1}(x²) = (f_i(x) + f_i(-x))/2 + β_i·(f_i(x) - f_i(-x))/(2x)
+//
+// Lo strato 0 non viene committato: il verifier lo ricalcola dalle aperture
+// di traccia e composizione (DEEP). Gli strati intermedi sono alberi di
+// Merkle con foglie (f(x), f(-x)), l'ultimo è costante e va in chiaro.
+
+var twoInv = func() fr.Element {
+	var t fr.Element
+	t.SetUint64(2).Inverse(&t)
+	return t
+}()
+
+// friDomain è il dominio dello strato: shift, generatore e taglia.
+type friDomain struct {
+	shift, omega fr.Element
+	size         int
+}
+
+func (d friDomain) next() friDomain {
+	var n friDomain
+	n.shift.Square(&d.shift)
+	n.omega.Square(&d.omega)
+	n.size = d.size / 2
+	return n
+}
+
+// point restituisce l'elemento di indice p del dominio.
+func (d friDomain) point(p int) fr.Element {
+	x := exp(d.omega, uint64(p))
+	x.Mul(&x, &d.shift)
+	return x
+}
+
+// foldPair piega la coppia (f(x), f(-x)) con la sfida beta.
+func foldPair(f0, f1, beta, x fr.Element) fr.Element {
+	var sum, diff, res fr.Element
+	sum.Add(&f0, &f1)
+	diff.Sub(&f0, &f1)
+	x.Inverse(&x)
+	diff.Mul(&diff, &x).Mul(&diff, &beta)
+	res.Add(&sum, &diff).Mul(&res, &twoInv)
+	return res
+}
+
+// fold piega un intero strato.
+func fold(f []fr.Element, beta fr.Element, d friDomain) []fr.Element {
+	half := len(f) / 2
+	xs := make([]fr.Element, half)
+	x := d.shift
+	for p := range xs {
+		xs[p] = x
+		x.Mul(&x, &d.omega)
+	}
+	xs = fr.BatchInvert(xs)
+	out := make([]fr.Element, half)
+	var sum, diff fr.Element
+	for p := range out {
+		sum.Add(&f[p], &f[p+half])
+		diff.Sub(&f[p], &f[p+half])
+		diff.Mul(&diff, &xs[p]).Mul(&diff, &beta)
+		out[p].Add(&sum, &diff).Mul(&out[p], &twoInv)
+	}
+	return out
+}
+
+// friLayer è uno strato committato.
+type friLayer struct {
+	values []fr.Element
+	tree   *merkleTree
+}
+
+func commitLayer(values []fr.Element) friLayer {
+	half := len(values) / 2
+	leaves := make([]Digest, half)
+	for p := range leaves {
+		leaves[p] = leafHash([]fr.Element{values[p], values[p+half]})
+	}
+	return friLayer{values: values, tree: newMerkleTree(leaves)}
+}
+
+// opening della coppia di indice p.
+func (l friLayer) opening(p int) Opening {
+	half := len(l.values) / 2
+	return Opening{
+		Values: []fr.Element{l.values[p], l.values[p+half]},
+		Path:   l.tree.path(p),
+	}
+}
+
+// friCommit piega f (lo strato 0) folds volte: restituisce gli strati
+// committati 1..folds-1 e l'ultimo, da mandare in chiaro.
+func friCommit(t *transcript, f []fr.Element, d friDomain, folds int) ([]fr.Element, []friLayer, []fr.Element) {
+	betas := make([]fr.Element, folds)
+	var layers []friLayer
+	for i := 0; i < folds; i++ {
+		betas[i] = t.challenge()
+		f = fold(f, betas[i], d)
+		d = d.next()
+		if i < folds-1 {
+			l := commitLayer(f)
+			root := l.tree.root()
+			t.absorb(root[:])
+			layers = append(layers, l)
+		}
+	}
+	t.absorbElements(f...)
+	return betas, layers, f
+}
@@ -0,0 +1,83 @@
+package zkstark
+
+import (
+	"crypto/sha256"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// Digest è un nodo degli alberi di Merkle, SHA-256.
+type Digest = [sha256.Size]byte
+
+// foglie e nodi hanno prefissi diversi, così una foglia non passa per nodo
+func leafHash(values []fr.Element) Digest {
+	h := sha256.New()
+	h.Write([]byte{0})
+	for i := range values {
+		b := values[i].Bytes()
+		h.Write(b[:])
+	}
+	var d Digest
+	h.Sum(d[:0])
+	return d
+}
+
+func nodeHash(left, right *Digest) Digest {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left[:])
+	h.Write(right[:])
+	var d Digest
+	h.Sum(d[:0])
+	return d
+}
+
+// merkleTree tiene tutti i livelli, levels[0] sono le foglie (in numero
+// potenza di 2) e l'ultimo la radice.
+type merkleTree struct {
+	levels [][]Digest
+}
+
+func newMerkleTree(leaves []Digest) *merkleTree {
+	t := &merkleTree{levels: [][]Digest{leaves}}
+	for level := leaves; len(level) > 1; {
+		next := make([]Digest, len(level)/2)
+		for i := range next {
+			next[i] = nodeHash(&level[2*i], &level[2*i+1])
+		}
+		t.levels = append(t.levels, next)
+		level = next
+	}
+	return t
+}
+
+func (t *merkleTree) root() Digest {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// path restituisce i fratelli dalla foglia i alla radice.
+func (t *merkleTree) path(i int) []Digest {
+	path := make([]Digest, len(t.levels)-1)
+	for l := range path {
+		path[l] = t.levels[l][i^1]
+		i >>= 1
+	}
+	return path
+}
+
+// verifyPath ricalcola la radice dalla foglia i e dal cammino, che deve
+// avere esattamente depth fratelli.
+func verifyPath(root Digest, leaf Digest, i int, path []Digest, depth int) bool {
+	if len(path) != depth || i < 0 || i >= 1<<depth {
+		return false
+	}
+	for l := range path {
+		if i&1 == 0 {
+			leaf = nodeHash(&leaf, &path[l])
+		} else {
+			leaf = nodeHash(&path[l], &leaf)
+		}
+		i >>= 1
+	}
+	return leaf == root
+}
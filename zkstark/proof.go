@@ -0,0 +1,208 @@
+package zkstark
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// Opening sono dei valori in una foglia di Merkle con il cammino fino alla
+// radice.
+type Opening struct {
+	Values []fr.Element
+	Path   []Digest
+}
+
+// Query sono le aperture per un indice q di FRI: riga di traccia e
+// composizione in x e -x, poi la coppia di ogni strato committato.
+type Query struct {
+	Trace  [2]Opening
+	Comp   [2]Opening
+	Layers []Opening
+}
+
+// Proof è la prova STARK. OOD sono le colonne della traccia in z, poi v e acc
+// in z·ω (fuori dal dominio, per il DEEP).
+type Proof struct {
+	TraceRoot Digest
+	CompRoot  Digest
+	OOD       []fr.Element
+	FRIRoots  []Digest
+	Final     []fr.Element
+	Queries   []Query
+}
+
+// Formato binario: "KPISTARK", versione, poi i campi in ordine; ogni slice
+// è preceduta dalla lunghezza uint32 big-endian, gli elementi sono 32 byte
+// big-endian canonici.
+const (
+	magic   = "KPISTARK"
+	version = 1
+
+	maxLen = 1 << 16 // limite alle lunghezze lette, la prova non è fidata
+)
+
+// WriteTo scrive la prova nel formato binario.
+func (p *Proof) WriteTo(w io.Writer) (int64, error) {
+	e := &encoder{w: bufio.NewWriter(w)}
+	e.bytes([]byte(magic))
+	e.bytes([]byte{version})
+	e.digest(p.TraceRoot)
+	e.digest(p.CompRoot)
+	e.elements(p.OOD)
+	e.length(len(p.FRIRoots))
+	for _, r := range p.FRIRoots {
+		e.digest(r)
+	}
+	e.elements(p.Final)
+	e.length(len(p.Queries))
+	for i := range p.Queries {
+		q := &p.Queries[i]
+		for _, o := range q.Trace {
+			e.opening(o)
+		}
+		for _, o := range q.Comp {
+			e.opening(o)
+		}
+		e.length(len(q.Layers))
+		for _, o := range q.Layers {
+			e.opening(o)
+		}
+	}
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.n, e.err
+}
+
+// ReadFrom legge una prova scritta da WriteTo.
+func (p *Proof) ReadFrom(r io.Reader) (int64, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+	head := d.bytes(len(magic) + 1)
+	if d.err == nil && (string(head[:len(magic)]) != magic || head[len(magic)] != version) {
+		return d.n, errors.New("zkstark: non è una prova KPISTARK v1")
+	}
+	p.TraceRoot = d.digest()
+	p.CompRoot = d.digest()
+	p.OOD = d.elements()
+	p.FRIRoots = make([]Digest, d.length())
+	for i := range p.FRIRoots {
+		p.FRIRoots[i] = d.digest()
+	}
+	p.Final = d.elements()
+	p.Queries = make([]Query, d.length())
+	for i := range p.Queries {
+		q := &p.Queries[i]
+		for j := range q.Trace {
+			q.Trace[j] = d.opening()
+		}
+		for j := range q.Comp {
+			q.Comp[j] = d.opening()
+		}
+		q.Layers = make([]Opening, d.length())
+		for j := range q.Layers {
+			q.Layers[j] = d.opening()
+		}
+	}
+	return d.n, d.err
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (e *encoder) bytes(b []byte) {
+	if e.err != nil {
+		return
+	}
+	n, err := e.w.Write(b)
+	e.n += int64(n)
+	e.err = err
+}
+
+func (e *encoder) length(n int) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(n))
+	e.bytes(b[:])
+}
+
+func (e *encoder) digest(d Digest) {
+	e.bytes(d[:])
+}
+
+func (e *encoder) elements(v []fr.Element) {
+	e.length(len(v))
+	for i := range v {
+		b := v[i].Bytes()
+		e.bytes(b[:])
+	}
+}
+
+func (e *encoder) opening(o Opening) {
+	e.elements(o.Values)
+	e.length(len(o.Path))
+	for _, d := range o.Path {
+		e.digest(d)
+	}
+}
+
+type decoder struct {
+	r   *bufio.Reader
+	n   int64
+	err error
+}
+
+func (d *decoder) bytes(n int) []byte {
+	b := make([]byte, n)
+	if d.err != nil {
+		return b
+	}
+	m, err := io.ReadFull(d.r, b)
+	d.n += int64(m)
+	d.err = err
+	return b
+}
+
+func (d *decoder) length() int {
+	n := int(binary.BigEndian.Uint32(d.bytes(4)))
+	if d.err == nil && n > maxLen {
+		d.err = fmt.Errorf("zkstark: lunghezza %d oltre %d", n, maxLen)
+	}
+	if d.err != nil {
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) digest() Digest {
+	var res Digest
+	copy(res[:], d.bytes(len(res)))
+	return res
+}
+
+func (d *decoder) elements() []fr.Element {
+	v := make([]fr.Element, d.length())
+	for i := range v {
+		b := d.bytes(fr.Bytes)
+		if d.err == nil {
+			d.err = v[i].SetBytesCanonical(b)
+		}
+	}
+	return v
+}
+
+func (d *decoder) opening() Opening {
+	var o Opening
+	o.Values = d.elements()
+	o.Path = make([]Digest, d.length())
+	for i := range o.Path {
+		o.Path[i] = d.digest()
+	}
+	return o
+}
@@ -0,0 +1,372 @@
+// Package zkstark è il backend di prova post-quantum dei KPI: uno STARK
+// trasparente, solo hash (SHA-256 per Merkle e Fiat-Shamir), niente pairing
+// né trusted setup. Prova lo stesso statement di
+// zsnark_Poseidon_linear_commitment: per Rows valori segreti v_i i hash
+// pubblici Poseidon2 (kpihash, foglie) e la loro somma pubblica.
+//
+// Schema, sul campo scalare di BN254 (2-adicità 28, così Poseidon2 resta
+// quello di gnark-crypto):
+//
+//  1. traccia di Rows righe (air.go), estesa su un coset di taglia
+//     Rows·Blowup e committata riga per riga con Merkle
+//  2. composizione dei vincoli con sfide α, committata
+//  3. DEEP: traccia aperta in un punto z fuori dal dominio, la composizione
+//     in z la ricalcola il verifier dai vincoli
+//  4. FRI (fri.go) sul quoziente DEEP, con Queries aperture
+//
+// La prova non è zero-knowledge (la traccia non è mascherata): qui non cambia
+// molto, i hash Poseidon2 pubblici di valori a pochi bit si invertono già
+// per forza bruta.
+package zkstark
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+)
+
+const (
+	Rows    = 128 // valori per prova, come MaxValues del circuito SNARK
+	Blowup  = 16  // fattore di estensione della traccia
+	Queries = 48  // aperture FRI
+)
+
+var (
+	ldeSize     = Rows * Blowup
+	degreeBound = (sboxDegree - 1) * Rows // grado della composizione e del quoziente DEEP
+	folds       = bits.TrailingZeros(uint(degreeBound))
+)
+
+// SecurityBits è la sicurezza congetturata di FRI: log2(1/rate) bit per
+// apertura, con rate = degreeBound/ldeSize.
+func SecurityBits() int {
+	return Queries * bits.TrailingZeros(uint(ldeSize/degreeBound))
+}
+
+// Statement è la parte pubblica: un hash per valore e la somma.
+type Statement struct {
+	Hashes []fr.Element
+	Sum    fr.Element
+}
+
+// NewStatement calcola hash e somma dei valori con la stessa funzione della
+// traccia.
+func NewStatement(values []fr.Element) (*Statement, error) {
+	if len(values) != Rows {
+		return nil, fmt.Errorf("zkstark: %d valori, servono %d (completare con zeri)", len(values), Rows)
+	}
+	st := &Statement{Hashes: make([]fr.Element, Rows)}
+	for i, v := range values {
+		st.Hashes[i] = leaf(v)
+		st.Sum.Add(&st.Sum, &v)
+	}
+	return st, nil
+}
+
+// transcript parte dai parametri e dallo statement.
+func (st *Statement) transcript() *transcript {
+	t := newTranscript("zkstark-kpi-v1")
+	t.absorb([]byte(fmt.Sprintf("rows=%d blowup=%d queries=%d", Rows, Blowup, Queries)))
+	t.absorbElements(st.Hashes...)
+	t.absorbElements(st.Sum)
+	return t
+}
+
+// domini: H delle righe, L del coset g·<ω_L> dell'estensione
+type domains struct {
+	h, l    *fft.Domain
+	shift   fr.Element // g
+	lastRow fr.Element // ω_H^(Rows-1)
+}
+
+func newDomains() *domains {
+	d := &domains{h: fft.NewDomain(uint64(Rows)), l: fft.NewDomain(uint64(ldeSize))}
+	d.shift = d.l.FrMultiplicativeGen
+	d.lastRow = d.h.GeneratorInv
+	return d
+}
+
+func (d *domains) fri() friDomain {
+	return friDomain{shift: d.shift, omega: d.l.Generator, size: ldeSize}
+}
+
+// interpolate restituisce i coefficienti del polinomio che vale evals su H.
+func (d *domains) interpolate(evals []fr.Element) []fr.Element {
+	c := append([]fr.Element(nil), evals...)
+	d.h.FFTInverse(c, fft.DIF)
+	fft.BitReverse(c)
+	return c
+}
+
+// extend valuta il polinomio di coefficienti coeffs sul coset g·<ω_L>,
+// in ordine naturale: p(g·x) ha coefficienti c_i·g^i.
+func (d *domains) extend(coeffs []fr.Element) []fr.Element {
+	e := make([]fr.Element, ldeSize)
+	s := fr.One()
+	for i := range coeffs {
+		e[i].Mul(&coeffs[i], &s)
+		s.Mul(&s, &d.shift)
+	}
+	d.l.FFT(e, fft.DIF)
+	fft.BitReverse(e)
+	return e
+}
+
+func horner(coeffs []fr.Element, x fr.Element) fr.Element {
+	var y fr.Element
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y.Mul(&y, &x).Add(&y, &coeffs[i])
+	}
+	return y
+}
+
+func powers(x fr.Element, n int) []fr.Element {
+	p := make([]fr.Element, n)
+	p[0] = fr.One()
+	for i := 1; i < n; i++ {
+		p[i].Mul(&p[i-1], &x)
+	}
+	return p
+}
+
+// outOfDomain controlla che z non cada in H né nel coset, dove i quozienti
+// non sono definiti; succede con probabilità trascurabile.
+func (d *domains) outOfDomain(z fr.Element) bool {
+	if zn := exp(z, uint64(Rows)); zn.IsOne() {
+		return false
+	}
+	var w fr.Element
+	w.Inverse(&d.shift).Mul(&w, &z)
+	w = exp(w, uint64(ldeSize))
+	return !w.IsOne()
+}
+
+// deep valuta il quoziente DEEP in x dai valori della riga e della
+// composizione in x e da quelli aperti in z e z·ω.
+func deep(gamma []fr.Element, row []fr.Element, comp fr.Element, ood []fr.Element, cz fr.Element, invZ, invZW fr.Element) fr.Element {
+	var atZ, atZW, t fr.Element
+	for j := range row {
+		t.Sub(&row[j], &ood[j]).Mul(&t, &gamma[j])
+		atZ.Add(&atZ, &t)
+	}
+	t.Sub(&comp, &cz).Mul(&t, &gamma[nbColumns+2])
+	atZ.Add(&atZ, &t)
+	t.Sub(&row[colV], &ood[nbColumns]).Mul(&t, &gamma[nbColumns])
+	atZW.Add(&atZW, &t)
+	t.Sub(&row[colAcc], &ood[nbColumns+1]).Mul(&t, &gamma[nbColumns+1])
+	atZW.Add(&atZW, &t)
+	atZ.Mul(&atZ, &invZ)
+	atZW.Mul(&atZW, &invZW)
+	return *atZ.Add(&atZ, &atZW)
+}
+
+// Prove prova che values hanno gli hash e la somma di st.
+func Prove(st *Statement, values []fr.Element) (*Proof, error) {
+	if len(values) != Rows || len(st.Hashes) != Rows {
+		return nil, fmt.Errorf("zkstark: servono %d valori e %d hash", Rows, Rows)
+	}
+	columns := buildTrace(values)
+	if err := checkTrace(columns, st); err != nil {
+		return nil, err
+	}
+	return prove(st, columns)
+}
+
+// prove costruisce la prova dalla traccia, senza controllarla.
+func prove(st *Statement, columns [][]fr.Element) (*Proof, error) {
+	d := newDomains()
+	t := st.transcript()
+	proof := &Proof{}
+
+	// 1. traccia estesa e committata per righe
+	coeffs := make([][]fr.Element, nbColumns)
+	ext := make([][]fr.Element, nbColumns)
+	for j := range columns {
+		coeffs[j] = d.interpolate(columns[j])
+		ext[j] = d.extend(coeffs[j])
+	}
+	rows := make([][]fr.Element, ldeSize)
+	leaves := make([]Digest, ldeSize)
+	for i := range rows {
+		rows[i] = make([]fr.Element, nbColumns)
+		for j := range ext {
+			rows[i][j] = ext[j][i]
+		}
+		leaves[i] = leafHash(rows[i])
+	}
+	traceTree := newMerkleTree(leaves)
+	proof.TraceRoot = traceTree.root()
+	t.absorb(proof.TraceRoot[:])
+
+	// 2. composizione sul coset
+	alpha := powers(t.challenge(), nbConstraints)
+	hashCoeffs := d.interpolate(st.Hashes)
+	hashExt := d.extend(hashCoeffs)
+	comp := make([]fr.Element, ldeSize)
+	compLeaves := make([]Digest, ldeSize)
+	x := d.shift
+	for i := range comp {
+		next := (i + Blowup) % ldeSize
+		comp[i] = compose(alpha, &point{
+			x: x, row: rows[i], vNext: ext[colV][next], accNext: ext[colAcc][next], hash: hashExt[i],
+		}, st.Sum, d.lastRow, uint64(Rows))
+		x.Mul(&x, &d.l.Generator)
+		compLeaves[i] = leafHash(comp[i : i+1])
+	}
+	compTree := newMerkleTree(compLeaves)
+	proof.CompRoot = compTree.root()
+	t.absorb(proof.CompRoot[:])
+
+	// 3. DEEP: traccia in z e z·ω
+	z := t.challenge()
+	if !d.outOfDomain(z) {
+		return nil, errors.New("zkstark: z cade nel dominio, riprovare")
+	}
+	var zw fr.Element
+	zw.Mul(&z, &d.h.Generator)
+	proof.OOD = make([]fr.Element, nbColumns+2)
+	for j := range coeffs {
+		proof.OOD[j] = horner(coeffs[j], z)
+	}
+	proof.OOD[nbColumns] = horner(coeffs[colV], zw)
+	proof.OOD[nbColumns+1] = horner(coeffs[colAcc], zw)
+	t.absorbElements(proof.OOD...)
+	cz := compose(alpha, &point{
+		x: z, row: proof.OOD[:nbColumns], vNext: proof.OOD[nbColumns], accNext: proof.OOD[nbColumns+1], hash: horner(hashCoeffs, z),
+	}, st.Sum, d.lastRow, uint64(Rows))
+
+	gamma := powers(t.challenge(), nbColumns+3)
+	invZ := make([]fr.Element, ldeSize)
+	invZW := make([]fr.Element, ldeSize)
+	x = d.shift
+	for i := range invZ {
+		invZ[i].Sub(&x, &z)
+		invZW[i].Sub(&x, &zw)
+		x.Mul(&x, &d.l.Generator)
+	}
+	invZ, invZW = fr.BatchInvert(invZ), fr.BatchInvert(invZW)
+	quotient := make([]fr.Element, ldeSize)
+	for i := range quotient {
+		quotient[i] = deep(gamma, rows[i], comp[i], proof.OOD, cz, invZ[i], invZW[i])
+	}
+
+	// 4. FRI e aperture
+	_, layers, final := friCommit(t, quotient, d.fri(), folds)
+	for _, l := range layers {
+		proof.FRIRoots = append(proof.FRIRoots, l.tree.root())
+	}
+	proof.Final = final
+	half := ldeSize / 2
+	for _, q := range t.indices(half, Queries) {
+		var query Query
+		for s, pos := range []int{q, q + half} {
+			query.Trace[s] = Opening{Values: rows[pos], Path: traceTree.path(pos)}
+			query.Comp[s] = Opening{Values: comp[pos : pos+1], Path: compTree.path(pos)}
+		}
+		idx := q
+		for _, l := range layers {
+			p := idx % (len(l.values) / 2)
+			query.Layers = append(query.Layers, l.opening(p))
+			idx = p
+		}
+		proof.Queries = append(proof.Queries, query)
+	}
+	return proof, nil
+}
+
+// Verify controlla la prova contro lo statement, senza alcuna chiave.
+func Verify(st *Statement, proof *Proof) error {
+	if len(st.Hashes) != Rows {
+		return fmt.Errorf("zkstark: servono %d hash", Rows)
+	}
+	finalSize := ldeSize >> folds
+	if len(proof.OOD) != nbColumns+2 || len(proof.FRIRoots) != folds-1 ||
+		len(proof.Final) != finalSize || len(proof.Queries) != Queries {
+		return errors.New("zkstark: prova malformata")
+	}
+	d := newDomains()
+	t := st.transcript()
+
+	t.absorb(proof.TraceRoot[:])
+	alpha := powers(t.challenge(), nbConstraints)
+	t.absorb(proof.CompRoot[:])
+	z := t.challenge()
+	if !d.outOfDomain(z) {
+		return errors.New("zkstark: z cade nel dominio")
+	}
+	var zw fr.Element
+	zw.Mul(&z, &d.h.Generator)
+	t.absorbElements(proof.OOD...)
+
+	// la composizione in z viene dai vincoli, non dalla prova
+	cz := compose(alpha, &point{
+		x: z, row: proof.OOD[:nbColumns], vNext: proof.OOD[nbColumns], accNext: proof.OOD[nbColumns+1],
+		hash: horner(d.interpolate(st.Hashes), z),
+	}, st.Sum, d.lastRow, uint64(Rows))
+
+	gamma := powers(t.challenge(), nbColumns+3)
+	betas := make([]fr.Element, folds)
+	for i := range betas {
+		betas[i] = t.challenge()
+		if i < folds-1 {
+			t.absorb(proof.FRIRoots[i][:])
+		}
+	}
+	t.absorbElements(proof.Final...)
+	for i := range proof.Final {
+		if !proof.Final[i].Equal(&proof.Final[0]) {
+			return errors.New("zkstark: l'ultimo strato FRI non è costante")
+		}
+	}
+
+	half := ldeSize / 2
+	depth := bits.TrailingZeros(uint(ldeSize))
+	dom := d.fri()
+	for k, q := range t.indices(half, Queries) {
+		query := &proof.Queries[k]
+		if len(query.Layers) != folds-1 {
+			return fmt.Errorf("zkstark: apertura %d malformata", k)
+		}
+
+		// strato 0: quoziente DEEP in x e -x dalle aperture
+		var f [2]fr.Element
+		for s, pos := range []int{q, q + half} {
+			tr, cp := query.Trace[s], query.Comp[s]
+			if len(tr.Values) != nbColumns || len(cp.Values) != 1 {
+				return fmt.Errorf("zkstark: apertura %d malformata", k)
+			}
+			if !verifyPath(proof.TraceRoot, leafHash(tr.Values), pos, tr.Path, depth) ||
+				!verifyPath(proof.CompRoot, leafHash(cp.Values), pos, cp.Path, depth) {
+				return fmt.Errorf("zkstark: cammino di Merkle non valido nell'apertura %d", k)
+			}
+			x := dom.point(pos)
+			var invZ, invZW fr.Element
+			invZ.Sub(&x, &z).Inverse(&invZ)
+			invZW.Sub(&x, &zw).Inverse(&invZW)
+			f[s] = deep(gamma, tr.Values, cp.Values[0], proof.OOD, cz, invZ, invZW)
+		}
+
+		// pieghe: ogni strato deve contenere il valore piegato dal precedente
+		val := foldPair(f[0], f[1], betas[0], dom.point(q))
+		idx, ld := q, dom.next()
+		for i, o := range query.Layers {
+			h := ld.size / 2
+			p, side := idx%h, idx/h
+			if len(o.Values) != 2 || !verifyPath(proof.FRIRoots[i], leafHash(o.Values), p, o.Path, bits.TrailingZeros(uint(h))) {
+				return fmt.Errorf("zkstark: strato FRI %d non valido nell'apertura %d", i+1, k)
+			}
+			if !o.Values[side].Equal(&val) {
+				return fmt.Errorf("zkstark: piega FRI %d incoerente nell'apertura %d", i+1, k)
+			}
+			val = foldPair(o.Values[0], o.Values[1], betas[i+1], ld.point(p))
+			idx, ld = p, ld.next()
+		}
+		if !val.Equal(&proof.Final[idx]) {
+			return fmt.Errorf("zkstark: ultima piega incoerente nell'apertura %d", k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package zkstark
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	"zk-test/kpihash"
+)
+
+func testValues() []fr.Element {
+	values := make([]fr.Element, Rows)
+	for i := range values {
+		values[i].SetUint64(uint64(1000 * (i + 1)))
+	}
+	return values
+}
+
+// La foglia della traccia deve essere quella di kpihash, altrimenti lo STARK
+// prova uno statement diverso da quello SNARK.
+func TestLeafKpihash(t *testing.T) {
+	h, err := kpihash.New(kpihash.Poseidon2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range append(testValues()[:3], fr.NewElement(0), fr.One()) {
+		if got, want := leaf(v), h.Leaf(v); !got.Equal(&want) {
+			t.Fatalf("leaf(%s) = %s, kpihash %s", v.String(), got.String(), want.String())
+		}
+	}
+	st, err := NewStatement(testValues())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range testValues() {
+		if want := h.Leaf(v); !st.Hashes[i].Equal(&want) {
+			t.Fatalf("hash %d dello statement diverso da kpihash", i)
+		}
+	}
+}
+
+func TestProve(t *testing.T) {
+	values := testValues()
+	st, err := NewStatement(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := Prove(st, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(st, proof); err != nil {
+		t.Fatal(err)
+	}
+
+	// Prove rifiuta una traccia che non soddisfa lo statement
+	other := *st
+	other.Sum.Add(&st.Sum, &one)
+	if _, err := Prove(&other, values); err == nil {
+		t.Fatal("Prove accetta una somma sbagliata")
+	}
+}
+
+// Tracce disoneste provate con prove, che non le controlla: Verify deve
+// fallire sui vincoli, cioè su FRI, e non per una prova malformata.
+func TestBadTrace(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		change func(st *Statement, columns [][]fr.Element)
+	}{
+		{"acc", func(st *Statement, columns [][]fr.Element) {
+			// somma dichiarata +1 e acc dell'ultima riga allineato
+			st.Sum.Add(&st.Sum, &one)
+			columns[colAcc][Rows-1] = st.Sum
+		}},
+		{"round", func(st *Statement, columns [][]fr.Element) {
+			columns[colA(10)][3].Add(&columns[colA(10)][3], &one)
+		}},
+		{"hash", func(st *Statement, columns [][]fr.Element) {
+			st.Hashes[7].Add(&st.Hashes[7], &one)
+		}},
+		{"valore", func(st *Statement, columns [][]fr.Element) {
+			// valore cambiato senza rifare i round
+			columns[colV][20].Add(&columns[colV][20], &one)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			values := testValues()
+			st, err := NewStatement(values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			columns := buildTrace(values)
+			tc.change(st, columns)
+			proof, err := prove(st, columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = Verify(st, proof)
+			if err == nil {
+				t.Fatal("prova di una traccia disonesta accettata")
+			}
+			if !strings.Contains(err.Error(), "FRI") && !strings.Contains(err.Error(), "piega") {
+				t.Fatalf("errore fuori dal controllo FRI: %v", err)
+			}
+		})
+	}
+}
+
+func TestProofRoundTrip(t *testing.T) {
+	values := testValues()
+	st, err := NewStatement(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := Prove(st, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	n, err := proof.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Fatalf("WriteTo: %d byte, scritti %d", n, buf.Len())
+	}
+	data := buf.Bytes()
+
+	var read Proof
+	m, err := read.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != n {
+		t.Fatalf("ReadFrom: %d byte letti, %d scritti", m, n)
+	}
+	if !reflect.DeepEqual(&read, proof) {
+		t.Fatal("la prova riletta è diversa")
+	}
+	if err := Verify(st, &read); err != nil {
+		t.Fatal(err)
+	}
+
+	// prova troncata, altro formato, elemento fuori dal campo
+	if _, err := new(Proof).ReadFrom(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Fatal("prova troncata letta")
+	}
+	bad := append([]byte(nil), data...)
+	bad[0] = 'X'
+	if _, err := new(Proof).ReadFrom(bytes.NewReader(bad)); err == nil {
+		t.Fatal("magic sbagliato accettato")
+	}
+	bad = append([]byte(nil), data...)
+	ood := len(magic) + 1 + 2*len(Digest{}) + 4 // primo elemento di OOD
+	for i := 0; i < fr.Bytes; i++ {
+		bad[ood+i] = 0xff
+	}
+	if _, err := new(Proof).ReadFrom(bytes.NewReader(bad)); err == nil {
+		t.Fatal("elemento non canonico accettato")
+	}
+}
@@ -0,0 +1,69 @@
+package zkstark
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// transcript è il Fiat-Shamir: una catena SHA-256 in cui prover e verifier
+// assorbono gli stessi messaggi nello stesso ordine.
+type transcript struct {
+	state Digest
+}
+
+func newTranscript(label string) *transcript {
+	return &transcript{state: sha256.Sum256([]byte(label))}
+}
+
+func (t *transcript) absorb(data ...[]byte) {
+	h := sha256.New()
+	h.Write(t.state[:])
+	for _, d := range data {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(d)))
+		h.Write(n[:])
+		h.Write(d)
+	}
+	h.Sum(t.state[:0])
+}
+
+func (t *transcript) absorbElements(values ...fr.Element) {
+	data := make([]byte, 0, len(values)*fr.Bytes)
+	for i := range values {
+		b := values[i].Bytes()
+		data = append(data, b[:]...)
+	}
+	t.absorb(data)
+}
+
+// next avanza lo stato e ne restituisce 32 byte pseudo-casuali.
+func (t *transcript) next() Digest {
+	t.absorb([]byte("challenge"))
+	return t.state
+}
+
+// challenge è un elemento di fr da 64 byte, così la riduzione mod r non ha
+// bias apprezzabile.
+func (t *transcript) challenge() fr.Element {
+	a, b := t.next(), t.next()
+	var e fr.Element
+	e.SetBytes(append(a[:], b[:]...))
+	return e
+}
+
+// indices restituisce count indici distinti in [0, n), n ≥ count.
+func (t *transcript) indices(n, count int) []int {
+	seen := make(map[int]bool, count)
+	res := make([]int, 0, count)
+	for len(res) < count {
+		d := t.next()
+		i := int(binary.BigEndian.Uint64(d[:8]) % uint64(n))
+		if !seen[i] {
+			seen[i] = true
+			res = append(res, i)
+		}
+	}
+	return res
+}
@@ -1,22 +1,18 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"math"
 
-	"github.com/consensys/gnark-crypto/ecc"
-	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/frontend/cs/r1cs"
+
+	"zk-test/kpiinput"
+	"zk-test/zkbackend"
 )
 
 const MaxsInputValues = 100
 
-type InputData struct {
-	Values []float64 `json:"values"`
-}
-
 // custom circuit ZK per somma dinamica di valori, per esempio 100!
 type DynamicSumCircuit struct {
 	Inputs [MaxsInputValues]frontend.Variable `gnark:",secret"`
@@ -36,23 +32,25 @@ func (c *DynamicSumCircuit) Define(api frontend.API) error {
 }
 
 func main() {
+	cfg := zkbackend.Flags()
+	input := kpiinput.Flag()
+	flag.Parse()
 
 	var myCircuit DynamicSumCircuit
-	// cyrve BN254 (Groth16), funziona ma non è quantum safe mmm
-	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &myCircuit)
+	// cyrve BN254 di default, -curve bls12_381 per ~128 bit (Groth16 o PLONK), comunque non quantum safe mmm
+	// gestioni chiavi: riusate da -keys se già presenti
+	sys, err := cfg.LoadOrSetup(&myCircuit)
 	if err != nil {
 		panic(err)
 	}
-
-	// gestioni chiavi
-	pk, vk, err := groth16.Setup(ccs)
+	// json esempio, oppure il file passato con -input
+	data, err := kpiinput.Load(*input, kpiinput.Example)
 	if err != nil {
 		panic(err)
 	}
-	// json esempio
-	jsonData := `{"values": [1.3, 2.3, 4.234]}`
-	var data InputData
-	json.Unmarshal([]byte(jsonData), &data)
+	if len(data.Values) > MaxsInputValues {
+		panic(fmt.Sprintf("%d valori, il circuito ne accetta al massimo %d", len(data.Values), MaxsInputValues))
+	}
 
 	// Withness con scaling a 1000 (valori con 3 cifre decimali per esempio, TODO riadattare)
 	var assignment DynamicSumCircuit
@@ -73,16 +71,16 @@ func main() {
 	assignment.ExpectedSum = currentSum
 
 	// Creiamo la witness (testimone)
-	witness, _ := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+	witness, _ := frontend.NewWitness(&assignment, sys.Curve.ScalarField())
 	publicWitness, _ := witness.Public()
 
 	// Generazione prova ZK
-	proof, err := groth16.Prove(ccs, pk, witness)
+	proof, err := sys.Prove(witness)
 	if err != nil {
 		panic(err)
 	}
 
-	err = groth16.Verify(proof, vk, publicWitness)
+	err = sys.Verify(proof, publicWitness)
 	if err != nil {
 		fmt.Printf("err: %v\n", err)
 	} else {
@@ -93,8 +91,8 @@ func main() {
 	badAssignment := DynamicSumCircuit{
 		ExpectedSum: 9999, // valore a caso errato
 	}
-	badPublicWitness, _ := frontend.NewWitness(&badAssignment, ecc.BN254.ScalarField(), frontend.PublicOnly())
-	err = groth16.Verify(proof, vk, badPublicWitness)
+	badPublicWitness, _ := frontend.NewWitness(&badAssignment, sys.Curve.ScalarField(), frontend.PublicOnly())
+	err = sys.Verify(proof, badPublicWitness)
 	if err != nil {
 		fmt.Println("Success test con errore")
 	}
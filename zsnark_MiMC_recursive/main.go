@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/algebra/native/sw_bls12377"
+	"github.com/consensys/gnark/std/math/emulated"
+	stdgroth16 "github.com/consensys/gnark/std/recursion/groth16"
+
+	"zk-test/kpihash"
+	"zk-test/kpiinput"
+	"zk-test/kpimerkle"
+)
+
+// numero di prove interne (una per dataset/periodo) aggregate nella prova esterna
+const NumProofs = 4
+
+// Il circuito interno è kpimerkle.Circuit con hash MiMC, lo stesso di
+// zsnark_MiMC, ma compilato su BLS12-377 per poter essere verificato
+// nativamente dentro un circuito BW6-761 (2-chain).
+
+// Circuito esterno: verifica NumProofs prove interne con la stessa vk (fissata nel
+// circuito) e dimostra che TotalSum è la somma dei rispettivi ExpectedSum.
+type AggregationCircuit struct {
+	Proofs        [NumProofs]stdgroth16.Proof[sw_bls12377.G1Affine, sw_bls12377.G2Affine]
+	InnerWitness  [NumProofs]stdgroth16.Witness[sw_bls12377.ScalarField]                              `gnark:",public"`
+	TotalSum      emulated.Element[sw_bls12377.ScalarField]                                           `gnark:",public"`
+	InnerVerifKey stdgroth16.VerifyingKey[sw_bls12377.G1Affine, sw_bls12377.G2Affine, sw_bls12377.GT] `gnark:"-"`
+}
+
+func (c *AggregationCircuit) Define(api frontend.API) error {
+	verifier, err := stdgroth16.NewVerifier[sw_bls12377.ScalarField, sw_bls12377.G1Affine, sw_bls12377.G2Affine, sw_bls12377.GT](api)
+	if err != nil {
+		return fmt.Errorf("new verifier: %w", err)
+	}
+	scalarApi, err := emulated.NewField[sw_bls12377.ScalarField](api)
+	if err != nil {
+		return err
+	}
+
+	totalSum := scalarApi.Zero()
+	for i := 0; i < NumProofs; i++ {
+		if err := verifier.AssertProof(c.InnerVerifKey, c.Proofs[i], c.InnerWitness[i]); err != nil {
+			return fmt.Errorf("proof %d: %w", i, err)
+		}
+		// Witness pubblico interno in ordine di dichiarazione: [0] = Root, [1] = ExpectedSum
+		totalSum = scalarApi.Add(totalSum, &c.InnerWitness[i].Public[1])
+	}
+
+	scalarApi.AssertIsEqual(totalSum, &c.TotalSum)
+	return nil
+}
+
+// innerProof contiene una prova interna con il suo witness pubblico.
+type innerProof struct {
+	proof         groth16.Proof
+	publicWitness witness.Witness
+	sum           int64
+}
+
+// proveDataset costruisce l'albero MiMC e la prova BLS12-377 per un singolo dataset.
+func proveDataset(ccs constraint.ConstraintSystem, pk groth16.ProvingKey, vk groth16.VerifyingKey, data kpiinput.Data) (innerProof, error) {
+	assignment, sum, err := kpimerkle.Assign(ecc.BLS12_377, kpihash.MiMC, data.Values)
+	if err != nil {
+		return innerProof{}, err
+	}
+	return proveInner(ccs, pk, vk, assignment, sum)
+}
+
+// proveInner prova assignment sul circuito interno e la verifica come farà
+// il circuito esterno.
+func proveInner(ccs constraint.ConstraintSystem, pk groth16.ProvingKey, vk groth16.VerifyingKey, assignment frontend.Circuit, sum int64) (innerProof, error) {
+	w, err := frontend.NewWitness(assignment, ecc.BLS12_377.ScalarField())
+	if err != nil {
+		return innerProof{}, err
+	}
+	publicWitness, err := w.Public()
+	if err != nil {
+		return innerProof{}, err
+	}
+
+	// La prova interna deve usare l'hash-to-field compatibile con la verifica in-circuit
+	proof, err := groth16.Prove(ccs, pk, w, stdgroth16.GetNativeProverOptions(ecc.BW6_761.ScalarField(), ecc.BLS12_377.ScalarField()))
+	if err != nil {
+		return innerProof{}, err
+	}
+	err = groth16.Verify(proof, vk, publicWitness, stdgroth16.GetNativeVerifierOptions(ecc.BW6_761.ScalarField(), ecc.BLS12_377.ScalarField()))
+	if err != nil {
+		return innerProof{}, err
+	}
+
+	return innerProof{proof: proof, publicWitness: publicWitness, sum: sum}, nil
+}
+
+// outer restituisce il circuito esterno per le prove di innerCcs, con la vk
+// interna fissata, e l'assignment per inner con la somma dei loro ExpectedSum.
+func outer(innerCcs constraint.ConstraintSystem, innerVk groth16.VerifyingKey, inner []innerProof) (circuit, assignment *AggregationCircuit, totalSum int64, err error) {
+	fixedVk, err := stdgroth16.ValueOfVerifyingKeyFixed[sw_bls12377.G1Affine, sw_bls12377.G2Affine, sw_bls12377.GT](innerVk)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	circuit = &AggregationCircuit{InnerVerifKey: fixedVk}
+	assignment = &AggregationCircuit{InnerVerifKey: fixedVk}
+	for i := 0; i < NumProofs; i++ {
+		circuit.Proofs[i] = stdgroth16.PlaceholderProof[sw_bls12377.G1Affine, sw_bls12377.G2Affine](innerCcs)
+		circuit.InnerWitness[i] = stdgroth16.PlaceholderWitness[sw_bls12377.ScalarField](innerCcs)
+
+		assignment.Proofs[i], err = stdgroth16.ValueOfProof[sw_bls12377.G1Affine, sw_bls12377.G2Affine](inner[i].proof)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		assignment.InnerWitness[i], err = stdgroth16.ValueOfWitness[sw_bls12377.ScalarField](inner[i].publicWitness)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		totalSum += inner[i].sum
+	}
+	assignment.TotalSum = emulated.ValueOf[sw_bls12377.ScalarField](big.NewInt(totalSum))
+	return circuit, assignment, totalSum, nil
+}
+
+func main() {
+	// 1. Circuito interno su BLS12-377
+	innerCircuit := kpimerkle.Circuit{Hash: kpihash.MiMC}
+	innerCcs, err := frontend.Compile(ecc.BLS12_377.ScalarField(), r1cs.NewBuilder, &innerCircuit)
+	if err != nil {
+		panic(err)
+	}
+	innerPk, innerVk, err := groth16.Setup(innerCcs)
+	if err != nil {
+		panic(err)
+	}
+
+	// Dati JSON esempio: un dataset per periodo
+	jsonData := `[
+		{"values": [1.3, 2.3, 4.234]},
+		{"values": [3.87, 5.12, 6.45, 7.01, 6.88]},
+		{"values": [5.76, 4.92, 3.58]},
+		{"values": [2.91, 3.14, 4.01, 5.33, 6.02, 6.77]}
+	]`
+	var datasets []kpiinput.Data
+	if err := json.Unmarshal([]byte(jsonData), &datasets); err != nil {
+		panic(err)
+	}
+	if len(datasets) != NumProofs {
+		panic(fmt.Sprintf("attesi %d dataset, trovati %d", NumProofs, len(datasets)))
+	}
+
+	inner := make([]innerProof, NumProofs)
+	for i, data := range datasets {
+		inner[i], err = proveDataset(innerCcs, innerPk, innerVk, data)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("[Dataset %d] prova interna generata, somma %d\n", i, inner[i].sum)
+	}
+
+	// 2. Circuito esterno su BW6-761 con la vk interna fissata
+	outerCircuit, outerAssignment, totalSum, err := outer(innerCcs, innerVk, inner)
+	if err != nil {
+		panic(err)
+	}
+	outerCcs, err := frontend.Compile(ecc.BW6_761.ScalarField(), r1cs.NewBuilder, outerCircuit)
+	if err != nil {
+		panic(err)
+	}
+	outerPk, outerVk, err := groth16.Setup(outerCcs)
+	if err != nil {
+		panic(err)
+	}
+
+	// 3. Prova esterna
+	outerWitness, err := frontend.NewWitness(outerAssignment, ecc.BW6_761.ScalarField())
+	if err != nil {
+		panic(err)
+	}
+	outerPublicWitness, err := outerWitness.Public()
+	if err != nil {
+		panic(err)
+	}
+
+	outerProof, err := groth16.Prove(outerCcs, outerPk, outerWitness)
+	if err != nil {
+		fmt.Printf("Errore: %v\n", err)
+		return
+	}
+
+	err = groth16.Verify(outerProof, outerVk, outerPublicWitness)
+	if err == nil {
+		fmt.Printf("Somma aggregata verificata: %d su %d prove interne.\n", totalSum, NumProofs)
+	} else {
+		fmt.Printf("Errore verifica: %v\n", err)
+	}
+}
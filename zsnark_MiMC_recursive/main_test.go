@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/algebra/native/sw_bls12377"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/test"
+)
+
+// smallCircuit ha gli stessi segnali pubblici di kpimerkle.Circuit, Root e
+// ExpectedSum in quest'ordine, ma pochi vincoli: Root = X² e ExpectedSum = X.
+type smallCircuit struct {
+	Root        frontend.Variable `gnark:",public"`
+	ExpectedSum frontend.Variable `gnark:",public"`
+	X           frontend.Variable
+}
+
+func (c *smallCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(c.X, c.X), c.Root)
+	api.AssertIsEqual(c.X, c.ExpectedSum)
+	return nil
+}
+
+// Il circuito esterno su BW6-761 deve accettare NumProofs prove interne
+// valide con la loro somma, e rifiutare una somma sbagliata o un witness
+// interno con l'ExpectedSum cambiato.
+func TestAggregation(t *testing.T) {
+	innerCcs, err := frontend.Compile(ecc.BLS12_377.ScalarField(), r1cs.NewBuilder, &smallCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	innerPk, innerVk, err := groth16.Setup(innerCcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := make([]innerProof, NumProofs)
+	for i := range inner {
+		x := int64(1000*i + 7)
+		inner[i], err = proveInner(innerCcs, innerPk, innerVk, &smallCircuit{Root: x * x, ExpectedSum: x, X: x}, x)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	circuit, assignment, totalSum, err := outer(innerCcs, innerVk, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totalSum != 6028 {
+		t.Fatalf("somma %d, attesa 6028", totalSum)
+	}
+	field := ecc.BW6_761.ScalarField()
+	if err := test.IsSolved(circuit, assignment, field); err != nil {
+		t.Fatalf("aggregazione valida rifiutata: %v", err)
+	}
+
+	// TotalSum diverso dalla somma delle prove
+	_, bad, _, err := outer(innerCcs, innerVk, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad.TotalSum = emulated.ValueOf[sw_bls12377.ScalarField](big.NewInt(totalSum + 1))
+	if test.IsSolved(circuit, bad, field) == nil {
+		t.Fatal("accettata una somma totale sbagliata")
+	}
+
+	// ExpectedSum della prova 2 cambiato, con TotalSum che lo segue: la somma
+	// torna, ma la prova interna non vale più per quel witness
+	_, bad, _, err = outer(innerCcs, innerVk, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad.InnerWitness[2].Public[1] = emulated.ValueOf[sw_bls12377.ScalarField](big.NewInt(inner[2].sum + 1))
+	bad.TotalSum = emulated.ValueOf[sw_bls12377.ScalarField](big.NewInt(totalSum + 1))
+	if test.IsSolved(circuit, bad, field) == nil {
+		t.Fatal("accettata una prova interna con l'ExpectedSum cambiato")
+	}
+}
@@ -2,135 +2,145 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"math"
+	"math/big"
 	"os"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
-	poseidon2_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
 	"github.com/consensys/gnark/backend/groth16"
 	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
 	"github.com/consensys/gnark/backend/witness"
 	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/frontend/cs/r1cs"
-	gnark_poseidon2 "github.com/consensys/gnark/std/permutation/poseidon2"
 	gnarktosnarkjs "github.com/mysteryon88/gnark-to-snarkjs"
-)
 
-const (
-	MaxValues = 128 // provo a usare 128 come valore esponenziale di 2, let s try!
-	TreeDepth = 7
+	"zk-test/arkworks"
+	"zk-test/kpicommit"
+	"zk-test/kpihash"
+	"zk-test/kpiinput"
+	"zk-test/manifest"
+	"zk-test/zkbackend"
 )
 
-type InputData struct {
-	Values []float64 `json:"values"`
-}
-
-type QuantumValueCircuit struct {
-	Hashes      [MaxValues]frontend.Variable `gnark:",public"`
-	ExpectedSum frontend.Variable            `gnark:",public"`
-
-	Values [MaxValues]frontend.Variable `gnark:",secret"`
-}
-
-func (c *QuantumValueCircuit) Define(api frontend.API) error {
-	p2, _ := gnark_poseidon2.NewPoseidon2FromParameters(api, 2, 8, 56)
-	totalSum := frontend.Variable(0)
-
-	for i := 0; i < MaxValues; i++ {
-		// Accumulo la somma
-		totalSum = api.Add(totalSum, c.Values[i])
-
-		// Verifico l'hash del singolo KPI (Mapping Lineare)
-		state := []frontend.Variable{c.Values[i], 0} // [valore, padding]
-		p2.Permutation(state)
-
-		// Questo vincolo assicura la provenienza del dato
-		api.AssertIsEqual(state[0], c.Hashes[i])
-	}
-
-	api.AssertIsEqual(totalSum, c.ExpectedSum)
-	return nil
-}
+// exampleData sono i valori usati senza -input.
+const exampleData = `{
+	"values": [
+		1.3, 2.3, 4.234, 3.87, 5.12, 6.45, 7.01, 6.88,
+		5.76, 4.92, 3.58, 2.91, 3.14, 4.01, 5.33, 6.02,
+		6.77, 7.25, 8.1, 7.84, 6.59, 5.48, 4.66, 3.97,
+		3.21, 2.75, 2.18, 1.92, 1.56, 1.11
+	]
+}`
 
 func main() {
+	cfg := zkbackend.Flags()
+	hashKind := kpihash.Flag()
+	input := kpiinput.Flag()
+	flag.Parse()
+
 	// crea cistom ciurcuit
-	var myCircuit QuantumValueCircuit
-	ccs, _ := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &myCircuit)
-	pk, vk, _ := groth16.Setup(ccs)
+	myCircuit := kpicommit.Circuit{Hash: *hashKind}
+	sys, err := cfg.LoadOrSetup(&myCircuit)
+	if err != nil {
+		panic(err)
+	}
 
 	// valori scalari, attenzione che zkstark NON gestisce i float, quindi scala
 
-	// Dati JSON  esempio
-	jsonData := `{
-		"values": [
-			1.3, 2.3, 4.234, 3.87, 5.12, 6.45, 7.01, 6.88,
-			5.76, 4.92, 3.58, 2.91, 3.14, 4.01, 5.33, 6.02,
-			6.77, 7.25, 8.1, 7.84, 6.59, 5.48, 4.66, 3.97,
-			3.21, 2.75, 2.18, 1.92, 1.56, 1.11
-		]
-	}`
-
-	var data InputData
-	json.Unmarshal([]byte(jsonData), &data)
-
-	// 1. Inizializzazione della permutazione Poseidon2
-	// t=2 (width), rf=8 (full rounds), rp=56 (partial rounds)
-	p2Instance := poseidon2_bn254.NewPermutation(2, 8, 56)
-	var scaledValues [MaxValues]int64
-	var publicHashes [MaxValues]fr.Element // Array di hash al posto della Root
+	// Dati JSON  esempio, oppure il file passato con -input
+	data, err := kpiinput.Load(*input, exampleData)
+	if err != nil {
+		panic(err)
+	}
+	if len(data.Values) > kpicommit.MaxValues {
+		panic(fmt.Sprintf("%d valori, il circuito ne accetta al massimo %d", len(data.Values), kpicommit.MaxValues))
+	}
+
+	// 1. Stesso hash del circuito, sul campo della curva scelta
+	hasher, err := kpihash.NewField(sys.Curve, *hashKind)
+	if err != nil {
+		panic(err)
+	}
+	var scaledValues [kpicommit.MaxValues]int64
+	var publicHashes [kpicommit.MaxValues]*big.Int // Array di hash al posto della Root
 
 	// Supponiamo di caricare i dati JSON
-	for i := 0; i < MaxValues; i++ {
+	for i := 0; i < kpicommit.MaxValues; i++ {
 		if i < len(data.Values) {
 			scaledValues[i] = int64(math.Round(data.Values[i] * 1000))
 		}
 
-		var e fr.Element
-		e.SetInt64(scaledValues[i])
-
-		// Calcolo l'hash per ogni singolo valore
-		state := []fr.Element{e, {}}
-		p2Instance.Permutation(state)
-		publicHashes[i] = state[0]
+		// Calcolo l'hash per ogni singolo valore (negativi ridotti nel campo)
+		e := new(big.Int).Mod(big.NewInt(scaledValues[i]), sys.Curve.ScalarField())
+		publicHashes[i] = hasher.Leaf(e)
 	}
 
 	// Assignment
-	var assignment QuantumValueCircuit
+	assignment := kpicommit.Circuit{Hash: *hashKind}
 	var sum int64 = 0
-	for i := 0; i < MaxValues; i++ {
+	for i := 0; i < kpicommit.MaxValues; i++ {
 		assignment.Values[i] = scaledValues[i]
 		assignment.Hashes[i] = publicHashes[i] // Passiamo l'array
 		sum += scaledValues[i]
 	}
 	assignment.ExpectedSum = sum
 
-	witness, _ := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+	witness, _ := frontend.NewWitness(&assignment, sys.Curve.ScalarField())
 	publicWitness, _ := witness.Public()
 	fmt.Println("public witness ", publicWitness)
 
-	proof, err := groth16.Prove(ccs, pk, witness)
+	proof, err := sys.Prove(witness)
 	if err != nil {
 		fmt.Printf("Errore: %v\n", err)
 		return
 	}
 
-	err = groth16.Verify(proof, vk, publicWitness)
+	err = sys.Verify(proof, publicWitness)
 	if err == nil {
-		fmt.Printf("Somma verificata: %d su %d slot.\n", sum, MaxValues)
+		fmt.Printf("Somma verificata: %d su %d slot.\n", sum, kpicommit.MaxValues)
+	}
+
+	// con -keys salvo anche la prova, la usa zsnark_solidity per la calldata
+	if cfg.KeysDir != "" {
+		if err := zkbackend.SaveProof(cfg.KeysDir, proof, publicWitness); err != nil {
+			fmt.Printf("Errore salvataggio prova: %v\n", err)
+		}
 	}
 
 	//exportForSnarkJS(proof, vk, publicWitness)
-	// exportBinaryForRust(proof, vk, publicWitness)
-	exportForSnark(proof, vk, publicWitness)
+	// manifest dei segnali pubblici accanto a public.json, vale per entrambi i backend
+	m, err := manifest.New(&assignment)
+	if err == nil {
+		err = m.Write(".")
+	}
+	if err != nil {
+		fmt.Printf("Errore manifest: %v\n", err)
+		return
+	}
+
+	// snarkjs e arkworks leggono solo BN254
+	if sys.Curve != ecc.BN254 {
+		fmt.Printf("Export snarkjs/arkworks saltato: solo bn254, non %s\n", sys.Curve)
+		return
+	}
+	switch sys.Backend {
+	case zkbackend.Groth16:
+		exportForSnark(proof.(groth16.Proof), sys.VerifyingKey.(groth16.VerifyingKey), publicWitness, m)
+		exportBinaryForRust(proof.(groth16.Proof), sys.VerifyingKey.(groth16.VerifyingKey), publicWitness)
+	case zkbackend.Plonk:
+		// snarkjsplonk non è ancora verificato contro snarkjs plonk verify
+		fmt.Println("Export snarkjs saltato: plonk non ancora verificato con snarkjs (snarkjsplonk/testdata/snarkjs.sh)")
+	}
 }
 
-func exportForSnark(proof groth16.Proof, vk groth16.VerifyingKey, publicWitness witness.Witness) {
+func exportForSnark(proof groth16.Proof, vk groth16.VerifyingKey, publicWitness witness.Witness, m *manifest.Manifest) {
 	// 1. Estraiamo i valori dal Witness Pubblico
-	// Gnark ordina alfabeticamente: [0] = ExpectedSum, [1] = Root
 	pubVals := publicWitness.Vector().(fr.Vector)
 
+	// L'ordine è quello di dichiarazione dei campi pubblici nella struct (non
+	// alfabetico): i nomi per indice sono in public_manifest.json
 	publicSignals := make([]string, len(pubVals))
 	for i := range pubVals {
 		publicSignals[i] = pubVals[i].String()
@@ -162,17 +172,16 @@ func exportForSnark(proof groth16.Proof, vk groth16.VerifyingKey, publicWitness
 	}
 
 	fmt.Println(" File JSON generati con successo per SnarkJS!")
+	fmt.Printf("   Signals: %s\n", m.Summary(publicSignals))
 }
 
+// exportBinaryForRust scrive proof.bin, vk.bin e public_witness.bin nel formato
+// compresso di ark-serialize letto da testRsnarkRust (non il WriteTo di gnark)
 func exportBinaryForRust(proof groth16.Proof, vk groth16.VerifyingKey, publicWitness witness.Witness) {
-
-	fProof, _ := os.Create("proof.bin")
-	proof.WriteTo(fProof)
-
-	fVk, _ := os.Create("vk.bin")
-	vk.WriteTo(fVk)
-
-	fPub, _ := os.Create("public_witness.bin")
-	publicWitness.WriteTo(fPub)
-
+	err := arkworks.Export(".", proof.(*groth16_bn254.Proof), vk.(*groth16_bn254.VerifyingKey), publicWitness.Vector().(fr.Vector))
+	if err != nil {
+		fmt.Printf("Errore export Rust: %v\n", err)
+		return
+	}
+	fmt.Println("🦀 File binari arkworks generati per Rust!")
 }
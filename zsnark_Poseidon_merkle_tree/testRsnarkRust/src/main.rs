@@ -1,26 +1,25 @@
 use std::fs::File;
-use ark_bn254::Bn254;
-use ark_groth16::Groth16;
+use ark_bn254::{Bn254, Fr};
+use ark_groth16::{Groth16, Proof, VerifyingKey};
 use ark_snark::SNARK;
 use ark_serialize::CanonicalDeserialize;
 
-use rsnark_provers_gnark::gnark::{GnarkProof, GnarkVerifyingKey, GnarkPublicWitness};
-
 fn main() {
 
-    let mut vk_file = File::open("vk.bin").expect("Manca vk.bin");
-    let mut proof_file = File::open("proof.bin").expect("Manca proof.bin");
-    let mut pub_file = File::open("public_witness.bin").expect("Manca public_witness.bin");
+    // file scritti da exportBinaryForRust nel formato compresso di ark-serialize
+    let vk_file = File::open("vk.bin").expect("Manca vk.bin");
+    let proof_file = File::open("proof.bin").expect("Manca proof.bin");
+    let pub_file = File::open("public_witness.bin").expect("Manca public_witness.bin");
 
-    let vk = GnarkVerifyingKey::<Bn254>::read(&mut vk_file)
+    let vk = VerifyingKey::<Bn254>::deserialize_compressed(vk_file)
         .expect("Errore  VK");
-    let proof = GnarkProof::<Bn254>::read(&mut proof_file)
+    let proof = Proof::<Bn254>::deserialize_compressed(proof_file)
         .expect("Error Proof");
-    let pub_witness = GnarkPublicWitness::<Bn254>::read(&mut pub_file)
+    let pub_witness = Vec::<Fr>::deserialize_compressed(pub_file)
         .expect("Error Witness");
     println!("Dati cariticati finiti");
 
-    let is_valid = Groth16::<Bn254>::verify_proof(&vk.into(), &pub_witness.into(), &proof.into())
+    let is_valid = Groth16::<Bn254>::verify(&vk, &pub_witness, &proof)
         .expect("Errore verifica");
 
     if is_valid {
@@ -28,4 +27,4 @@ fn main() {
     } else {
         println!("errore validazione");
     }
-}
\ No newline at end of file
+}
@@ -1,4 +1,4 @@
-import { groth16 } from "snarkjs";
+import { groth16, plonk } from "snarkjs";
 import fs from "fs";
 
 async function run() {
@@ -12,9 +12,26 @@ async function run() {
     console.log("pubs :", publicSignals);
     console.log("vKey :", vKey);
 
-    console.log(" SnarkJS prova");
+    // nomi dei segnali dal manifest scritto dal main (ordine della struct gnark)
+    if (fs.existsSync("./public_manifest.json")) {
+      const manifest = JSON.parse(fs.readFileSync("./public_manifest.json"));
+      if (manifest.signals.length !== publicSignals.length) {
+        throw new Error(
+          `manifest con ${manifest.signals.length} segnali, public.json ne ha ${publicSignals.length}`
+        );
+      }
+      for (const s of manifest.signals) {
+        const v = publicSignals[s.index];
+        const scaled = s.scale > 1 ? ` (${Number(v) / s.scale})` : "";
+        console.log(`  [${s.index}] ${s.name} (${s.type}) = ${v}${scaled}`);
+      }
+    }
+
+    // il main esporta groth16 o plonk a seconda di -backend
+    const verifier = vKey.protocol === "plonk" ? plonk : groth16;
+    console.log(" SnarkJS prova", vKey.protocol);
 
-    const res = await groth16.verify(vKey, publicSignals, proof);
+    const res = await verifier.verify(vKey, publicSignals, proof);
 
     if (res === true) {
       console.log("VERIFICA fatta");
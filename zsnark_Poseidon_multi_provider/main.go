@@ -0,0 +1,293 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	eddsa_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/twistededwards/eddsa"
+	eddsa_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/twistededwards/eddsa"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	eddsa_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	"github.com/consensys/gnark-crypto/signature"
+	native_eddsa "github.com/consensys/gnark-crypto/signature/eddsa"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	gnarktosnarkjs "github.com/mysteryon88/gnark-to-snarkjs"
+
+	"zk-test/kpihash"
+	"zk-test/kpiprovider"
+	"zk-test/manifest"
+	"zk-test/zkbackend"
+)
+
+type ProviderData struct {
+	Name   string    `json:"name"`
+	Values []float64 `json:"values"`
+}
+
+type InputData struct {
+	Providers []ProviderData `json:"providers"`
+}
+
+// Provider tiene i propri dati e la propria chiave di firma, non condivisa con l'aggregatore.
+type Provider struct {
+	Name   string
+	Values [kpiprovider.ProviderValues]int64
+	curve  ecc.ID
+	signer signature.Signer
+}
+
+// SignedCommitment è quello che il provider consegna all'aggregatore.
+type SignedCommitment struct {
+	Name      string
+	Values    [kpiprovider.ProviderValues]int64
+	SubRoot   *big.Int
+	PublicKey []byte
+	Signature []byte
+}
+
+func NewProvider(curve ecc.ID, data ProviderData) (*Provider, error) {
+	if len(data.Values) > kpiprovider.ProviderValues {
+		return nil, fmt.Errorf("provider %s: %d valori, massimo %d", data.Name, len(data.Values), kpiprovider.ProviderValues)
+	}
+	ed, _, err := kpiprovider.Edwards(curve)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := native_eddsa.New(ed, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{Name: data.Name, curve: curve, signer: signer}
+	for i, v := range data.Values {
+		p.Values[i] = int64(math.Round(v * 1000))
+	}
+	return p, nil
+}
+
+// Commit costruisce il sotto-albero con hasher e firma la sotto-root.
+func (p *Provider) Commit(hasher kpihash.Field) (SignedCommitment, error) {
+	level := make([]*big.Int, kpiprovider.ProviderValues)
+	for i := 0; i < kpiprovider.ProviderValues; i++ {
+		e := new(big.Int).Mod(big.NewInt(p.Values[i]), p.curve.ScalarField())
+		level[i] = hasher.Leaf(e)
+	}
+	for len(level) > 1 {
+		next := make([]*big.Int, len(level)/2)
+		for i := range next {
+			next[i] = hasher.Node(level[2*i], level[2*i+1])
+		}
+		level = next
+	}
+	subRoot := level[0]
+
+	_, mimc, err := kpiprovider.Edwards(p.curve)
+	if err != nil {
+		return SignedCommitment{}, err
+	}
+	sig, err := p.signer.Sign(message(subRoot), mimc.New())
+	if err != nil {
+		return SignedCommitment{}, err
+	}
+
+	return SignedCommitment{
+		Name:      p.Name,
+		Values:    p.Values,
+		SubRoot:   subRoot,
+		PublicKey: p.signer.Public().Bytes(),
+		Signature: sig,
+	}, nil
+}
+
+// message è la sotto-root firmata: 32 byte big-endian, un blocco MiMC su
+// tutte e tre le curve.
+func message(subRoot *big.Int) []byte {
+	return subRoot.FillBytes(make([]byte, fr.Bytes))
+}
+
+// publicKey legge la chiave pubblica EdDSA di un provider.
+func publicKey(curve ecc.ID, b []byte) (signature.PublicKey, error) {
+	var pk signature.PublicKey
+	switch curve {
+	case ecc.BN254:
+		pk = new(eddsa_bn254.PublicKey)
+	case ecc.BLS12_381:
+		pk = new(eddsa_bls12381.PublicKey)
+	case ecc.BLS12_377:
+		pk = new(eddsa_bls12377.PublicKey)
+	default:
+		return nil, fmt.Errorf("eddsa non supportato su %s", curve)
+	}
+	if _, err := pk.SetBytes(b); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+// verifyCommitment controlla la firma fuori dal circuito prima di procedere con la prova.
+func verifyCommitment(curve ecc.ID, sc SignedCommitment) error {
+	pubKey, err := publicKey(curve, sc.PublicKey)
+	if err != nil {
+		return err
+	}
+	_, mimc, err := kpiprovider.Edwards(curve)
+	if err != nil {
+		return err
+	}
+	ok, err := pubKey.Verify(sc.Signature, message(sc.SubRoot), mimc.New())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("provider %s: firma della sotto-root non valida", sc.Name)
+	}
+	return nil
+}
+
+func main() {
+	cfg := zkbackend.Flags()
+	hashKind := kpihash.Flag()
+	flag.Parse()
+
+	// crea cistom ciurcuit
+	myCircuit := kpiprovider.Circuit{Hash: *hashKind}
+	sys, err := cfg.LoadOrSetup(&myCircuit)
+	if err != nil {
+		panic(err)
+	}
+
+	// Dati JSON esempio: ogni controllata ha i propri valori
+	jsonData := `{
+		"providers": [
+			{"name": "controllata-A", "values": [1.3, 2.3, 4.234]},
+			{"name": "controllata-B", "values": [3.87, 5.12, 6.45, 7.01]},
+			{"name": "controllata-C", "values": [6.88, 5.76]},
+			{"name": "controllata-D", "values": [4.92, 3.58, 2.91, 3.14, 4.01]}
+		]
+	}`
+	var data InputData
+	if err := json.Unmarshal([]byte(jsonData), &data); err != nil {
+		panic(err)
+	}
+	if len(data.Providers) != kpiprovider.NumProviders {
+		panic(fmt.Sprintf("attesi %d provider, trovati %d", kpiprovider.NumProviders, len(data.Providers)))
+	}
+
+	// 1. Ogni provider committa e firma per conto proprio, con lo stesso hash del circuito
+	hasher, err := kpihash.NewField(sys.Curve, *hashKind)
+	if err != nil {
+		panic(err)
+	}
+	ed, _, err := kpiprovider.Edwards(sys.Curve)
+	if err != nil {
+		panic(err)
+	}
+	commitments := make([]SignedCommitment, kpiprovider.NumProviders)
+	for p, providerData := range data.Providers {
+		provider, err := NewProvider(sys.Curve, providerData)
+		if err != nil {
+			panic(err)
+		}
+		commitments[p], err = provider.Commit(hasher)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("[%s] sotto-root firmata: %s\n", provider.Name, commitments[p].SubRoot.String())
+	}
+
+	// 2. L'aggregatore riceve solo i commitment firmati e costruisce la prova
+	assignment := kpiprovider.Circuit{Hash: *hashKind}
+	var sum int64 = 0
+	for p, sc := range commitments {
+		if err := verifyCommitment(sys.Curve, sc); err != nil {
+			panic(err)
+		}
+		assignment.SubRoots[p] = sc.SubRoot
+		assignment.PublicKeys[p].Assign(ed, sc.PublicKey)
+		assignment.Signatures[p].Assign(ed, sc.Signature)
+		for i := 0; i < kpiprovider.ProviderValues; i++ {
+			assignment.Values[p][i] = sc.Values[i]
+			sum += sc.Values[i]
+		}
+	}
+	assignment.ExpectedSum = sum
+
+	witness, _ := frontend.NewWitness(&assignment, sys.Curve.ScalarField())
+	publicWitness, _ := witness.Public()
+
+	proof, err := sys.Prove(witness)
+	if err != nil {
+		fmt.Printf("Errore: %v\n", err)
+		return
+	}
+
+	err = sys.Verify(proof, publicWitness)
+	if err == nil {
+		fmt.Printf("Somma aggregata verificata: %d su %d provider.\n", sum, kpiprovider.NumProviders)
+	}
+
+	// manifest dei segnali pubblici accanto a public.json, vale per entrambi i backend
+	m, err := manifest.New(&assignment)
+	if err == nil {
+		err = m.Write(".")
+	}
+	if err != nil {
+		fmt.Printf("Errore manifest: %v\n", err)
+		return
+	}
+
+	// snarkjs legge solo BN254
+	if sys.Curve != ecc.BN254 {
+		fmt.Printf("Export snarkjs saltato: solo bn254, non %s\n", sys.Curve)
+		return
+	}
+	switch sys.Backend {
+	case zkbackend.Groth16:
+		exportForSnark(proof.(groth16.Proof), sys.VerifyingKey.(groth16.VerifyingKey), publicWitness, m)
+	case zkbackend.Plonk:
+		// snarkjsplonk non è ancora verificato contro snarkjs plonk verify
+		fmt.Println("Export snarkjs saltato: plonk non ancora verificato con snarkjs (snarkjsplonk/testdata/snarkjs.sh)")
+	}
+}
+
+func exportForSnark(proof groth16.Proof, vk groth16.VerifyingKey, publicWitness witness.Witness, m *manifest.Manifest) {
+	pubVals := publicWitness.Vector().(fr.Vector)
+
+	publicSignals := make([]string, len(pubVals))
+	for i := range pubVals {
+		publicSignals[i] = pubVals[i].String()
+	}
+
+	proofOut, err := os.Create("proof.json")
+	if err == nil {
+		// La libreria richiede il puntatore specifico della curva BN254
+		gnarktosnarkjs.ExportProof(proof.(*groth16_bn254.Proof), publicSignals, proofOut)
+		proofOut.Close()
+	}
+
+	vkOut, err := os.Create("verification_key.json")
+	if err == nil {
+		gnarktosnarkjs.ExportVerifyingKey(vk.(*groth16_bn254.VerifyingKey), vkOut)
+		vkOut.Close()
+	}
+
+	publicOut, err := os.Create("public.json")
+	if err == nil {
+		enc := json.NewEncoder(publicOut)
+		enc.SetIndent("", "  ")
+		enc.Encode(publicSignals)
+		publicOut.Close()
+	}
+
+	fmt.Println(" File JSON generati con successo per SnarkJS!")
+	fmt.Printf("   Signals: %s\n", m.Summary(publicSignals))
+}
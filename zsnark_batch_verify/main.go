@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+
+	"zk-test/groth16batch"
+	"zk-test/zkbackend"
+)
+
+const (
+	MaxsInputValues = 100
+	NumProofs       = 16 // prove verificate in un'unica multi-pairing
+)
+
+// stesso circuito di zsnark_BN254
+type DynamicSumCircuit struct {
+	Inputs [MaxsInputValues]frontend.Variable `gnark:",secret"`
+
+	ExpectedSum frontend.Variable `gnark:",public"`
+}
+
+func (c *DynamicSumCircuit) Define(api frontend.API) error {
+	var sum frontend.Variable = 0
+	for idx := 0; idx < MaxsInputValues; idx++ {
+		sum = api.Add(sum, c.Inputs[idx])
+	}
+	api.AssertIsEqual(sum, c.ExpectedSum)
+	return nil
+}
+
+func main() {
+	curveName := flag.String("curve", zkbackend.DefaultCurve.String(), "curva: solo bn254, la batch verification usa i tipi groth16 di BN254")
+	flag.Parse()
+
+	curve, err := zkbackend.ParseCurve(*curveName)
+	if err != nil {
+		panic(err)
+	}
+	// groth16batch lavora sui punti e sul pairing di BN254
+	if curve != ecc.BN254 {
+		fmt.Printf("batch verification disponibile solo su bn254, non su %s\n", curve)
+		os.Exit(2)
+	}
+
+	var myCircuit DynamicSumCircuit
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &myCircuit)
+	if err != nil {
+		panic(err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		panic(err)
+	}
+
+	// genero NumProofs prove indipendenti sulla stessa vk
+	proofs := make([]*groth16_bn254.Proof, NumProofs)
+	publicWitnesses := make([]fr.Vector, NumProofs)
+	for j := 0; j < NumProofs; j++ {
+		var assignment DynamicSumCircuit
+		var sum int64 = 0
+		for i := 0; i < MaxsInputValues; i++ {
+			v := int64((j+1)*1000 + i)
+			assignment.Inputs[i] = v
+			sum += v
+		}
+		assignment.ExpectedSum = sum
+
+		witness, _ := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+		publicWitness, _ := witness.Public()
+		proof, err := groth16.Prove(ccs, pk, witness)
+		if err != nil {
+			panic(err)
+		}
+		proofs[j] = proof.(*groth16_bn254.Proof)
+		publicWitnesses[j] = publicWitness.Vector().(fr.Vector)
+	}
+
+	bvk := vk.(*groth16_bn254.VerifyingKey)
+	if err := groth16batch.Verify(bvk, proofs, publicWitnesses); err != nil {
+		fmt.Printf("err: %v\n", err)
+	} else {
+		fmt.Printf("Success: %d prove verificate in batch\n", NumProofs)
+	}
+
+	// test controprova: una somma pubblica errata deve far fallire il batch e venire individuata
+	publicWitnesses[5][0].SetUint64(9999)
+	if err := groth16batch.Verify(bvk, proofs, publicWitnesses); err != nil {
+		var batchErr *groth16batch.BatchError
+		if errors.As(err, &batchErr) {
+			fmt.Printf("Success test con errore: %v\n", batchErr)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"zk-test/kpihash"
+	"zk-test/latcommit"
+	"zk-test/secreport"
+)
+
+// Report di sicurezza classica e post-quantum per una configurazione: curva,
+// backend e hash del circuito KPI, più i parametri lattigo usati nel repo.
+// Il JSON va su stdout o nel file di -out.
+//
+//	go run ./zsnark_inspect -curve bn254 -backend groth16 -hash poseidon2 -out report.json
+
+func main() {
+	curve := flag.String("curve", "bn254", "curva: bn254, bls12_377, bls12_381 o bw6_761")
+	backend := flag.String("backend", "groth16", "backend: "+strings.Join(secreport.Backends, ", "))
+	hashKind := kpihash.Flag()
+	valueBits := flag.Int("valuebits", latcommit.ValueBits, "bit dei KPI scalati (forza bruta sugli hash pubblici)")
+	lattice := flag.Bool("lattice", true, "aggiunge i parametri lattigo (bfv, ckks, latcommit)")
+	out := flag.String("out", "", "file JSON, vuoto per stdout")
+	flag.Parse()
+
+	report, err := secreport.Inspect(secreport.Config{
+		Curve:     *curve,
+		Backend:   *backend,
+		Hash:      *hashKind,
+		ValueBits: *valueBits,
+		Lattice:   *lattice,
+	})
+	if err != nil {
+		fmt.Printf("Errore: %v\n", err)
+		os.Exit(1)
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	if *out == "" {
+		fmt.Println(string(b))
+		return
+	}
+	if err := os.WriteFile(*out, append(b, '\n'), 0o644); err != nil {
+		panic(err)
+	}
+	fmt.Println(report.Summary)
+}
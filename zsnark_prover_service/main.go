@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"zk-test/kpihash"
+	"zk-test/proverd"
+	"zk-test/zkbackend"
+)
+
+// Servizio di prova per il circuito Merkle da 128 slot (kpimerkle): invece di
+// un go run per dataset resta acceso, carica ccs e chiavi una volta sola da
+// -keys e prova i dataset inviati via HTTP con -workers worker. I job sono
+// salvati in -data e sopravvivono al riavvio.
+//
+//	go run ./zsnark_prover_service -keys keys -data jobs
+//	curl -X POST localhost:8080/jobs -d '{"values": [1.3, 2.3, 4.234]}'
+//	curl localhost:8080/jobs/<id>
+//	curl -o kpi.zip localhost:8080/jobs/<id>/bundle
+//	curl -X DELETE localhost:8080/jobs/<id>
+func main() {
+	cfg := zkbackend.Flags()
+	hashKind := kpihash.Flag()
+	addr := flag.String("addr", "localhost:8080", "indirizzo HTTP")
+	dataDir := flag.String("data", "jobs", "cartella dello stato dei job")
+	workers := flag.Int("workers", 1, "prove in parallelo, ognuna con la memoria di un prover")
+	queue := flag.Int("queue", 16, "job in coda al massimo, oltre POST /jobs risponde 503")
+	flag.Parse()
+
+	// senza -keys il setup si rifarebbe a ogni avvio
+	if cfg.KeysDir == "" {
+		cfg.KeysDir = "keys"
+	}
+	if *workers < 1 || *queue < 1 {
+		fmt.Println("-workers e -queue devono essere almeno 1")
+		os.Exit(2)
+	}
+
+	start := time.Now()
+	circuit := proverd.Merkle(*hashKind)
+	sys, err := cfg.LoadOrSetup(circuit.Schema())
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("circuito %s/%s/%s pronto in %v (chiavi in %s)\n", sys.Curve, sys.Backend, *hashKind, time.Since(start), cfg.KeysDir)
+
+	svc, err := proverd.New(sys, circuit, *dataDir, *queue)
+	if err != nil {
+		panic(err)
+	}
+	svc.Start(*workers)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: *addr, Handler: svc.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		// prima niente nuove richieste, poi si aspettano le prove in corso
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("in ascolto su %s, %d worker, coda di %d job\n", *addr, *workers, *queue)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		panic(err)
+	}
+	fmt.Println("chiusura: attendo le prove in corso")
+	svc.Close()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+
+	"zk-test/kpicommit"
+	"zk-test/kpimerkle"
+	"zk-test/kpiprovider"
+	"zk-test/manifest"
+	"zk-test/snarkjsgroth16"
+	"zk-test/snarkjsplonk"
+)
+
+// circuits sono i circuiti KPI che scrivono public_manifest.json, per -circuit.
+var circuits = map[string]frontend.Circuit{
+	"merkle":   &kpimerkle.Circuit{},
+	"commit":   &kpicommit.Circuit{},
+	"provider": &kpiprovider.Circuit{},
+}
+
+// Verifica in Go una prova Groth16 nel formato di snarkjs/circom:
+// verification_key.json, proof.json e public.json nella cartella -dir.
+//
+//	go run main.go -dir ../zsnark_MiMC/testSnarkJS -circuit merkle
+func main() {
+	dir := flag.String("dir", ".", "cartella con verification_key.json, proof.json e public.json")
+	name := flag.String("circuit", "", "circuito atteso per public_manifest.json: merkle, commit o provider (vuoto per prove circom senza manifest)")
+	flag.Parse()
+
+	var circuit frontend.Circuit
+	if *name != "" {
+		var ok bool
+		if circuit, ok = circuits[*name]; !ok {
+			fmt.Printf("circuito sconosciuto %q: merkle, commit o provider\n", *name)
+			os.Exit(2)
+		}
+	}
+
+	vk, proof, public, err := snarkjsgroth16.Read(*dir)
+	if err != nil {
+		fmt.Printf("Errore lettura: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("File snarkjs caricati: %d segnali pubblici\n", len(public))
+
+	// il manifest dei main gnark deve essere quello del circuito atteso:
+	// ordine, nomi, tipi e scale dei segnali
+	m, err := manifest.Read(*dir)
+	switch {
+	case err == nil && circuit == nil:
+		fmt.Println("public_manifest.json presente: serve -circuit per controllarlo")
+		os.Exit(2)
+	case err == nil:
+		if err := m.Check(circuit, len(public)); err != nil {
+			fmt.Printf("Errore manifest: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("   Signals: %s\n", m.Summary(snarkjsplonk.PublicSignals(public)))
+	case !errors.Is(err, os.ErrNotExist):
+		fmt.Printf("Errore manifest: %v\n", err)
+		os.Exit(1)
+	case circuit != nil:
+		fmt.Printf("Errore manifest: -circuit %s ma %s manca in %s\n", *name, manifest.File, *dir)
+		os.Exit(1)
+	}
+
+	if err := snarkjsgroth16.Verify(vk, proof, public); err != nil {
+		fmt.Printf("err: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Success VERIFICA snarkjs in Go")
+
+	// controprova: primo segnale pubblico alterato
+	if len(public) > 0 {
+		bad := make([]fr.Element, len(public))
+		copy(bad, public)
+		bad[0].SetUint64(9999)
+		if err := snarkjsgroth16.Verify(vk, proof, bad); err != nil {
+			fmt.Println("Success test con errore")
+		} else {
+			fmt.Println("Errore: prova accettata con un segnale pubblico alterato")
+			os.Exit(1)
+		}
+	}
+}
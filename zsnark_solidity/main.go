@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	"zk-test/evm"
+	"zk-test/zkbackend"
+)
+
+// Legge vk e ultima prova salvate da un main con -keys, scrive Verifier.sol e
+// calldata.hex, compila il verificatore con solc e lo esegue su una EVM locale.
+//
+//	cd zsnark_MiMC && go run main.go -keys keys
+//	cd ../zsnark_solidity && go run main.go -keys ../zsnark_MiMC/keys
+func main() {
+	cfg := zkbackend.Flags()
+	out := flag.String("out", ".", "cartella per Verifier.sol e calldata.hex")
+	bin := flag.String("bin", "", "bytecode di creazione del verificatore in hex (solc --bin Verifier.sol), al posto di solc")
+	flag.Parse()
+
+	curve, b, err := cfg.Parse()
+	if err != nil {
+		panic(err)
+	}
+	// il verificatore Solidity di gnark e i precompile EIP-196/197 sono solo BN254
+	if curve != ecc.BN254 {
+		fmt.Printf("verificatore Solidity disponibile solo su bn254, non su %s\n", curve)
+		os.Exit(2)
+	}
+	if cfg.KeysDir == "" {
+		fmt.Println("serve -keys con la cartella delle chiavi salvate da un main")
+		os.Exit(2)
+	}
+
+	vk, err := zkbackend.LoadVerifyingKey(curve, b, cfg.KeysDir)
+	if err != nil {
+		panic(err)
+	}
+	if err := evm.WriteVerifier(filepath.Join(*out, "Verifier.sol"), vk); err != nil {
+		panic(err)
+	}
+	fmt.Println("Verifier.sol generato")
+
+	proof, publicWitness, err := zkbackend.LoadProof(curve, b, cfg.KeysDir)
+	if err != nil {
+		panic(err)
+	}
+	public := publicWitness.Vector().(fr.Vector)
+	for i := range public {
+		fmt.Printf("   input[%d] = %s\n", i, public[i].String())
+	}
+
+	calldata, err := evm.Calldata(b, proof, public)
+	if err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(filepath.Join(*out, "calldata.hex"), []byte("0x"+hex.EncodeToString(calldata)+"\n"), 0o644); err != nil {
+		panic(err)
+	}
+	fmt.Printf("calldata.hex generato (%d byte)\n", len(calldata))
+
+	// il verificatore compilato (solc o -bin) viene deployato ed eseguito
+	creation, err := bytecode(b, *bin, filepath.Join(*out, "Verifier.sol"))
+	if errors.Is(err, evm.ErrNoSolc) {
+		fmt.Println("serve solc nel PATH, o -bin con il bytecode di Verifier.sol")
+		os.Exit(2)
+	}
+	if err != nil {
+		panic(err)
+	}
+	chain, err := evm.Deploy(creation)
+	if err != nil {
+		panic(err)
+	}
+	gas, err := chain.Verify(b, calldata)
+	if err != nil {
+		fmt.Printf("err contratto: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Success contratto (gas %d)\n", gas)
+
+	// controprova: stessa prova con il primo input pubblico alterato
+	bad := make(fr.Vector, len(public))
+	copy(bad, public)
+	bad[0].SetUint64(9999)
+	badCalldata, err := evm.Calldata(b, proof, bad)
+	if err != nil {
+		panic(err)
+	}
+	if _, err := chain.Verify(b, badCalldata); err != nil {
+		fmt.Println("Success test con errore contratto")
+	} else {
+		fmt.Println("Errore: il contratto accetta un input pubblico alterato")
+		os.Exit(1)
+	}
+}
+
+// bytecode legge il bytecode di creazione da bin, se dato, altrimenti compila
+// Verifier.sol con solc.
+func bytecode(b zkbackend.Backend, bin, sol string) ([]byte, error) {
+	if bin != "" {
+		return evm.ReadBin(bin)
+	}
+	return evm.Compile(b, sol)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	"zk-test/kpihash"
+	"zk-test/kpiinput"
+	"zk-test/zkstark"
+)
+
+// Stesso statement di zsnark_Poseidon_linear_commitment (hash Poseidon2 di
+// ogni valore + somma) provato con lo STARK di zkstark: niente trusted setup
+// né chiavi, solo SHA-256 e FRI. La prova viene scritta su file e riletta
+// prima di verificarla; poi il main prova a farsi accettare statement e
+// prove alterati e, se uno passa, esce con codice 1.
+//
+//	go run ./zstark_linear_commitment -input kpi.json
+
+// exampleData sono i valori usati senza -input.
+const exampleData = `{
+	"values": [
+		1.3, 2.3, 4.234, 3.87, 5.12, 6.45, 7.01, 6.88,
+		5.76, 4.92, 3.58, 2.91, 3.14, 4.01, 5.33, 6.02,
+		6.77, 7.25, 8.1, 7.84, 6.59, 5.48, 4.66, 3.97,
+		3.21, 2.75, 2.18, 1.92, 1.56, 1.11
+	]
+}`
+
+// publicJSON è la parte pubblica scritta accanto alla prova.
+type publicJSON struct {
+	Hashes []string `json:"hashes"`
+	Sum    string   `json:"sum"`
+}
+
+func main() {
+	input := kpiinput.Flag()
+	out := flag.String("out", "kpi.stark", "file della prova")
+	pub := flag.String("public", "stark_public.json", "file con hash e somma pubblici")
+	flag.Parse()
+
+	data, err := kpiinput.Load(*input, exampleData)
+	if err != nil {
+		panic(err)
+	}
+	if len(data.Values) > zkstark.Rows {
+		fmt.Printf("%d valori, la prova ne accetta al massimo %d\n", len(data.Values), zkstark.Rows)
+		os.Exit(1)
+	}
+
+	// valori scalati come nel circuito SNARK, il resto a zero
+	values := make([]fr.Element, zkstark.Rows)
+	var sum int64
+	for i, v := range data.Values {
+		s := int64(math.Round(v * 1000))
+		values[i].SetInt64(s)
+		sum += s
+	}
+	st, err := zkstark.NewStatement(values)
+	if err != nil {
+		panic(err)
+	}
+
+	// gli hash devono essere quelli di kpihash, altrimenti lo statement non è
+	// lo stesso della versione SNARK
+	hasher, err := kpihash.New(kpihash.Poseidon2)
+	if err != nil {
+		panic(err)
+	}
+	for i := range values {
+		if h := hasher.Leaf(values[i]); !h.Equal(&st.Hashes[i]) {
+			fmt.Printf("hash %d diverso da kpihash\n", i)
+			os.Exit(1)
+		}
+	}
+
+	start := time.Now()
+	proof, err := zkstark.Prove(st, values)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("prova in %v\n", time.Since(start))
+
+	// su file e ritorno, il verifier lavora solo su quello che legge
+	var buf bytes.Buffer
+	if _, err := proof.WriteTo(&buf); err != nil {
+		panic(err)
+	}
+	raw := buf.Bytes()
+	if err := os.WriteFile(*out, raw, 0o644); err != nil {
+		panic(err)
+	}
+	p := publicJSON{Sum: st.Sum.String()}
+	for i := range st.Hashes {
+		p.Hashes = append(p.Hashes, st.Hashes[i].String())
+	}
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(*pub, b, 0o644); err != nil {
+		panic(err)
+	}
+	fmt.Printf("prova in %s (%d KiB), parte pubblica in %s\n", *out, len(raw)/1024, *pub)
+
+	saved, err := os.ReadFile(*out)
+	if err != nil {
+		panic(err)
+	}
+	read := new(zkstark.Proof)
+	if _, err := read.ReadFrom(bytes.NewReader(saved)); err != nil {
+		panic(err)
+	}
+	start = time.Now()
+	if err := zkstark.Verify(st, read); err != nil {
+		fmt.Printf("Errore: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Somma verificata: %d su %d slot in %v (sicurezza congetturata %d bit, nessun setup)\n",
+		sum, zkstark.Rows, time.Since(start), zkstark.SecurityBits())
+
+	// statement e prove alterati devono essere rifiutati
+	failed := false
+	reject := func(what string, st *zkstark.Statement, raw []byte) {
+		p := new(zkstark.Proof)
+		_, err := p.ReadFrom(bytes.NewReader(raw))
+		if err == nil {
+			err = zkstark.Verify(st, p)
+		}
+		if err == nil {
+			fmt.Printf("ERRORE: %s accettato\n", what)
+			failed = true
+			return
+		}
+		fmt.Printf("%s rifiutato: %v\n", what, err)
+	}
+
+	wrongSum := &zkstark.Statement{Hashes: st.Hashes, Sum: st.Sum}
+	wrongSum.Sum.Add(&wrongSum.Sum, new(fr.Element).SetOne())
+	reject("somma +1", wrongSum, saved)
+
+	wrongHash := &zkstark.Statement{Hashes: append([]fr.Element(nil), st.Hashes...), Sum: st.Sum}
+	wrongHash.Hashes[0] = hasher.Leaf(*new(fr.Element).SetInt64(31337))
+	reject("hash del primo valore cambiato", wrongHash, saved)
+
+	flipped := append([]byte(nil), saved...)
+	flipped[len(flipped)/2] ^= 1
+	reject("un bit della prova cambiato", st, flipped)
+
+	if failed {
+		os.Exit(1)
+	}
+}